@@ -0,0 +1,475 @@
+//go:build linux && !tinygo
+
+package nrf24
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// GPIO character device v2 uAPI (linux/gpio.h).
+const (
+	_GPIO_V2_LINE_FLAG_INPUT          = 1 << 2
+	_GPIO_V2_LINE_FLAG_OUTPUT         = 1 << 3
+	_GPIO_V2_LINE_FLAG_EDGE_RISING    = 1 << 4
+	_GPIO_V2_LINE_FLAG_EDGE_FALLING   = 1 << 5
+	_GPIO_V2_LINE_FLAG_BIAS_PULL_UP   = 1 << 8
+	_GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN = 1 << 9
+	_GPIO_V2_LINE_FLAG_BIAS_DISABLED  = 1 << 10
+
+	_GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES = 2
+
+	// Size of struct gpio_v2_line_event
+	_GPIO_V2_LINE_EVENT_SIZE = 48
+)
+
+// ioctl request numbers, encoded as _IOR/_IOWR(0xB4, nr, struct).
+var (
+	_GPIO_GET_CHIPINFO_IOCTL       = gpioIoctl(2, 0x01, unsafe.Sizeof(gpioChipInfo{}))
+	_GPIO_V2_GET_LINEINFO_IOCTL    = gpioIoctl(3, 0x05, unsafe.Sizeof(gpioLineInfo{}))
+	_GPIO_V2_GET_LINE_IOCTL        = gpioIoctl(3, 0x07, unsafe.Sizeof(gpioLineRequest{}))
+	_GPIO_V2_LINE_SET_CONFIG_IOCTL = gpioIoctl(3, 0x0D, unsafe.Sizeof(gpioLineConfig{}))
+	_GPIO_V2_LINE_GET_VALUES_IOCTL = gpioIoctl(3, 0x0E, unsafe.Sizeof(gpioLineValues{}))
+	_GPIO_V2_LINE_SET_VALUES_IOCTL = gpioIoctl(3, 0x0F, unsafe.Sizeof(gpioLineValues{}))
+)
+
+func gpioIoctl(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 0xB4<<8 | nr
+}
+
+// The structs below mirror the kernel layout. Every 64-bit field is at an offset
+// multiple of 8, so the layout is the same on 32 and 64-bit platforms.
+
+type gpioChipInfo struct {
+	Name  [32]byte
+	Label [32]byte
+	Lines uint32
+}
+
+type gpioLineAttribute struct {
+	ID    uint32
+	_     uint32
+	Value uint64 // flags, values or debounce_period_us
+}
+
+type gpioLineConfigAttribute struct {
+	Attr gpioLineAttribute
+	Mask uint64
+}
+
+type gpioLineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	_        [5]uint32
+	Attrs    [10]gpioLineConfigAttribute
+}
+
+type gpioLineRequest struct {
+	Offsets         [64]uint32
+	Consumer        [32]byte
+	Config          gpioLineConfig
+	NumLines        uint32
+	EventBufferSize uint32
+	_               [5]uint32
+	Fd              int32
+}
+
+type gpioLineInfo struct {
+	Name     [32]byte
+	Consumer [32]byte
+	Offset   uint32
+	NumAttrs uint32
+	Flags    uint64
+	Attrs    [10]gpioLineAttribute
+	_        [4]uint32
+}
+
+type gpioLineValues struct {
+	Bits uint64
+	Mask uint64
+}
+
+func ioctl(fd int, req uintptr, arg unsafe.Pointer) error {
+	for {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(arg))
+		switch errno {
+		case 0:
+			return nil
+		case syscall.EINTR:
+			continue
+		}
+		return errno
+	}
+}
+
+// GPIOLine is a GPIO line requested through the Linux GPIO character device
+// (/dev/gpiochipN, uAPI v2). It works on every board with a kernel GPIO driver,
+// without periph.io support for the SoC.
+type GPIOLine struct {
+	fd     int
+	name   string
+	mu     sync.Mutex
+	flags  uint64 // current direction and bias flags, without edges
+	output bool
+
+	// Edge watching
+	stop chan struct{}
+	done chan struct{}
+	wake [2]int // pipe waking the watcher up on Unwatch
+}
+
+// OpenGPIOLine requests a line of a GPIO chip as an input.
+// chip is a device path ("/dev/gpiochip0"), a chip name ("gpiochip0"), a chip number ("0")
+// or the chip label ("pinctrl-bcm2711"). line is a line offset ("25") or a line name ("GPIO25").
+// consumer labels the line in the kernel (gpioinfo); it defaults to "nrf24".
+func OpenGPIOLine(chip, line, consumer string) (*GPIOLine, error) {
+	path, err := findGPIOChip(chip)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GPIO chip: %w", err)
+	}
+	defer f.Close()
+	chipFd := int(f.Fd())
+
+	offset, err := findGPIOLine(chipFd, line)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if consumer == "" {
+		consumer = "nrf24"
+	}
+	var req gpioLineRequest
+	req.Offsets[0] = offset
+	req.NumLines = 1
+	copy(req.Consumer[:len(req.Consumer)-1], consumer)
+	req.Config.Flags = _GPIO_V2_LINE_FLAG_INPUT
+	if err := ioctl(chipFd, _GPIO_V2_GET_LINE_IOCTL, unsafe.Pointer(&req)); err != nil {
+		return nil, fmt.Errorf("failed to request GPIO line %s of %s: %w", line, path, err)
+	}
+	return &GPIOLine{
+		fd:    int(req.Fd),
+		name:  fmt.Sprintf("%s:%d", filepath.Base(path), offset),
+		flags: _GPIO_V2_LINE_FLAG_INPUT,
+	}, nil
+}
+
+// findGPIOChip returns the device path of a GPIO chip given by path, name, number or label.
+func findGPIOChip(chip string) (string, error) {
+	switch {
+	case strings.HasPrefix(chip, "/"):
+		return chip, nil
+	case strings.HasPrefix(chip, "gpiochip"):
+		return "/dev/" + chip, nil
+	}
+	if _, err := strconv.Atoi(chip); err == nil {
+		return "/dev/gpiochip" + chip, nil
+	}
+
+	paths, _ := filepath.Glob("/dev/gpiochip*")
+	for _, path := range paths {
+		f, err := os.OpenFile(path, os.O_RDWR|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			continue
+		}
+		var info gpioChipInfo
+		err = ioctl(int(f.Fd()), _GPIO_GET_CHIPINFO_IOCTL, unsafe.Pointer(&info))
+		f.Close()
+		if err == nil && cString(info.Label[:]) == chip {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("GPIO chip %q not found", chip)
+}
+
+// findGPIOLine returns the offset of a line given by offset or name.
+func findGPIOLine(chipFd int, line string) (uint32, error) {
+	if n, err := strconv.ParseUint(line, 10, 32); err == nil {
+		return uint32(n), nil
+	}
+	var chip gpioChipInfo
+	if err := ioctl(chipFd, _GPIO_GET_CHIPINFO_IOCTL, unsafe.Pointer(&chip)); err != nil {
+		return 0, fmt.Errorf("failed to read chip info: %w", err)
+	}
+	for offset := uint32(0); offset < chip.Lines; offset++ {
+		info := gpioLineInfo{Offset: offset}
+		if err := ioctl(chipFd, _GPIO_V2_GET_LINEINFO_IOCTL, unsafe.Pointer(&info)); err != nil {
+			return 0, fmt.Errorf("failed to read line %d info: %w", offset, err)
+		}
+		if cString(info.Name[:]) == line {
+			return offset, nil
+		}
+	}
+	return 0, fmt.Errorf("GPIO line %q not found", line)
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// String returns the chip and offset of the line, e.g. "gpiochip0:25".
+func (p *GPIOLine) String() string {
+	return p.name
+}
+
+// setConfig reconfigures the line. Called with p.mu held.
+func (p *GPIOLine) setConfig(flags uint64, value Level) error {
+	var c gpioLineConfig
+	c.Flags = flags
+	if flags&_GPIO_V2_LINE_FLAG_OUTPUT != 0 {
+		c.NumAttrs = 1
+		c.Attrs[0].Attr.ID = _GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES
+		if value == High {
+			c.Attrs[0].Attr.Value = 1
+		}
+		c.Attrs[0].Mask = 1
+	}
+	if err := ioctl(p.fd, _GPIO_V2_LINE_SET_CONFIG_IOCTL, unsafe.Pointer(&c)); err != nil {
+		return fmt.Errorf("failed to configure GPIO line %s: %w", p.name, err)
+	}
+	return nil
+}
+
+func (p *GPIOLine) Out(l Level) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.output {
+		// Fast path for CE toggling: only set the value
+		v := gpioLineValues{Mask: 1}
+		if l == High {
+			v.Bits = 1
+		}
+		if err := ioctl(p.fd, _GPIO_V2_LINE_SET_VALUES_IOCTL, unsafe.Pointer(&v)); err != nil {
+			return fmt.Errorf("failed to set GPIO line %s: %w", p.name, err)
+		}
+		return nil
+	}
+	if err := p.setConfig(_GPIO_V2_LINE_FLAG_OUTPUT, l); err != nil {
+		return err
+	}
+	p.flags = _GPIO_V2_LINE_FLAG_OUTPUT
+	p.output = true
+	return nil
+}
+
+func gpioBias(pull Pull) uint64 {
+	switch pull {
+	case PullFloat:
+		return _GPIO_V2_LINE_FLAG_BIAS_DISABLED
+	case PullDown:
+		return _GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN
+	case PullUp:
+		return _GPIO_V2_LINE_FLAG_BIAS_PULL_UP
+	}
+	return 0
+}
+
+func (p *GPIOLine) In(pull Pull) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	flags := _GPIO_V2_LINE_FLAG_INPUT | gpioBias(pull)
+	if pull == PullNoChange {
+		flags = _GPIO_V2_LINE_FLAG_INPUT | p.flags&^(_GPIO_V2_LINE_FLAG_INPUT|_GPIO_V2_LINE_FLAG_OUTPUT)
+	}
+	if err := p.setConfig(flags, Low); err != nil {
+		return err
+	}
+	p.flags = flags
+	p.output = false
+	return nil
+}
+
+func (p *GPIOLine) Read() Level {
+	v := gpioLineValues{Mask: 1}
+	if err := ioctl(p.fd, _GPIO_V2_LINE_GET_VALUES_IOCTL, unsafe.Pointer(&v)); err != nil {
+		return Low
+	}
+	if v.Bits&1 != 0 {
+		return High
+	}
+	return Low
+}
+
+// Watch configures the line as an input with pull-up and edge detection, and calls handler
+// from a goroutine for every edge event reported by the kernel.
+func (p *GPIOLine) Watch(edge Edge, handler func()) error {
+	var edgeFlags uint64
+	switch edge {
+	case RisingEdge:
+		edgeFlags = _GPIO_V2_LINE_FLAG_EDGE_RISING
+	case FallingEdge:
+		edgeFlags = _GPIO_V2_LINE_FLAG_EDGE_FALLING
+	case BothEdges:
+		edgeFlags = _GPIO_V2_LINE_FLAG_EDGE_RISING | _GPIO_V2_LINE_FLAG_EDGE_FALLING
+	default:
+		return p.Unwatch()
+	}
+	if err := p.Unwatch(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var flags uint64 = _GPIO_V2_LINE_FLAG_INPUT | _GPIO_V2_LINE_FLAG_BIAS_PULL_UP
+	if err := p.setConfig(flags|edgeFlags, Low); err != nil {
+		return err
+	}
+	p.flags = flags
+	p.output = false
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("failed to create epoll instance: %w", err)
+	}
+	var wake [2]int
+	if err := syscall.Pipe2(wake[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		syscall.Close(epfd)
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	for _, fd := range []int{p.fd, wake[0]} {
+		ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+		if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+			syscall.Close(epfd)
+			syscall.Close(wake[0])
+			syscall.Close(wake[1])
+			return fmt.Errorf("failed to watch GPIO line %s: %w", p.name, err)
+		}
+	}
+
+	p.wake = wake
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.watch(epfd, handler, p.stop, p.done)
+	return nil
+}
+
+// watch waits for edge events until stop is closed.
+func (p *GPIOLine) watch(epfd int, handler func(), stop, done chan struct{}) {
+	defer close(done)
+	defer syscall.Close(epfd)
+
+	events := make([]syscall.EpollEvent, 2)
+	buf := make([]byte, 16*_GPIO_V2_LINE_EVENT_SIZE)
+	for {
+		n, err := syscall.EpollWait(epfd, events, -1)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		for _, ev := range events[:n] {
+			if int(ev.Fd) != p.fd {
+				continue
+			}
+			// Consume the queued events: one handler call per edge
+			r, err := syscall.Read(p.fd, buf)
+			if err != nil {
+				continue
+			}
+			for i := 0; i < r/_GPIO_V2_LINE_EVENT_SIZE; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+					handler()
+				}
+			}
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
+
+// Unwatch stops the edge watcher and disables edge detection.
+func (p *GPIOLine) Unwatch() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stop == nil {
+		return nil
+	}
+	close(p.stop)
+	syscall.Write(p.wake[1], []byte{0})
+	<-p.done
+	syscall.Close(p.wake[0])
+	syscall.Close(p.wake[1])
+	p.stop, p.done = nil, nil
+
+	return p.setConfig(p.flags, Low)
+}
+
+// Close stops watching and releases the line.
+func (p *GPIOLine) Close() error {
+	p.Unwatch()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fd < 0 {
+		return nil
+	}
+	err := syscall.Close(p.fd)
+	p.fd = -1
+	return err
+}
+
+// openGPIOLines requests the CE, IRQ and chip select lines of c.GPIOChip for New.
+// irq and cs are nil if not configured.
+func openGPIOLines(c Config) (ce, irq, cs Pin, lines []io.Closer, err error) {
+	consumer := "nrf24"
+	if c.Name != "" {
+		consumer = "nrf24 " + c.Name
+	}
+	open := func(role, line string, pin int) (Pin, error) {
+		if line == "" && pin != 0 {
+			line = strconv.Itoa(pin)
+		}
+		if line == "" {
+			return nil, nil
+		}
+		l, err := OpenGPIOLine(c.GPIOChip, line, consumer+" "+role)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s pin: %w", role, err)
+		}
+		lines = append(lines, l)
+		return l, nil
+	}
+	release := func() {
+		for _, l := range lines {
+			l.Close()
+		}
+	}
+
+	if ce, err = open("CE", c.CELine, c.CEPin); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if irq, err = open("IRQ", c.IRQLine, c.IRQPin); err != nil {
+		release()
+		return nil, nil, nil, nil, err
+	}
+	if cs, err = open("CS", c.CSLine, c.CSPin); err != nil {
+		release()
+		return nil, nil, nil, nil, err
+	}
+	return ce, irq, cs, lines, nil
+}
@@ -0,0 +1,41 @@
+//go:build linux && !tinygo
+
+package nrf24
+
+import "testing"
+
+func TestGPIOIoctlNumbers(t *testing.T) {
+	// Values of the linux/gpio.h macros
+	tests := []struct {
+		name string
+		got  uintptr
+		want uintptr
+	}{
+		{"GPIO_GET_CHIPINFO_IOCTL", _GPIO_GET_CHIPINFO_IOCTL, 0x8044B401},
+		{"GPIO_V2_GET_LINEINFO_IOCTL", _GPIO_V2_GET_LINEINFO_IOCTL, 0xC100B405},
+		{"GPIO_V2_GET_LINE_IOCTL", _GPIO_V2_GET_LINE_IOCTL, 0xC250B407},
+		{"GPIO_V2_LINE_SET_CONFIG_IOCTL", _GPIO_V2_LINE_SET_CONFIG_IOCTL, 0xC110B40D},
+		{"GPIO_V2_LINE_GET_VALUES_IOCTL", _GPIO_V2_LINE_GET_VALUES_IOCTL, 0xC010B40E},
+		{"GPIO_V2_LINE_SET_VALUES_IOCTL", _GPIO_V2_LINE_SET_VALUES_IOCTL, 0xC010B40F},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %#X, want %#X", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestFindGPIOChip(t *testing.T) {
+	for chip, want := range map[string]string{
+		"/dev/gpiochip2": "/dev/gpiochip2",
+		"gpiochip1":      "/dev/gpiochip1",
+		"0":              "/dev/gpiochip0",
+	} {
+		if got, err := findGPIOChip(chip); err != nil || got != want {
+			t.Errorf("findGPIOChip(%q) = %q, %v, want %q", chip, got, err, want)
+		}
+	}
+	if _, err := findGPIOChip("no-such-chip-label"); err == nil {
+		t.Error("Expected an error for an unknown chip label")
+	}
+}
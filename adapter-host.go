@@ -0,0 +1,249 @@
+//go:build !tinygo
+
+package nrf24
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Config holds the configuration for the Linux/periph.io driver.
+type Config struct {
+	RadioConfig
+	// CEPin is the GPIO pin number (BCM numbering) for the Chip Enable (CE) pin.
+	// Defaults to 25 if not provided.
+	CEPin int
+	// IRQPin is the GPIO pin number (BCM numbering) for the Interrupt Request (IRQ) pin.
+	// Optional. If not provided, polling is used.
+	IRQPin int
+	// GPIOChip is the Linux GPIO character device driving CE and IRQ instead of periph.io,
+	// for boards periph.io has no GPIO driver for: a path ("/dev/gpiochip0"), a name
+	// ("gpiochip0"), a number ("0") or the chip label ("pinctrl-bcm2711").
+	// Optional.
+	GPIOChip string
+	// CELine is the name ("GPIO25") or offset ("25") of the CE line of GPIOChip.
+	// Defaults to CEPin as an offset.
+	CELine string
+	// IRQLine is the name or offset of the IRQ line of GPIOChip.
+	// Defaults to IRQPin as an offset if provided.
+	IRQLine string
+	// CSPin is the GPIO pin number (BCM numbering) of a chip select driven by the driver,
+	// for more radios than the hardware chip selects of the bus.
+	// SpiBusPath must then name a chip select of the controller that is not wired to a radio.
+	// Optional. If not provided, the hardware chip select of SpiBusPath is used.
+	CSPin int
+	// CSLine is the name or offset of the chip select line of GPIOChip.
+	// Defaults to CSPin as an offset if provided.
+	CSLine string
+	// SpiBusPath is the path to the SPI bus (e.g., "/dev/spidev0.0").
+	// Defaults to "/dev/spidev0.0" if not provided.
+	// Radios on the same bus (e.g. "/dev/spidev0.0" and "/dev/spidev0.1") share a Bus,
+	// and radios with the same path share the connection opened by the first one, which
+	// requires the same SpiClockHz and NativeSPI.
+	SpiBusPath string
+	// SpiClockHz is the SPI clock frequency in Hz.
+	// Defaults to 1000000 (1MHz) if not provided.
+	SpiClockHz int
+	// NativeSPI opens SpiBusPath with the spidev ioctl adapter (OpenSPIDev) instead of periph.io.
+	// Together with GPIOChip, New does not initialize periph.io at all.
+	// Optional.
+	NativeSPI bool
+	// WrapSPI wraps the SPI connection, e.g. to record it with spitrace.NewRecorder.
+	// Optional.
+	WrapSPI func(SPI) SPI
+	// Name identifies the device in log messages.
+	// Optional.
+	Name string
+	// Logger receives the log messages of the device.
+	// Optional. If not provided, the global logger is used.
+	Logger Logger
+	// Clock provides the time and the delays of the device, e.g. a SpinClock for precise
+	// CE pulses.
+	// Optional. If not provided, SystemClock is used.
+	Clock Clock
+}
+
+// New creates and initializes a new NRF24L01 driver for Linux systems.
+// It applies configuration defaults, initializes the GPIO and SPI interfaces using periph.io
+// (or the Linux GPIO character device and spidev, see GPIOChip and NativeSPI),
+// and configures the radio module.
+// It returns the initialized driver or an error if hardware initialization fails.
+func New(c Config) (*Device, error) {
+	// 1. Initialize periph.io host (Required for periph.io SPI and GPIO)
+	if !c.NativeSPI || c.GPIOChip == "" {
+		if err := initPeriph(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Default SPI Path and Clock
+	if c.SpiBusPath == "" {
+		c.SpiBusPath = "/dev/spidev0.0"
+	}
+	if c.SpiClockHz == 0 {
+		c.SpiClockHz = 1000000
+	}
+
+	// 3. Open (or share) the SPI Port
+	conn, p, err := openSharedSPI(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Setup CE Pin
+	if c.CEPin == 0 {
+		c.CEPin = 25
+	}
+	var ceWrapper, irqWrapper, csWrapper Pin
+	var lines []io.Closer
+	if c.GPIOChip != "" {
+		ceWrapper, irqWrapper, csWrapper, lines, err = openGPIOLines(c)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+	} else {
+		ceWrapper, err = openPeriphPin("CE", c.CEPin)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+
+		// 5. Setup IRQ and CS Pins
+		if c.IRQPin != 0 {
+			if irqWrapper, err = openPeriphPin("IRQ", c.IRQPin); err != nil {
+				p.Close()
+				return nil, err
+			}
+		}
+		if c.CSPin != 0 {
+			if csWrapper, err = openPeriphPin("CS", c.CSPin); err != nil {
+				p.Close()
+				return nil, err
+			}
+		}
+	}
+	closeAll := func() {
+		p.Close()
+		for _, l := range lines {
+			l.Close()
+		}
+	}
+
+	// Serialize the transactions with the other radios of the bus
+	conn, err = sharedBus(c.SpiBusPath).Conn(conn, csWrapper)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	// 6. Call internal constructor
+	hwConfig := HardwareConfig{
+		RadioConfig: c.RadioConfig,
+		CE:          ceWrapper,
+		IRQ:         irqWrapper,
+		Name:        c.Name,
+		Logger:      c.Logger,
+		Clock:       c.Clock,
+	}
+	if c.WrapSPI != nil {
+		conn = c.WrapSPI(conn)
+	}
+	dev, err := NewWithHardware(hwConfig, conn)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	// Store the port and line closers so we can close them later
+	dev.nrfPort = p
+	dev.gpioLines = lines
+	return dev, nil
+}
+
+// sharedSPI is an SPI connection opened by New, shared by the radios with the same SpiBusPath.
+type sharedSPI struct {
+	conn SPI
+	port io.Closer
+	refs int
+	// clockHz and native are the settings the connection was opened with
+	clockHz int
+	native  bool
+}
+
+var (
+	sharedMu    sync.Mutex
+	sharedConns = map[string]*sharedSPI{}
+	sharedBuses = map[string]*Bus{}
+)
+
+// openSharedSPI opens the SPI connection of c.SpiBusPath, or shares the one already opened
+// with the same SpiClockHz and NativeSPI.
+// Closing the returned closer releases the connection, closing it after the last radio.
+func openSharedSPI(c Config) (SPI, io.Closer, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	s := sharedConns[c.SpiBusPath]
+	if s != nil && (s.clockHz != c.SpiClockHz || s.native != c.NativeSPI) {
+		return nil, nil, fmt.Errorf("SPI bus %s already opened with SpiClockHz %d and NativeSPI %v",
+			c.SpiBusPath, s.clockHz, s.native)
+	}
+	if s == nil {
+		s = &sharedSPI{clockHz: c.SpiClockHz, native: c.NativeSPI}
+		if c.NativeSPI {
+			conn, port, err := openSPIDev(c)
+			if err != nil {
+				return nil, nil, err
+			}
+			s.conn, s.port = conn, port
+		} else {
+			conn, port, err := openPeriphSPI(c)
+			if err != nil {
+				return nil, nil, err
+			}
+			s.conn, s.port = conn, port
+		}
+		sharedConns[c.SpiBusPath] = s
+	}
+	s.refs++
+
+	var once sync.Once
+	release := closerFunc(func() error {
+		var err error
+		once.Do(func() {
+			sharedMu.Lock()
+			defer sharedMu.Unlock()
+			if s.refs--; s.refs == 0 {
+				delete(sharedConns, c.SpiBusPath)
+				err = s.port.Close()
+			}
+		})
+		return err
+	})
+	return s.conn, release, nil
+}
+
+// sharedBus returns the Bus of the radios on the same SPI bus as path.
+// "/dev/spidev0.0" and "/dev/spidev0.1" are both on bus "/dev/spidev0".
+func sharedBus(path string) *Bus {
+	name := path
+	if i := strings.LastIndexByte(path, '.'); i > 0 && strings.Contains(path[:i], "spidev") {
+		name = path[:i]
+	}
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	b := sharedBuses[name]
+	if b == nil {
+		b = NewBus()
+		sharedBuses[name] = b
+	}
+	return b
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
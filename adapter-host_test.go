@@ -0,0 +1,32 @@
+//go:build !tinygo
+
+package nrf24
+
+import "testing"
+
+func TestSharedSPISettings(t *testing.T) {
+	const path = "/dev/spidev9.0"
+	sharedMu.Lock()
+	sharedConns[path] = &sharedSPI{conn: &mockSPIConn{}, port: closerFunc(func() error { return nil }), clockHz: 1000000}
+	sharedMu.Unlock()
+	t.Cleanup(func() {
+		sharedMu.Lock()
+		delete(sharedConns, path)
+		sharedMu.Unlock()
+	})
+
+	conn, release, err := openSharedSPI(Config{SpiBusPath: path, SpiClockHz: 1000000})
+	if err != nil || conn == nil {
+		t.Fatalf("Expected the connection to be shared, got %v", err)
+	}
+	defer release.Close()
+
+	for _, c := range []Config{
+		{SpiBusPath: path, SpiClockHz: 8000000},
+		{SpiBusPath: path, SpiClockHz: 1000000, NativeSPI: true},
+	} {
+		if _, _, err := openSharedSPI(c); err == nil {
+			t.Errorf("Expected an error sharing the connection with SpiClockHz %d and NativeSPI %v", c.SpiClockHz, c.NativeSPI)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !tinygo && noperiph
+
+package nrf24
+
+import (
+	"errors"
+	"io"
+)
+
+// errNoPeriph is returned by New for the hardware only periph.io drives, when built with the
+// noperiph tag.
+var errNoPeriph = errors.New("built without periph.io (noperiph tag): set GPIOChip and NativeSPI")
+
+// initPeriph is not available without periph.io.
+func initPeriph() error {
+	return errNoPeriph
+}
+
+// openPeriphPin is not available without periph.io.
+func openPeriphPin(role string, num int) (Pin, error) {
+	return nil, errNoPeriph
+}
+
+// openPeriphSPI is not available without periph.io.
+func openPeriphSPI(c Config) (SPI, io.Closer, error) {
+	return nil, nil, errNoPeriph
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !tinygo
+
+package nrf24
+
+import (
+	"errors"
+	"io"
+)
+
+// openGPIOLines is only supported on Linux.
+func openGPIOLines(c Config) (ce, irq, cs Pin, lines []io.Closer, err error) {
+	return nil, nil, nil, nil, errors.New("GPIOChip requires the Linux GPIO character device")
+}
+
+// openSPIDev is only supported on Linux.
+func openSPIDev(c Config) (SPI, io.Closer, error) {
+	return nil, nil, errors.New("NativeSPI requires the Linux spidev driver")
+}
@@ -1,9 +1,10 @@
-//go:build !tinygo
+//go:build !tinygo && !noperiph
 
 package nrf24
 
 import (
 	"fmt"
+	"io"
 
 	"periph.io/x/conn/v3/gpio"
 	"periph.io/x/conn/v3/gpio/gpioreg"
@@ -100,93 +101,36 @@ func (p *realPin) Unwatch() error {
 	return p.PinIO.In(gpio.PullUp, gpio.NoEdge)
 }
 
-// Config holds the configuration for the Linux/periph.io driver.
-type Config struct {
-	RadioConfig
-	// CEPin is the GPIO pin number (BCM numbering) for the Chip Enable (CE) pin.
-	// Defaults to 25 if not provided.
-	CEPin int
-	// IRQPin is the GPIO pin number (BCM numbering) for the Interrupt Request (IRQ) pin.
-	// Optional. If not provided, polling is used.
-	IRQPin int
-	// SpiBusPath is the path to the SPI bus (e.g., "/dev/spidev0.0").
-	// Defaults to "/dev/spidev0.0" if not provided.
-	SpiBusPath string
-	// SpiClockHz is the SPI clock frequency in Hz.
-	// Defaults to 1000000 (1MHz) if not provided.
-	SpiClockHz int
-}
-
-// New creates and initializes a new NRF24L01 driver for Linux systems.
-// It applies configuration defaults, initializes the GPIO and SPI interfaces using periph.io,
-// and configures the radio module.
-// It returns the initialized driver or an error if hardware initialization fails.
-func New(c Config) (*Device, error) {
-	// 1. Initialize periph.io host (Required for both SPI and GPIO)
+// initPeriph initializes the periph.io drivers of the host.
+func initPeriph() error {
 	if _, err := host.Init(); err != nil {
-		return nil, fmt.Errorf("failed to initialize periph.io host: %w", err)
-	}
-
-	// 2. Default SPI Path
-	if c.SpiBusPath == "" {
-		c.SpiBusPath = "/dev/spidev0.0"
-	}
-
-	// 3. Open the SPI Port
-	p, err := spireg.Open(c.SpiBusPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open SPI port: %w", err)
+		return fmt.Errorf("failed to initialize periph.io host: %w", err)
 	}
+	return nil
+}
 
-	// 4. Default Clock
-	if c.SpiClockHz == 0 {
-		c.SpiClockHz = 1000000
+// openPeriphPin opens a GPIO pin by BCM number with periph.io.
+func openPeriphPin(role string, num int) (Pin, error) {
+	name := fmt.Sprintf("GPIO%d", num)
+	p := gpioreg.ByName(name)
+	if p == nil {
+		return nil, fmt.Errorf("failed to open %s pin %s", role, name)
 	}
+	return &realPin{PinIO: p}, nil
+}
 
-	// 5. Create the SPI Connection (Mode 0, 8 bits)
-	conn, err := p.Connect(physic.Frequency(c.SpiClockHz)*physic.Hertz, spi.Mode0, 8)
+// openPeriphSPI opens c.SpiBusPath with periph.io.
+func openPeriphSPI(c Config) (SPI, io.Closer, error) {
+	port, err := spireg.Open(c.SpiBusPath)
 	if err != nil {
-		p.Close()
-		return nil, fmt.Errorf("failed to create SPI connection: %w", err)
-	}
-
-	// 6. Setup CE Pin
-	if c.CEPin == 0 {
-		c.CEPin = 25
-	}
-	ceName := fmt.Sprintf("GPIO%d", c.CEPin)
-	realCe := gpioreg.ByName(ceName)
-	if realCe == nil {
-		p.Close()
-		return nil, fmt.Errorf("failed to open CE pin %s", ceName)
-	}
-	ceWrapper := &realPin{PinIO: realCe}
-
-	// 7. Setup IRQ Pin
-	var irqWrapper Pin
-	if c.IRQPin != 0 {
-		irqName := fmt.Sprintf("GPIO%d", c.IRQPin)
-		realIrq := gpioreg.ByName(irqName)
-		if realIrq == nil {
-			p.Close()
-			return nil, fmt.Errorf("failed to open IRQ pin %s", irqName)
-		}
-		irqWrapper = &realPin{PinIO: realIrq}
+		return nil, nil, fmt.Errorf("failed to open SPI port: %w", err)
 	}
 
-	// 8. Call internal constructor
-	hwConfig := HardwareConfig{
-		RadioConfig: c.RadioConfig,
-		CE:          ceWrapper,
-		IRQ:         irqWrapper,
-	}
-	dev, err := NewWithHardware(hwConfig, conn)
+	// Create the SPI Connection (Mode 0, 8 bits)
+	conn, err := port.Connect(physic.Frequency(c.SpiClockHz)*physic.Hertz, spi.Mode0, 8)
 	if err != nil {
-		p.Close()
-		return nil, err
+		port.Close()
+		return nil, nil, fmt.Errorf("failed to create SPI connection: %w", err)
 	}
-
-	// Store the port closer so we can close it later
-	dev.nrfPort = p
-	return dev, nil
+	return conn, port, nil
 }
@@ -0,0 +1,199 @@
+//go:build linux && !tinygo
+
+package nrf24
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// spidev uAPI (linux/spi/spidev.h).
+const (
+	_SPI_IOC_WR_MODE          = 0x40016B01 // _IOW('k', 1, __u8)
+	_SPI_IOC_WR_BITS_PER_WORD = 0x40016B03 // _IOW('k', 3, __u8)
+	_SPI_IOC_WR_MAX_SPEED_HZ  = 0x40046B04 // _IOW('k', 4, __u32)
+
+	// Maximum number of transfers of one SPI_IOC_MESSAGE, limited by the 14-bit ioctl size
+	_SPI_IOC_MAX_TRANSFERS = (1<<14 - 1) / int(unsafe.Sizeof(spiIocTransfer{}))
+)
+
+// spiIocMessage returns SPI_IOC_MESSAGE(n), _IOW('k', 0, char[n * sizeof(struct spi_ioc_transfer)]).
+func spiIocMessage(n int) uintptr {
+	return 1<<30 | uintptr(n)*unsafe.Sizeof(spiIocTransfer{})<<16 | 'k'<<8
+}
+
+// spiIocTransfer mirrors struct spi_ioc_transfer.
+type spiIocTransfer struct {
+	TxBuf          uint64
+	RxBuf          uint64
+	Len            uint32
+	SpeedHz        uint32
+	DelayUsecs     uint16
+	BitsPerWord    uint8
+	CSChange       uint8
+	TxNbits        uint8
+	RxNbits        uint8
+	WordDelayUsecs uint8
+	_              uint8
+}
+
+// SPIDevConfig configures an SPIDev.
+type SPIDevConfig struct {
+	// Mode is the SPI mode (0-3), CPOL and CPHA.
+	// Defaults to 0, as required by the nRF24L01+.
+	Mode byte
+	// BitsPerWord is the word size.
+	// Defaults to 8 if not provided.
+	BitsPerWord byte
+	// SpeedHz is the maximum clock frequency in Hz.
+	// Defaults to 1000000 (1MHz) if not provided.
+	SpeedHz uint32
+	// DelayUsecs is the delay after each transfer, before CS is deasserted.
+	// Optional.
+	DelayUsecs uint16
+	// KeepCS keeps CS asserted between the transfers of a batch (no cs_change).
+	// By default CS is deasserted between transfers, since every nRF24L01+ command
+	// starts on a falling CS edge.
+	KeepCS bool
+}
+
+// SPIDev is an SPI connection using the Linux spidev driver (/dev/spidevB.C) directly
+// through SPI_IOC_MESSAGE ioctls, without periph.io.
+type SPIDev struct {
+	mu   sync.Mutex
+	fd   int
+	cfg  SPIDevConfig
+	xfer []spiIocTransfer
+}
+
+var _ BatchSPI = (*SPIDev)(nil)
+
+// OpenSPIDev opens an spidev device such as "/dev/spidev0.0" and configures its mode,
+// word size and clock.
+func OpenSPIDev(path string, c SPIDevConfig) (*SPIDev, error) {
+	if c.Mode > 3 {
+		return nil, fmt.Errorf("invalid SPI mode %d", c.Mode)
+	}
+	if c.BitsPerWord == 0 {
+		c.BitsPerWord = 8
+	}
+	if c.SpeedHz == 0 {
+		c.SpeedHz = 1000000
+	}
+
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SPI device %s: %w", path, err)
+	}
+
+	mode, bits, speed := c.Mode, c.BitsPerWord, c.SpeedHz
+	for _, s := range []struct {
+		name string
+		req  uintptr
+		arg  unsafe.Pointer
+	}{
+		{"mode", _SPI_IOC_WR_MODE, unsafe.Pointer(&mode)},
+		{"bits per word", _SPI_IOC_WR_BITS_PER_WORD, unsafe.Pointer(&bits)},
+		{"max speed", _SPI_IOC_WR_MAX_SPEED_HZ, unsafe.Pointer(&speed)},
+	} {
+		if err := ioctl(fd, s.req, s.arg); err != nil {
+			syscall.Close(fd)
+			return nil, fmt.Errorf("failed to set SPI %s of %s: %w", s.name, path, err)
+		}
+	}
+	return &SPIDev{fd: fd, cfg: c}, nil
+}
+
+// Tx sends w and reads into r in a single transfer.
+// len(r) must be >= len(w).
+func (s *SPIDev) Tx(w, r []byte) error {
+	return s.TxBatch([]SPITransfer{{Write: w, Read: r}})
+}
+
+// TxBatch performs several transfers with a single ioctl. CS is deasserted between
+// transfers unless the device was opened with KeepCS. SpeedHz, DelayUsecs and BitsPerWord
+// of a transfer override the device settings when not zero.
+func (s *SPIDev) TxBatch(t []SPITransfer) error {
+	if len(t) == 0 {
+		return nil
+	}
+	if len(t) > _SPI_IOC_MAX_TRANSFERS {
+		return fmt.Errorf("too many SPI transfers in a batch: %d, max %d", len(t), _SPI_IOC_MAX_TRANSFERS)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fd < 0 {
+		return fmt.Errorf("SPI device closed")
+	}
+
+	// The kernel reads and writes the buffers after the ioctl call: pin them
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	s.xfer = s.xfer[:0]
+	for i, tr := range t {
+		if tr.Read != nil && len(tr.Read) < len(tr.Write) {
+			return fmt.Errorf("SPI transfer %d: read buffer shorter than write buffer", i)
+		}
+		x := spiIocTransfer{
+			Len:         uint32(len(tr.Write)),
+			SpeedHz:     s.cfg.SpeedHz,
+			DelayUsecs:  s.cfg.DelayUsecs,
+			BitsPerWord: s.cfg.BitsPerWord,
+		}
+		if tr.SpeedHz != 0 {
+			x.SpeedHz = tr.SpeedHz
+		}
+		if tr.DelayUsecs != 0 {
+			x.DelayUsecs = tr.DelayUsecs
+		}
+		if tr.BitsPerWord != 0 {
+			x.BitsPerWord = tr.BitsPerWord
+		}
+		// cs_change on the last transfer would leave CS asserted after the message
+		if !s.cfg.KeepCS && i < len(t)-1 {
+			x.CSChange = 1
+		}
+		if len(tr.Write) > 0 {
+			pinner.Pin(&tr.Write[0])
+			x.TxBuf = uint64(uintptr(unsafe.Pointer(&tr.Write[0])))
+			if len(tr.Read) > 0 {
+				pinner.Pin(&tr.Read[0])
+				x.RxBuf = uint64(uintptr(unsafe.Pointer(&tr.Read[0])))
+			}
+		}
+		s.xfer = append(s.xfer, x)
+	}
+	pinner.Pin(&s.xfer[0])
+
+	if err := ioctl(s.fd, spiIocMessage(len(s.xfer)), unsafe.Pointer(&s.xfer[0])); err != nil {
+		return fmt.Errorf("SPI transfer failed: %w", err)
+	}
+	return nil
+}
+
+// Close releases the SPI device.
+func (s *SPIDev) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fd < 0 {
+		return nil
+	}
+	err := syscall.Close(s.fd)
+	s.fd = -1
+	return err
+}
+
+// openSPIDev opens c.SpiBusPath with the native spidev adapter for New.
+func openSPIDev(c Config) (SPI, io.Closer, error) {
+	dev, err := OpenSPIDev(c.SpiBusPath, SPIDevConfig{SpeedHz: uint32(c.SpiClockHz)})
+	if err != nil {
+		return nil, nil, err
+	}
+	return dev, dev, nil
+}
@@ -0,0 +1,33 @@
+//go:build linux && !tinygo
+
+package nrf24
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestSPIIoctlNumbers(t *testing.T) {
+	if size := unsafe.Sizeof(spiIocTransfer{}); size != 32 {
+		t.Errorf("sizeof(spi_ioc_transfer) = %d, want 32", size)
+	}
+	// Values of the linux/spi/spidev.h macros
+	if got := spiIocMessage(1); got != 0x40206B00 {
+		t.Errorf("SPI_IOC_MESSAGE(1) = %#X, want 0x40206B00", got)
+	}
+	if got := spiIocMessage(3); got != 0x40606B00 {
+		t.Errorf("SPI_IOC_MESSAGE(3) = %#X, want 0x40606B00", got)
+	}
+	if _SPI_IOC_MAX_TRANSFERS != 511 {
+		t.Errorf("Max transfers = %d, want 511", _SPI_IOC_MAX_TRANSFERS)
+	}
+}
+
+func TestOpenSPIDevErrors(t *testing.T) {
+	if _, err := OpenSPIDev("/dev/spidev0.0", SPIDevConfig{Mode: 4}); err == nil {
+		t.Error("Expected an error for SPI mode 4")
+	}
+	if _, err := OpenSPIDev("/nonexistent/spidev9.9", SPIDevConfig{}); err == nil {
+		t.Error("Expected an error for a missing device")
+	}
+}
@@ -86,6 +86,15 @@ type Config struct {
 	// IRQPin is the Interrupt Request (IRQ) pin.
 	// Use machine.NoPin if not using interrupts.
 	IRQPin machine.Pin
+	// Name identifies the device in log messages.
+	// Optional.
+	Name string
+	// Logger receives the log messages of the device.
+	// Optional. If not provided, the global logger is used.
+	Logger Logger
+	// Clock provides the time and the delays of the device.
+	// Optional. If not provided, SystemClock is used.
+	Clock Clock
 }
 
 // New creates a new NRF24L01 driver for TinyGo systems.
@@ -107,6 +116,9 @@ func New(c Config) (*Device, error) {
 		RadioConfig: c.RadioConfig,
 		CE:          ceWrapper,
 		IRQ:         irqWrapper,
+		Name:        c.Name,
+		Logger:      c.Logger,
+		Clock:       c.Clock,
 	}
 
 	return NewWithHardware(hwConfig, spiWrapper)
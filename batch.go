@@ -0,0 +1,84 @@
+package nrf24
+
+// SPITransfer is one transfer of a batch, see BatchSPI.
+type SPITransfer struct {
+	// Write holds the bytes to send.
+	Write []byte
+	// Read receives len(Write) bytes. It may be nil, or be Write itself.
+	Read []byte
+	// SpeedHz, DelayUsecs and BitsPerWord override the connection settings when not zero.
+	SpeedHz     uint32
+	DelayUsecs  uint16
+	BitsPerWord byte
+}
+
+// BatchSPI is an SPI connection performing several transfers in one call, each in its own
+// transaction (CS deasserted in between), such as SPIDev with a single ioctl.
+// When its connection implements BatchSPI, the Device sends its sequences of register writes
+// (configuration, target address) as a single batch.
+type BatchSPI interface {
+	SPI
+	// TxBatch performs the transfers in order.
+	TxBatch(t []SPITransfer) error
+}
+
+// writeBatch holds the register writes queued between beginBatch and endBatch.
+type writeBatch struct {
+	active bool
+	// buf holds the bytes of the queued transfers, ends the end offset of each of them
+	buf  []byte
+	ends []int
+	xfer []SPITransfer
+}
+
+// beginBatch queues the following register writes until endBatch, if the connection
+// performs batches. Registers must not be read before endBatch.
+// Call with lock held.
+func (d *Device) beginBatch() {
+	if _, ok := d.conn.(BatchSPI); ok {
+		d.batch.active = true
+	}
+}
+
+// endBatch sends the queued register writes in a single batch and reports whether it
+// succeeded. A failed batch leaves the shadow unknown, so that registers are read again.
+// Call with lock held.
+func (d *Device) endBatch() bool {
+	b := &d.batch
+	if !b.active {
+		return true
+	}
+	b.active = false
+	if len(b.ends) == 0 {
+		return true
+	}
+
+	b.xfer = b.xfer[:0]
+	start := 0
+	for _, end := range b.ends {
+		t := b.buf[start:end]
+		b.xfer = append(b.xfer, SPITransfer{Write: t, Read: t})
+		start = end
+	}
+	err := d.conn.(BatchSPI).TxBatch(b.xfer)
+	b.buf, b.ends = b.buf[:0], b.ends[:0]
+	if err != nil {
+		d.log(LogError, "SPI Transfer Error", Field{"error", err})
+		d.shadow.valid = 0
+		return false
+	}
+	return true
+}
+
+// transferWrite sends the write command held in the first n bytes of scratch, or queues it
+// while batching, and reports whether it succeeded.
+// Call with lock held.
+func (d *Device) transferWrite(n int) bool {
+	if d.batch.active {
+		d.batch.buf = append(d.batch.buf, d.scratch[:n]...)
+		d.batch.ends = append(d.batch.ends, len(d.batch.buf))
+		return true
+	}
+	_, data := d.spiTransfer(n)
+	return data != nil
+}
@@ -0,0 +1,89 @@
+package nrf24
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// batchSPI records the batches it performs.
+type batchSPI struct {
+	mockSPIConn
+	batches [][]SPITransfer
+	err     error
+}
+
+func (m *batchSPI) TxBatch(t []SPITransfer) error {
+	if m.err != nil {
+		return m.err
+	}
+	recorded := make([]SPITransfer, len(t))
+	for i, tr := range t {
+		recorded[i].Write = append([]byte(nil), tr.Write...)
+		m.Tx(tr.Write, tr.Read)
+	}
+	m.batches = append(m.batches, recorded)
+	return nil
+}
+
+func TestBatchedWrites(t *testing.T) {
+	plain := &mockSPIConn{}
+	if _, err := NewWithHardware(HardwareConfig{CE: &mockPin{}}, plain); err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+	batched := &batchSPI{}
+	dev, err := NewWithHardware(HardwareConfig{CE: &mockPin{}}, batched)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+
+	// The same commands are sent, the configuration registers in one batch
+	if !bytes.Equal(batched.tx, plain.tx) {
+		t.Errorf("Expected the batched commands to match: got %X, want %X", batched.tx, plain.tx)
+	}
+	if len(batched.batches) != 1 || len(batched.batches[0]) != 11 {
+		t.Fatalf("Expected one batch of 11 writes, got %d batches", len(batched.batches))
+	}
+	if w := batched.batches[0][0].Write; !bytes.Equal(w, []byte{0x20 | _RF_CH, 0}) {
+		t.Errorf("Expected the batch to start with RF_CH, got %X", w)
+	}
+
+	// TX_ADDR and RX_ADDR_P0 are written together
+	batched.batches = nil
+	dev.setTargetAddress(Address{1, 2, 3, 4, 5})
+	if len(batched.batches) != 1 || len(batched.batches[0]) != 2 || !dev.txAddrSet {
+		t.Errorf("Expected TX_ADDR and RX_ADDR_P0 in one batch, got %v", batched.batches)
+	}
+
+	// A failed batch leaves the registers unknown
+	batched.err = errors.New("bus error")
+	dev.setTargetAddress(Address{6, 7, 8, 9, 10})
+	if dev.txAddrSet || dev.shadow.valid != 0 {
+		t.Error("Expected a failed batch to invalidate the target address and the shadow")
+	}
+}
+
+func TestBusBatches(t *testing.T) {
+	bus := NewBus()
+	spi := &batchSPI{}
+	conn, err := bus.Conn(spi, nil)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	b, ok := conn.(BatchSPI)
+	if !ok {
+		t.Fatal("Expected the bus to forward batches with a hardware chip select")
+	}
+	if err := b.TxBatch([]SPITransfer{{Write: []byte{_FLUSH_RX}}, {Write: []byte{_FLUSH_TX}}}); err != nil || len(spi.batches) != 1 {
+		t.Errorf("TxBatch failed: %v, %d batches", err, len(spi.batches))
+	}
+
+	// A GPIO chip select is toggled for every transfer
+	conn, err = bus.Conn(spi, &csPin{})
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	if _, ok := conn.(BatchSPI); ok {
+		t.Error("Expected no batches with a GPIO chip select")
+	}
+}
@@ -0,0 +1,273 @@
+package nrf24
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// --- Beacon ---
+//
+// A beacon sends the same payload over and over. The payload is written once to the TX FIFO
+// and the REUSE_TX_PL command keeps it there after each transmission: every beacon then only
+// takes a CE pulse, and with CE held high the radio sends the payload back to back. Beacons
+// are sent without acknowledgement (W_TX_PAYLOAD_NOACK).
+// Between periodic beacons the radio listens: it leaves RX mode for the pulse and returns to
+// it once TX_DS reports the beacon sent.
+
+// ErrBeaconActive is returned when enabling the sniffer mode while a beacon is running.
+var ErrBeaconActive = errors.New("beacon active")
+
+// ErrNoBeacon is returned when updating the payload while no beacon is running.
+var ErrNoBeacon = errors.New("no beacon running")
+
+// beaconPulse is the duration of the CE pulse sending one beacon.
+const beaconPulse = 15 * time.Microsecond
+
+// beaconAirtime bounds the time a beacon takes to be sent after its CE pulse: 130µs of
+// settling plus the longest packet at 250kbps. The radio listens again after it in any case.
+const beaconAirtime = 5 * time.Millisecond
+
+// beaconPoll is the interval between two reads of STATUS while waiting for a beacon to be sent.
+const beaconPoll = 250 * time.Microsecond
+
+// beacon is a running beacon. It is immutable, except payload, which is guarded by Device.mu.
+type beacon struct {
+	addr     Address
+	payload  []byte
+	interval time.Duration
+}
+
+// StartBeacon starts sending payload to addr every interval, or back to back if interval is 0.
+// A running beacon is replaced.
+//
+// Between periodic beacons the radio keeps receiving. A continuous beacon leaves no time to
+// receive: Receive reports no packet until it is stopped. Transmit, TransmitNoAck and Ping
+// pause the beacon and resume it once done.
+// This method is concurrent safe.
+func (d *Device) StartBeacon(addr Address, payload []byte, interval time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.sniffing {
+		return fmt.Errorf("%w: %w", ErrPkg, ErrSnifferActive)
+	}
+	if interval < 0 {
+		return fmt.Errorf("%w: negative beacon interval", ErrPkg)
+	}
+	if limit := d.payloadLimit(); len(payload) > limit {
+		return fmt.Errorf("%w: payload too large (%d bytes), limit is %d", ErrPkg, len(payload), limit)
+	}
+
+	d.pauseBeacon()
+	b := &beacon{addr: addr, payload: bytes.Clone(payload), interval: interval}
+	d.beacon = b
+	d.loadBeacon()
+	if interval > 0 {
+		go d.runBeacon(b)
+	}
+	d.log(LogInfo, "Beacon started.", Field{"address", addr}, Field{"interval", interval})
+	return nil
+}
+
+// SetBeaconPayload replaces the payload of the running beacon.
+// It returns ErrNoBeacon if no beacon is running.
+// This method is concurrent safe.
+func (d *Device) SetBeaconPayload(payload []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.beacon == nil {
+		return fmt.Errorf("%w: %w", ErrPkg, ErrNoBeacon)
+	}
+	if limit := d.payloadLimit(); len(payload) > limit {
+		return fmt.Errorf("%w: payload too large (%d bytes), limit is %d", ErrPkg, len(payload), limit)
+	}
+
+	d.pauseBeacon()
+	d.beacon.payload = bytes.Clone(payload)
+	d.loadBeacon()
+	return nil
+}
+
+// StopBeacon stops the running beacon, if any, and switches the radio back to RX mode.
+// This method is concurrent safe.
+func (d *Device) StopBeacon() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopBeacon() {
+		d.resume()
+	}
+}
+
+// stopBeacon stops the running beacon, if any, leaving the radio in standby. It reports
+// whether a beacon was running.
+// Call with lock held.
+func (d *Device) stopBeacon() bool {
+	if d.beacon == nil {
+		return false
+	}
+	d.pauseBeacon()
+	d.beacon = nil
+	// Let TX_DS reach the IRQ pin again
+	d.writeRegister(_CONFIG, d.register(_CONFIG)&^byte(_MASK_TX_DS))
+	d.log(LogInfo, "Beacon stopped.")
+	return true
+}
+
+// loadBeacon sets the radio up to send the beacon: the payload is written to the TX FIFO and
+// marked for reuse. The beacon is sent continuously if it has no interval, otherwise the radio
+// listens until the next beacon.
+// Call with lock held.
+func (d *Device) loadBeacon() {
+	b := d.beacon
+	d.beaconLoads++
+	d.stopListening()
+	d.setTargetAddress(b.addr)
+	d.flushTX()
+	// Every beacon sets TX_DS: keep it off the IRQ pin, which signals received packets
+	d.writeRegister(_CONFIG, d.register(_CONFIG)|_MASK_TX_DS)
+	d.writeRegister(_STATUS, _TX_DS|_MAX_RT)
+	d.loadPayload(b.payload, true)
+	d.scratch[0] = _REUSE_TX_PL
+	d.spiTransfer(1)
+	if b.interval == 0 {
+		d.setCE(true)
+		d.setMode(ModeTX)
+	} else {
+		d.listenBetweenBeacons()
+	}
+}
+
+// listenBetweenBeacons switches the radio to RX mode without flushing the RX FIFO, so that
+// the packets received before the beacon are kept. Radios of a Link that only transmit stay
+// in standby.
+// Call with lock held.
+func (d *Device) listenBetweenBeacons() {
+	if d.txOnly {
+		return
+	}
+	d.writeRegister(_CONFIG, d.register(_CONFIG)|_PRIM_RX)
+	d.setCE(true)
+	d.config.Clock.Sleep(130 * time.Microsecond)
+	d.setMode(ModeRX)
+}
+
+// receiving reports whether packets can be read: not while sniffing, nor while a continuous
+// beacon keeps the radio in TX mode.
+// Call with lock held.
+func (d *Device) receiving() bool {
+	return !d.sniffing && (d.beacon == nil || d.beacon.interval > 0)
+}
+
+// pauseBeacon stops sending the beacon and empties the TX FIFO. Call resume to send it again.
+// Call with lock held.
+func (d *Device) pauseBeacon() {
+	if d.beacon == nil {
+		return
+	}
+	d.setCE(false)
+	// FLUSH_TX also clears the reuse of the payload
+	d.flushTX()
+	d.writeRegister(_STATUS, _TX_DS|_MAX_RT)
+	d.setMode(ModeStandby)
+}
+
+// resumeBeacon sends the beacon again after pauseBeacon.
+// Call with lock held.
+func (d *Device) resumeBeacon() {
+	if d.beacon != nil {
+		d.loadBeacon()
+	}
+}
+
+// resume returns to the beacon, if one is running, or to RX mode after a transmission.
+// Radios of a Link that only transmit stay in standby.
+// Call with lock held.
+func (d *Device) resume() {
+	switch {
+	case d.beacon != nil:
+		d.loadBeacon()
+	case d.txOnly:
+		d.setMode(ModeStandby)
+	default:
+		d.startListening()
+	}
+}
+
+// runBeacon sends the beacon every interval until it is stopped or replaced. The lock is only
+// held for the SPI and CE accesses, not across the sleeps, so that receiving and transmitting
+// go on between beacons whatever the Clock.
+func (d *Device) runBeacon(b *beacon) {
+	for d.sendBeacon(b) {
+	}
+}
+
+// sendBeacon waits for the interval and sends the beacon once. It reports whether b still runs.
+func (d *Device) sendBeacon(b *beacon) bool {
+	d.config.Clock.Sleep(b.interval)
+
+	d.mu.Lock()
+	if d.beacon != b {
+		d.mu.Unlock()
+		return false
+	}
+	load := d.beaconLoads
+	if d.mode == ModeRX {
+		d.stopListening()
+	}
+	d.setCE(true)
+	d.setMode(ModeTX)
+	d.mu.Unlock()
+
+	d.config.Clock.Sleep(beaconPulse)
+	running, current := d.beaconStep(b, load, func() {
+		d.setCE(false)
+		d.setMode(ModeStandby)
+	})
+	if !current {
+		return running
+	}
+
+	// Listen again once the beacon is sent
+	deadline := d.config.Clock.Now().Add(beaconAirtime)
+	for sent := false; !sent; {
+		running, current := d.beaconStep(b, load, func() {
+			if d.txOnly {
+				sent = true
+				return
+			}
+			status := d.readRegister(_STATUS)
+			if status&(_TX_DS|_MAX_RT) == 0 && d.config.Clock.Now().Before(deadline) {
+				return
+			}
+			d.writeRegister(_STATUS, _TX_DS|_MAX_RT)
+			d.listenBetweenBeacons()
+			sent = true
+		})
+		if !current {
+			return running
+		}
+		if !sent {
+			d.config.Clock.Sleep(beaconPoll)
+		}
+	}
+	return true
+}
+
+// beaconStep runs f with the lock held, unless b was stopped or replaced, or loaded again since
+// the load-th time: Transmit or SetBeaconPayload set the radio up for the next beacon then.
+// It reports whether b still runs, and whether f ran.
+func (d *Device) beaconStep(b *beacon, load uint64, f func()) (running, current bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.beacon != b {
+		return false, false
+	}
+	if d.beaconLoads != load {
+		return true, false
+	}
+	f()
+	return true, true
+}
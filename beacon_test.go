@@ -0,0 +1,188 @@
+package nrf24_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/nrf24test"
+)
+
+// countReuse returns the number of REUSE_TX_PL commands sent to the radio.
+func countReuse(spi *nrf24test.SPI) int {
+	n := 0
+	for _, tx := range spi.Transactions() {
+		if len(tx) == 1 && tx[0] == 0xE3 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBeacon(t *testing.T) {
+	r := nrf24test.NewRadio()
+	dev, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	addr := nrf24.Address{0xB0, 0xB1, 0xB2, 0xB3, 0xB4}
+
+	if err := dev.SetBeaconPayload([]byte("x")); !errors.Is(err, nrf24.ErrNoBeacon) {
+		t.Errorf("Expected ErrNoBeacon without a beacon, got %v", err)
+	}
+	if err := dev.StartBeacon(addr, make([]byte, 33), time.Second); err == nil {
+		t.Error("Expected an error for a payload too large")
+	}
+
+	if err := dev.StartBeacon(addr, []byte("beacon"), 100*time.Millisecond); err != nil {
+		t.Fatalf("StartBeacon failed: %v", err)
+	}
+	payloads := r.SPI.TxPayloads()
+	if len(payloads) != 1 || string(payloads[0].Data) != "beacon" || !payloads[0].NoAck {
+		t.Fatalf("Expected the beacon to be loaded once without ACK, got %v", payloads)
+	}
+	if countReuse(r.SPI) != 1 {
+		t.Error("Expected the payload to be marked for reuse")
+	}
+	if !bytes.Equal(r.SPI.RegisterBytes(0x10), addr[:]) {
+		t.Errorf("TX_ADDR = %X, want %X", r.SPI.RegisterBytes(0x10), addr)
+	}
+	if r.SPI.Register(0x00)&0x01 == 0 {
+		t.Error("Expected the radio to listen until the first beacon")
+	}
+	if r.IRQ.Read() != nrf24.High {
+		t.Error("Expected TX_DS to be masked from the IRQ pin")
+	}
+
+	// Every interval is a CE pulse, without writing the payload again
+	r.CE.ResetTransitions()
+	for deadline := time.Now().Add(time.Second); len(r.CE.Pulses()) < 3; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected beacon pulses, got %d", len(r.CE.Pulses()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for _, p := range r.CE.Pulses() {
+		if p < 10*time.Microsecond {
+			t.Errorf("CE pulse lasted %v, want at least 10µs", p)
+		}
+	}
+	if got := r.SPI.TxPayloads(); len(got) != 1 {
+		t.Errorf("Expected the payload to be written once, got %v", got)
+	}
+
+	// The radio receives between beacons
+	r.SPI.QueueRx(1, []byte("between"))
+	if p, ok := dev.Receive(); !ok || string(p) != "between" {
+		t.Errorf("Expected to receive between beacons, got %q, %v", p, ok)
+	}
+
+	// Transmit pauses the beacon and resumes it
+	if err := dev.Transmit(nrf24.Address{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}, []byte("data")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	payloads = r.SPI.TxPayloads()
+	if len(payloads) != 3 || string(payloads[1].Data) != "data" || string(payloads[2].Data) != "beacon" {
+		t.Fatalf("Expected the beacon to be loaded again after Transmit, got %v", payloads)
+	}
+	if countReuse(r.SPI) != 2 || !bytes.Equal(r.SPI.RegisterBytes(0x10), addr[:]) {
+		t.Error("Expected the beacon to resume after Transmit")
+	}
+
+	if err := dev.SetBeaconPayload([]byte("v2")); err != nil {
+		t.Fatalf("SetBeaconPayload failed: %v", err)
+	}
+	payloads = r.SPI.TxPayloads()
+	if string(payloads[len(payloads)-1].Data) != "v2" || countReuse(r.SPI) != 3 {
+		t.Errorf("Expected the new payload to be loaded for reuse, got %v", payloads)
+	}
+
+	if err := dev.StartSniffer(); !errors.Is(err, nrf24.ErrBeaconActive) {
+		t.Errorf("Expected ErrBeaconActive, got %v", err)
+	}
+
+	dev.StopBeacon()
+	if config := r.SPI.Register(0x00); config&0x01 == 0 || config&0x20 != 0 {
+		t.Errorf("CONFIG = %02X, want RX mode with TX_DS unmasked", config)
+	}
+	if r.CE.Read() != nrf24.High {
+		t.Error("Expected CE high in RX mode")
+	}
+	r.SPI.QueueRx(1, []byte("received"))
+	if p, ok := dev.Receive(); !ok || string(p) != "received" {
+		t.Errorf("Expected to receive again after StopBeacon, got %q, %v", p, ok)
+	}
+}
+
+func TestBeaconPowerDown(t *testing.T) {
+	r := nrf24test.NewRadio()
+	dev, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	if err := dev.StartBeacon(nrf24.Address{1, 2, 3, 4, 5}, []byte("beacon"), time.Millisecond); err != nil {
+		t.Fatalf("StartBeacon failed: %v", err)
+	}
+
+	before := len(r.SPI.Transactions())
+	dev.PowerDown()
+	if err := dev.SetBeaconPayload([]byte("x")); !errors.Is(err, nrf24.ErrNoBeacon) {
+		t.Errorf("Expected PowerDown to stop the beacon, got %v", err)
+	}
+	if config := r.SPI.Register(0x00); config&0x02 != 0 || config&0x20 != 0 {
+		t.Errorf("CONFIG = %02X, want PWR_UP cleared and TX_DS unmasked", config)
+	}
+	// The radio is not switched back to RX mode on its way to Power Down
+	for _, tx := range r.SPI.Transactions()[before:] {
+		if tx[0] == 0xE2 {
+			t.Error("Expected no FLUSH_RX on PowerDown")
+		}
+	}
+	if r.CE.Read() != nrf24.Low {
+		t.Error("Expected CE low after PowerDown")
+	}
+	r.CE.ResetTransitions()
+	time.Sleep(10 * time.Millisecond)
+	if pulses := r.CE.Pulses(); len(pulses) != 0 {
+		t.Errorf("Expected no beacon after PowerDown, got %d pulses", len(pulses))
+	}
+}
+
+func TestBeaconContinuous(t *testing.T) {
+	r := nrf24test.NewRadio()
+	dev, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, PayloadSize: 8})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+
+	if err := dev.StartBeacon(nrf24.Address{1, 2, 3, 4, 5}, []byte("on"), 0); err != nil {
+		t.Fatalf("StartBeacon failed: %v", err)
+	}
+	if r.CE.Read() != nrf24.High {
+		t.Error("Expected CE to stay high for a continuous beacon")
+	}
+	// A continuous beacon leaves no time to receive
+	r.SPI.QueueRx(1, []byte("ignored"))
+	if _, ok := dev.Receive(); ok {
+		t.Error("Expected no packet during a continuous beacon")
+	}
+	// Fixed payloads are padded
+	payloads := r.SPI.TxPayloads()
+	if len(payloads) != 1 || !bytes.Equal(payloads[0].Data, []byte("on\x00\x00\x00\x00\x00\x00")) {
+		t.Errorf("Expected a padded payload, got %v", payloads)
+	}
+
+	if _, err := dev.Ping(t.Context(), nrf24.Address{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if r.CE.Read() != nrf24.High || countReuse(r.SPI) != 2 {
+		t.Error("Expected the beacon to resume after Ping")
+	}
+
+	dev.StopBeacon()
+	if r.SPI.Register(0x00)&0x01 == 0 {
+		t.Error("Expected RX mode after StopBeacon")
+	}
+}
@@ -0,0 +1,77 @@
+package nrf24
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Bus serializes the SPI transactions of several radios sharing one SPI bus,
+// so that a transaction is never interleaved with another one.
+// The zero value is ready to use.
+//
+// New shares a Bus between the radios whose SpiBusPath is on the same bus
+// (e.g. /dev/spidev0.0 and /dev/spidev0.1); NewBus is for NewWithHardware users.
+type Bus struct {
+	mu sync.Mutex
+}
+
+// NewBus creates a bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Conn returns the connection of a radio on the bus, to pass to NewWithHardware.
+// conn performs the transfers. cs is an optional GPIO chip select, driven low for the
+// duration of every transaction: it allows more radios than the hardware chip selects of
+// the SPI controller. With a GPIO chip select, conn must not assert the hardware chip select
+// of another radio (use a chip select line of the controller that is not wired).
+// Conn drives cs high immediately.
+func (b *Bus) Conn(conn SPI, cs Pin) (SPI, error) {
+	if cs != nil {
+		if err := cs.Out(High); err != nil {
+			return nil, fmt.Errorf("failed to set up chip select pin: %w", err)
+		}
+	}
+	c := &busConn{bus: b, conn: conn, cs: cs}
+	if _, ok := conn.(BatchSPI); ok && cs == nil {
+		return &batchBusConn{c}, nil
+	}
+	return c, nil
+}
+
+// busConn is the connection of a radio on a shared bus.
+type busConn struct {
+	bus  *Bus
+	conn SPI
+	cs   Pin
+}
+
+func (c *busConn) Tx(w, r []byte) error {
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+
+	if c.cs == nil {
+		return c.conn.Tx(w, r)
+	}
+	if err := c.cs.Out(Low); err != nil {
+		return fmt.Errorf("failed to assert chip select: %w", err)
+	}
+	err := c.conn.Tx(w, r)
+	if csErr := c.cs.Out(High); csErr != nil && err == nil {
+		err = fmt.Errorf("failed to release chip select: %w", csErr)
+	}
+	return err
+}
+
+// batchBusConn is the connection of a radio on a shared bus whose connection performs
+// batches. A GPIO chip select must be toggled between the transfers of a batch, so only
+// radios on hardware chip selects get one.
+type batchBusConn struct {
+	*busConn
+}
+
+func (c *batchBusConn) TxBatch(t []SPITransfer) error {
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	return c.conn.(BatchSPI).TxBatch(t)
+}
@@ -0,0 +1,98 @@
+package nrf24
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// overlapSPI fails the test if two transactions overlap.
+type overlapSPI struct {
+	t      *testing.T
+	active atomic.Int32
+	count  atomic.Int32
+}
+
+func (s *overlapSPI) Tx(w, r []byte) error {
+	if s.active.Add(1) != 1 {
+		s.t.Error("Overlapping SPI transactions")
+	}
+	s.count.Add(1)
+	copy(r, w)
+	s.active.Add(-1)
+	return nil
+}
+
+// csPin records the chip select levels seen by the transactions.
+type csPin struct {
+	mockPin
+	low bool
+}
+
+func (p *csPin) Out(l Level) error {
+	p.low = l == Low
+	return nil
+}
+
+func TestBusSerializes(t *testing.T) {
+	bus := NewBus()
+	spi := &overlapSPI{t: t}
+	cs := &csPin{low: true}
+	a, err := bus.Conn(spi, nil)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	b, err := bus.Conn(spi, cs)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	if cs.low {
+		t.Error("Expected Conn to release the chip select")
+	}
+
+	var wg sync.WaitGroup
+	for _, conn := range []SPI{a, b, a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 2)
+			for i := 0; i < 200; i++ {
+				conn.Tx([]byte{0x07, _NOP}, buf)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := spi.count.Load(); got != 800 {
+		t.Errorf("Expected 800 transactions, got %d", got)
+	}
+	if cs.low {
+		t.Error("Expected the chip select to be released after the transactions")
+	}
+}
+
+// csCheckSPI checks that the chip select is asserted during every transaction.
+type csCheckSPI struct {
+	*mockSPIConn
+	t  *testing.T
+	cs *csPin
+}
+
+func (s *csCheckSPI) Tx(w, r []byte) error {
+	if !s.cs.low {
+		s.t.Error("Transaction without the chip select asserted")
+	}
+	return s.mockSPIConn.Tx(w, r)
+}
+
+func TestBusChipSelect(t *testing.T) {
+	cs := &csPin{}
+	conn, _ := NewBus().Conn(&csCheckSPI{mockSPIConn: &mockSPIConn{}, t: t, cs: cs}, cs)
+	dev, err := NewWithHardware(HardwareConfig{RadioConfig: RadioConfig{EnableDynamicPayload: true}, CE: &mockPin{}}, conn)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+	dev.ReadRegisters()
+	if cs.low {
+		t.Error("Expected the chip select to be released")
+	}
+}
@@ -0,0 +1,90 @@
+-- Wireshark dissector for nrf24 capture files written by github.com/michcald/nrf24/capture.
+--
+-- Frames use the LINKTYPE_USER0 (DLT 147) encapsulation with a 16-byte pseudo-header:
+--
+--   offset  size  field
+--   0       1     version (1)
+--   1       1     direction (0 = RX, 1 = TX)
+--   2       1     pipe (0-5)
+--   3       1     RF channel (0-124)
+--   4       1     data rate (0 = 250kbps, 1 = 1mbps, 2 = 2mbps)
+--   5       1     flags (bit 0 = NoAck, bit 1 = TX failed)
+--   6       1     retransmit count
+--   7       1     address width (3-5)
+--   8       5     destination address, LSByte first (register order)
+--   13      1     payload length
+--   14      2     reserved (0)
+--   16      n     payload
+--
+-- Install: copy this file into Wireshark's personal Lua plugins folder
+-- (Help > About Wireshark > Folders) and restart Wireshark.
+
+local nrf24 = Proto("nrf24", "nRF24L01+ Radio Frame")
+
+local directions = { [0] = "RX", [1] = "TX" }
+local data_rates = { [0] = "250kbps", [1] = "1mbps", [2] = "2mbps" }
+
+local f_version     = ProtoField.uint8("nrf24.version", "Version")
+local f_direction   = ProtoField.uint8("nrf24.direction", "Direction", base.DEC, directions)
+local f_pipe        = ProtoField.uint8("nrf24.pipe", "Pipe")
+local f_channel     = ProtoField.uint8("nrf24.channel", "Channel")
+local f_data_rate   = ProtoField.uint8("nrf24.data_rate", "Data Rate", base.DEC, data_rates)
+local f_flags       = ProtoField.uint8("nrf24.flags", "Flags", base.HEX)
+local f_flag_noack  = ProtoField.bool("nrf24.flags.noack", "NoAck", 8, nil, 0x01)
+local f_flag_failed = ProtoField.bool("nrf24.flags.failed", "TX Failed", 8, nil, 0x02)
+local f_retransmits = ProtoField.uint8("nrf24.retransmits", "Retransmits")
+local f_addr_width  = ProtoField.uint8("nrf24.address_width", "Address Width")
+local f_address     = ProtoField.string("nrf24.address", "Address")
+local f_length      = ProtoField.uint8("nrf24.length", "Payload Length")
+local f_payload     = ProtoField.bytes("nrf24.payload", "Payload")
+
+nrf24.fields = {
+    f_version, f_direction, f_pipe, f_channel, f_data_rate, f_flags, f_flag_noack,
+    f_flag_failed, f_retransmits, f_addr_width, f_address, f_length, f_payload,
+}
+
+local HEADER_SIZE = 16
+
+function nrf24.dissector(buffer, pinfo, tree)
+    if buffer:len() < HEADER_SIZE then
+        return 0
+    end
+
+    pinfo.cols.protocol = "NRF24"
+
+    local subtree = tree:add(nrf24, buffer(), "nRF24L01+ Frame")
+    subtree:add(f_version, buffer(0, 1))
+    subtree:add(f_direction, buffer(1, 1))
+    subtree:add(f_pipe, buffer(2, 1))
+    subtree:add(f_channel, buffer(3, 1))
+    subtree:add(f_data_rate, buffer(4, 1))
+    local flags = subtree:add(f_flags, buffer(5, 1))
+    flags:add(f_flag_noack, buffer(5, 1))
+    flags:add(f_flag_failed, buffer(5, 1))
+    subtree:add(f_retransmits, buffer(6, 1))
+    subtree:add(f_addr_width, buffer(7, 1))
+
+    -- Display the address the same way as nrf24.Address.String()
+    local width = buffer(7, 1):uint()
+    local parts = {}
+    for i = 0, 4 do
+        parts[#parts + 1] = string.format("%02X", buffer(8 + i, 1):uint())
+    end
+    subtree:add(f_address, buffer(8, 5), table.concat(parts, ":"))
+
+    local length = buffer(13, 1):uint()
+    subtree:add(f_length, buffer(13, 1))
+    if length > 0 and buffer:len() >= HEADER_SIZE + length then
+        subtree:add(f_payload, buffer(HEADER_SIZE, length))
+    end
+
+    local direction = directions[buffer(1, 1):uint()] or "?"
+    pinfo.cols.src = direction == "TX" and "local" or "air"
+    pinfo.cols.dst = table.concat(parts, ":", 1, width)
+    pinfo.cols.info = string.format("%s ch=%d pipe=%d len=%d retr=%d",
+        direction, buffer(3, 1):uint(), buffer(2, 1):uint(), length, buffer(6, 1):uint())
+
+    return buffer:len()
+end
+
+DissectorTable.get("wtap_encap"):add(wtap.USER0, nrf24)
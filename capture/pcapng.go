@@ -0,0 +1,186 @@
+// Package capture records the frames seen by an nrf24.Device to pcapng files that can be
+// opened with Wireshark.
+//
+// Frames use the LINKTYPE_USER0 (147) encapsulation. Each packet starts with a fixed
+// 16-byte pseudo-header followed by the payload:
+//
+//	offset  size  field
+//	0       1     version (1)
+//	1       1     direction (0 = RX, 1 = TX)
+//	2       1     pipe (0-5)
+//	3       1     RF channel (0-124)
+//	4       1     data rate (0 = 250kbps, 1 = 1mbps, 2 = 2mbps)
+//	5       1     flags (bit 0 = NoAck, bit 1 = TX failed)
+//	6       1     retransmit count
+//	7       1     address width (3-5)
+//	8       5     destination address, LSByte first (register order)
+//	13      1     payload length
+//	14      2     reserved (0)
+//	16      n     payload
+//
+// The nrf24.lua dissector shipped with this package decodes this format.
+// Install it in Wireshark's personal plugins folder and map the USER0 DLT to it.
+package capture
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/michcald/nrf24"
+)
+
+// LinkTypeUser0 is the pcap link type used for nrf24 frames.
+const LinkTypeUser0 = 147
+
+// HeaderVersion is the version of the pseudo-header written before every payload.
+const HeaderVersion = 1
+
+// HeaderSize is the size of the pseudo-header written before every payload.
+const HeaderSize = 16
+
+const (
+	flagNoAck  = 1 << 0
+	flagFailed = 1 << 1
+)
+
+const (
+	blockSHB = 0x0A0D0D0A
+	blockIDB = 0x00000001
+	blockEPB = 0x00000006
+
+	byteOrderMagic = 0x1A2B3C4D
+
+	optEndOfOpt = 0
+	optIfName   = 2
+	optTSResol  = 9
+)
+
+// Writer writes frames to a pcapng stream.
+// It implements nrf24.FrameRecorder, so it can be attached to a device with SetFrameRecorder.
+type Writer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+	buf []byte
+}
+
+// NewWriter writes the pcapng section and interface headers to w and returns a Writer
+// ready to record frames. Timestamps are stored with nanosecond resolution.
+func NewWriter(w io.Writer) (*Writer, error) {
+	cw := &Writer{w: w}
+
+	// Section Header Block
+	shb := make([]byte, 0, 28)
+	shb = binary.LittleEndian.AppendUint32(shb, byteOrderMagic)
+	shb = binary.LittleEndian.AppendUint16(shb, 1) // Major version
+	shb = binary.LittleEndian.AppendUint16(shb, 0) // Minor version
+	shb = binary.LittleEndian.AppendUint64(shb, 0xFFFFFFFFFFFFFFFF)
+	if err := cw.writeBlock(blockSHB, shb); err != nil {
+		return nil, err
+	}
+
+	// Interface Description Block
+	idb := make([]byte, 0, 32)
+	idb = binary.LittleEndian.AppendUint16(idb, LinkTypeUser0)
+	idb = binary.LittleEndian.AppendUint16(idb, 0) // Reserved
+	idb = binary.LittleEndian.AppendUint32(idb, 0) // No snap length limit
+	idb = appendOption(idb, optIfName, []byte("nrf24"))
+	idb = appendOption(idb, optTSResol, []byte{9}) // 10^-9 s
+	idb = appendOption(idb, optEndOfOpt, nil)
+	if err := cw.writeBlock(blockIDB, idb); err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+// RecordFrame writes a frame as an Enhanced Packet Block.
+// Write errors are kept and reported by Err; once an error occurs, further frames are dropped.
+// This method is concurrent safe.
+func (c *Writer) RecordFrame(f nrf24.Frame) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = c.writeFrame(f)
+}
+
+// Err returns the first write error encountered by RecordFrame, if any.
+// This method is concurrent safe.
+func (c *Writer) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Writer) writeFrame(f nrf24.Frame) error {
+	data := EncodeFrame(f)
+	ts := uint64(f.Timestamp.UnixNano())
+
+	epb := c.buf[:0]
+	epb = binary.LittleEndian.AppendUint32(epb, 0) // Interface ID
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(ts>>32))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(ts))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(data))) // Captured length
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(data))) // Original length
+	epb = append(epb, data...)
+	epb = pad4(epb)
+	c.buf = epb
+
+	return c.writeBlock(blockEPB, epb)
+}
+
+// EncodeFrame returns the pseudo-header and payload of a frame as stored in a capture.
+func EncodeFrame(f nrf24.Frame) []byte {
+	out := make([]byte, HeaderSize+len(f.Payload))
+	out[0] = HeaderVersion
+	out[1] = byte(f.Direction)
+	out[2] = byte(f.Pipe)
+	out[3] = f.Channel
+	out[4] = byte(f.DataRate)
+	if f.NoAck {
+		out[5] |= flagNoAck
+	}
+	if f.Failed {
+		out[5] |= flagFailed
+	}
+	out[6] = f.Retransmits
+	out[7] = f.AddressWidth
+	copy(out[8:13], f.Address[:])
+	out[13] = byte(len(f.Payload))
+	copy(out[HeaderSize:], f.Payload)
+	return out
+}
+
+// writeBlock writes a pcapng block with the given body, which must be 32-bit aligned.
+func (c *Writer) writeBlock(blockType uint32, body []byte) error {
+	var hdr [8]byte
+	total := uint32(12 + len(body))
+	binary.LittleEndian.PutUint32(hdr[0:], blockType)
+	binary.LittleEndian.PutUint32(hdr[4:], total)
+	if _, err := c.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	if _, err := c.w.Write(body); err != nil {
+		return err
+	}
+	_, err := c.w.Write(hdr[4:8])
+	return err
+}
+
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	b = append(b, value...)
+	return pad4(b)
+}
+
+func pad4(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
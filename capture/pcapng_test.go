@@ -0,0 +1,75 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	ts := time.Unix(1700000000, 123456789)
+	w.RecordFrame(nrf24.Frame{
+		Direction:    nrf24.FrameTX,
+		Timestamp:    ts,
+		Address:      nrf24.Address{0x01, 0x02, 0x03, 0x04, 0x05},
+		AddressWidth: 5,
+		Channel:      76,
+		DataRate:     nrf24.DataRate1mbps,
+		Retransmits:  2,
+		NoAck:        true,
+		Payload:      []byte("hello"),
+	})
+	if err := w.Err(); err != nil {
+		t.Fatalf("RecordFrame failed: %v", err)
+	}
+
+	// Walk the blocks: SHB, IDB, EPB
+	data := buf.Bytes()
+	var types []uint32
+	var epb []byte
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("Truncated block: %X", data)
+		}
+		typ := binary.LittleEndian.Uint32(data[0:])
+		total := binary.LittleEndian.Uint32(data[4:])
+		if total%4 != 0 || int(total) > len(data) {
+			t.Fatalf("Invalid block length %d", total)
+		}
+		if trailer := binary.LittleEndian.Uint32(data[total-4:]); trailer != total {
+			t.Fatalf("Block trailer %d does not match length %d", trailer, total)
+		}
+		types = append(types, typ)
+		if typ == blockEPB {
+			epb = data[8 : total-4]
+		}
+		data = data[total:]
+	}
+	if len(types) != 3 || types[0] != blockSHB || types[1] != blockIDB || types[2] != blockEPB {
+		t.Fatalf("Unexpected block sequence: %X", types)
+	}
+
+	gotTS := uint64(binary.LittleEndian.Uint32(epb[4:]))<<32 | uint64(binary.LittleEndian.Uint32(epb[8:]))
+	if gotTS != uint64(ts.UnixNano()) {
+		t.Errorf("Expected timestamp %d, got %d", ts.UnixNano(), gotTS)
+	}
+	capLen := binary.LittleEndian.Uint32(epb[12:])
+	if capLen != HeaderSize+5 {
+		t.Fatalf("Expected captured length %d, got %d", HeaderSize+5, capLen)
+	}
+
+	want := []byte{HeaderVersion, 1, 0, 76, 1, flagNoAck, 2, 5, 0x01, 0x02, 0x03, 0x04, 0x05, 5, 0, 0}
+	want = append(want, "hello"...)
+	if got := epb[20 : 20+capLen]; !bytes.Equal(got, want) {
+		t.Errorf("Expected packet data %X, got %X", want, got)
+	}
+}
@@ -0,0 +1,54 @@
+package nrf24
+
+import "time"
+
+// Clock provides the time and the delays of the driver: the oscillator start-up after
+// power-up, the settling time of RX mode and addresses, the 10µs+ CE pulse starting a
+// transmission, and the polling of the TX result and of received packets.
+//
+// Tests and simulations can run the driver in virtual time with a Clock whose Sleep only
+// advances Now (see nrf24test.Clock); platforms can supply precise short delays.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep pauses the calling goroutine for at least d.
+	Sleep(d time.Duration)
+}
+
+// SystemClock is the default Clock, using time.Now and time.Sleep.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// defaultSpinThreshold is the Threshold of a zero SpinClock.
+const defaultSpinThreshold = time.Millisecond
+
+// SpinClock is a Clock busy-waiting for short delays, which time.Sleep overshoots by tens of
+// microseconds or more on Linux (a 15µs CE pulse typically lasts 60-100µs). Longer delays
+// use time.Sleep.
+//
+// Busy-waiting keeps a CPU busy for the duration of the delay: it shortens transmissions,
+// at the cost of CPU time.
+type SpinClock struct {
+	// Threshold is the longest delay busy-waited.
+	// Defaults to 1ms if not provided.
+	Threshold time.Duration
+}
+
+func (SpinClock) Now() time.Time { return time.Now() }
+
+func (c SpinClock) Sleep(d time.Duration) {
+	threshold := c.Threshold
+	if threshold == 0 {
+		threshold = defaultSpinThreshold
+	}
+	if d > threshold {
+		time.Sleep(d)
+		return
+	}
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
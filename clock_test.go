@@ -0,0 +1,17 @@
+package nrf24
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpinClock(t *testing.T) {
+	var c SpinClock
+	for _, d := range []time.Duration{15 * time.Microsecond, 130 * time.Microsecond, 2 * time.Millisecond} {
+		start := time.Now()
+		c.Sleep(d)
+		if elapsed := time.Since(start); elapsed < d {
+			t.Errorf("Sleep(%v) returned after %v", d, elapsed)
+		}
+	}
+}
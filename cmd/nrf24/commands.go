@@ -0,0 +1,356 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/internal/radioflags"
+	"github.com/michcald/nrf24/spitrace"
+)
+
+func runScan(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	rf := radioflags.Add(fs)
+	sweeps := fs.Int("sweeps", 50, "number of sweeps over all channels")
+	dwell := fs.Duration("dwell", 200*time.Microsecond, "listening time per channel and sweep")
+	fs.Parse(args)
+
+	dev, release, err := rf.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var hits [125]int
+	for i := 0; i < *sweeps; i++ {
+		for ch := range hits {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			found, err := dev.ScanChannel(byte(ch), *dwell)
+			if err != nil {
+				return err
+			}
+			if found {
+				hits[ch]++
+			}
+		}
+	}
+
+	fmt.Printf("%-4s %-9s %-6s\n", "CH", "FREQ", "HITS")
+	for ch, n := range hits {
+		bar := strings.Repeat("#", n*40 / *sweeps)
+		fmt.Printf("%-4d %d MHz %3d%%  %s\n", ch, 2400+ch, n*100 / *sweeps, bar)
+	}
+	return nil
+}
+
+func runSend(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	rf := radioflags.Add(fs)
+	to := fs.String("to", "", "destination address (required)")
+	format := fs.String("format", "text", "data format of the arguments or stdin lines: text or hex")
+	noAck := fs.Bool("noack", false, "transmit without requesting an acknowledgement")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: nrf24 send --to ADDR [flags] [DATA...]")
+		fmt.Fprintln(os.Stderr, "Without DATA, every line read from stdin is sent as a message.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dest, err := nrf24.ParseAddress(*to)
+	if err != nil {
+		return fmt.Errorf("invalid --to: %w", err)
+	}
+	if *format != "text" && *format != "hex" {
+		return fmt.Errorf("invalid --format %q: expected text or hex", *format)
+	}
+
+	dev, release, err := rf.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	limit := 32
+	if rc := dev.RadioConfig(); !rc.EnableDynamicPayload {
+		limit = int(rc.PayloadSize)
+	}
+
+	send := func(line string) error {
+		data := []byte(line)
+		if *format == "hex" {
+			if data, err = hex.DecodeString(strings.ReplaceAll(line, " ", "")); err != nil {
+				return fmt.Errorf("invalid hex data: %w", err)
+			}
+		}
+		// Split messages longer than a single payload
+		for len(data) > 0 {
+			n := min(len(data), limit)
+			if *noAck {
+				err = dev.TransmitNoAck(dest, data[:n])
+			} else {
+				err = dev.Transmit(dest, data[:n])
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "sent %d bytes to %s\n", n, dest)
+			data = data[n:]
+		}
+		return nil
+	}
+
+	if fs.NArg() > 0 {
+		return send(strings.Join(fs.Args(), " "))
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := send(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+type jsonPacket struct {
+	Time    time.Time `json:"time"`
+	Pipe    int       `json:"pipe"`
+	Address string    `json:"address"`
+	Channel byte      `json:"channel"`
+	Length  int       `json:"length"`
+	Data    string    `json:"data"`
+}
+
+func runRecv(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("recv", flag.ExitOnError)
+	rf := radioflags.Add(fs)
+	format := fs.String("format", "text", "output format: text, hex or json")
+	count := fs.Int("count", 0, "stop after this many packets (0 = forever)")
+	var pipes pipeFlags
+	fs.Var(&pipes, "pipe", "open an extra pipe, as PIPE=ADDR (repeatable)")
+	fs.Parse(args)
+
+	if *format != "text" && *format != "hex" && *format != "json" {
+		return fmt.Errorf("invalid --format %q: expected text, hex or json", *format)
+	}
+
+	dev, release, err := rf.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, p := range pipes {
+		if err := dev.OpenRxPipe(p.id, p.addr); err != nil {
+			return err
+		}
+	}
+
+	channel := dev.RadioConfig().ChannelNumber
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	enc := json.NewEncoder(out)
+
+	for n := 0; *count == 0 || n < *count; n++ {
+		data, pipe, err := dev.ReceiveBlockingWithPipe(ctx)
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		switch *format {
+		case "text":
+			fmt.Fprintf(out, "%s pipe=%d %q\n", now.Format(time.TimeOnly), pipe, data)
+		case "hex":
+			fmt.Fprintf(out, "%s pipe=%d % X\n", now.Format(time.TimeOnly), pipe, data)
+		case "json":
+			addr, err := dev.PipeAddress(pipe)
+			if err != nil {
+				return err
+			}
+			enc.Encode(jsonPacket{
+				Time:    now,
+				Pipe:    pipe,
+				Address: addr.String(),
+				Channel: channel,
+				Length:  len(data),
+				Data:    hex.EncodeToString(data),
+			})
+		}
+		out.Flush()
+	}
+	return nil
+}
+
+func runPing(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("ping", flag.ExitOnError)
+	rf := radioflags.Add(fs)
+	to := fs.String("to", "", "destination address (required)")
+	count := fs.Int("count", 10, "number of pings (0 = until interrupted)")
+	interval := fs.Duration("interval", time.Second, "time between pings")
+	fs.Parse(args)
+
+	dest, err := nrf24.ParseAddress(*to)
+	if err != nil {
+		return fmt.Errorf("invalid --to: %w", err)
+	}
+
+	dev, release, err := rf.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var (
+		sent, received int
+		rttMin         = time.Duration(math.MaxInt64)
+		rttMax, rttSum time.Duration
+	)
+
+	fmt.Printf("PING %s on channel %d\n", dest, rf.Channel)
+	for seq := 1; *count == 0 || seq <= *count; seq++ {
+		if seq > 1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(*interval):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		start := time.Now()
+		ok, err := dev.Ping(ctx, dest)
+		rtt := time.Since(start)
+		if err != nil {
+			return err
+		}
+		sent++
+		if !ok {
+			fmt.Printf("seq=%d no ack\n", seq)
+			continue
+		}
+		received++
+		_, retries := dev.GetRetransmissionCounters()
+		fmt.Printf("seq=%d rtt=%s retries=%d\n", seq, rtt.Round(time.Microsecond), retries)
+		rttSum += rtt
+		rttMin = min(rttMin, rtt)
+		rttMax = max(rttMax, rtt)
+	}
+
+	if sent == 0 {
+		return nil
+	}
+	fmt.Printf("--- %s ping statistics ---\n", dest)
+	fmt.Printf("%d sent, %d acknowledged, %.1f%% loss\n", sent, received, float64(sent-received)*100/float64(sent))
+	if received > 0 {
+		avg := rttSum / time.Duration(received)
+		fmt.Printf("rtt min/avg/max = %s/%s/%s\n",
+			rttMin.Round(time.Microsecond), avg.Round(time.Microsecond), rttMax.Round(time.Microsecond))
+	}
+	return nil
+}
+
+func runDump(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	rf := radioflags.Add(fs)
+	fs.Parse(args)
+
+	dev, release, err := rf.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	fmt.Println(dev)
+	fmt.Println()
+	io.WriteString(os.Stdout, dev.ReadRegisters().String())
+	return nil
+}
+
+func runDecode(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	csvInput := fs.Bool("csv", false, "input is a logic-analyzer CSV export (default: by .csv extension)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: nrf24 decode [flags] [FILE]")
+		fmt.Fprintln(fs.Output(), "Reads a spitrace file, or standard input if FILE is omitted.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("too many arguments")
+	}
+
+	in := io.Reader(os.Stdin)
+	if name := fs.Arg(0); name != "" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+		if strings.EqualFold(filepath.Ext(name), ".csv") {
+			*csvInput = true
+		}
+	}
+
+	load := spitrace.Load
+	if *csvInput {
+		load = spitrace.LoadCSV
+	}
+	txs, err := load(in)
+	if err != nil {
+		return err
+	}
+	for _, op := range spitrace.Decode(txs) {
+		fmt.Println(op)
+	}
+	return nil
+}
+
+type pipeFlag struct {
+	id   int
+	addr []byte
+}
+
+// pipeFlags collects repeated --pipe PIPE=ADDR flags.
+type pipeFlags []pipeFlag
+
+func (p *pipeFlags) String() string { return "" }
+
+func (p *pipeFlags) Set(s string) error {
+	id, addr, ok := strings.Cut(s, "=")
+	if !ok || len(id) != 1 || id[0] < '0' || id[0] > '5' {
+		return fmt.Errorf("expected PIPE=ADDR with PIPE between 0 and 5")
+	}
+	raw, err := hex.DecodeString(strings.ReplaceAll(addr, ":", ""))
+	if err != nil || len(raw) == 0 || len(raw) > 5 {
+		return fmt.Errorf("invalid pipe address %q", addr)
+	}
+	*p = append(*p, pipeFlag{id: int(id[0] - '0'), addr: raw})
+	return nil
+}
@@ -0,0 +1,78 @@
+//go:build !tinygo
+
+// Command nrf24 is a field diagnostics tool for nRF24L01+ radios on Linux.
+//
+// Usage:
+//
+//	nrf24 <command> [flags] [args]
+//
+// Commands:
+//
+//	scan   sweep all channels and show carrier activity
+//	send   transmit hex, text or stdin data to an address
+//	recv   print received packets as text, hex or JSON lines
+//	ping   measure round trip times to an address
+//	dump   print the decoded radio registers
+//	decode annotate the SPI commands of a trace or logic-analyzer CSV
+//
+// Every command but decode accepts the radio flags (channel, data rate, pins, ...) and --sim,
+// which runs the command against an emulated radio with a simulated peer instead of hardware.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"scan", "sweep all channels and show carrier activity", runScan},
+	{"send", "transmit hex, text or stdin data to an address", runSend},
+	{"recv", "print received packets as text, hex or JSON lines", runRecv},
+	{"ping", "measure round trip times to an address", runPing},
+	{"dump", "print the decoded radio registers", runDump},
+	{"decode", "annotate the SPI commands of a trace or logic-analyzer CSV", runDecode},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: nrf24 <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-6s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'nrf24 <command> -h' for the flags of a command.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(ctx, os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "nrf24:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "nrf24: unknown command %q\n\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,100 @@
+//go:build !tinygo
+
+// Command nrf24d owns an nRF24L01+ radio and shares it with local processes over a Unix socket.
+//
+// Usage:
+//
+//	nrf24d [--socket PATH] [--metrics ADDR] [--discovery-pipe N] [radio flags]
+//
+// Clients connect with gateway.Dial and get an API mirroring nrf24.Device:
+// transmit, open and close pipes, set ACK payloads, read the traffic counters and
+// subscribe to the packets received on any pipe.
+//
+// With --metrics, the radio counters are served to Prometheus on http://ADDR/metrics.
+//
+// With --discovery-pipe, the announcements of the nodes (see package discovery) received on
+// that pipe are recorded, and clients can list the discovered nodes with Client.Nodes.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/discovery"
+	"github.com/michcald/nrf24/gateway"
+	"github.com/michcald/nrf24/internal/radioflags"
+	"github.com/michcald/nrf24/metrics"
+)
+
+func run(ctx context.Context) error {
+	fs := flag.NewFlagSet("nrf24d", flag.ExitOnError)
+	rf := radioflags.Add(fs)
+	socket := fs.String("socket", "/run/nrf24d.sock", "path of the Unix socket to listen on")
+	mode := fs.Uint("socket-mode", 0o660, "permissions of the Unix socket")
+	metricsAddr := fs.String("metrics", "", "address to serve Prometheus metrics on (e.g. :9124)")
+	discoveryPipe := fs.Int("discovery-pipe", -1, "data pipe to record node announcements on (-1 disables discovery)")
+	fs.Parse(os.Args[1:])
+
+	dev, release, err := rf.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Remove a stale socket left by a previous run
+	if err := os.Remove(*socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	l, err := net.Listen("unix", *socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(*socket)
+	if err := os.Chmod(*socket, os.FileMode(*mode)); err != nil {
+		l.Close()
+		return err
+	}
+
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(dev))
+		srv := &http.Server{Addr: *metricsAddr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintln(os.Stderr, "nrf24d: metrics:", err)
+			}
+		}()
+		defer srv.Close()
+	}
+
+	srv := gateway.NewServer(dev)
+	srv.SetLogger(nrf24.NewSlogLogger(slog.NewTextHandler(os.Stderr, nil)))
+	if *discoveryPipe >= 0 {
+		if err := srv.EnableDiscovery(discovery.NewRegistry(), *discoveryPipe); err != nil {
+			l.Close()
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "nrf24d: listening on %s\n", *socket)
+	return srv.Serve(ctx, l)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "nrf24d:", err)
+		os.Exit(1)
+	}
+}
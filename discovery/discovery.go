@@ -0,0 +1,214 @@
+// Package discovery lets nodes announce themselves to a gateway, instead of every deployment
+// hardcoding the addresses of its nodes.
+//
+// A node broadcasts an Announcement (node ID, capabilities, RX address and firmware version)
+// with TransmitNoAck to the well-known AnnounceAddress, once at start-up and then periodically
+// (see Announcer). The gateway listens on AnnounceAddress and keeps a Registry of the nodes it
+// heard from, with the time they were last seen.
+//
+// Nodes and gateway must share the RF settings (channel, data rate, CRC and address width).
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/michcald/nrf24"
+)
+
+// ProtocolVersion is the version carried in the header of every announcement.
+const ProtocolVersion = 1
+
+// magic marks the first byte of an announcement.
+const magic = 0xA7
+
+// HeaderSize is the size of an announcement without its name.
+const HeaderSize = 17
+
+// MaxNameSize is the longest name that fits in an announcement.
+const MaxNameSize = 32 - HeaderSize
+
+// announceStep is the longest sleep of Run between two checks of its context.
+const announceStep = 10 * time.Millisecond
+
+// AnnounceAddress is the well-known address announcements are sent to, in register order
+// (LSByte first). With 3 or 4-byte addresses, its first 3 or 4 bytes are used.
+var AnnounceAddress = nrf24.Address{0xA5, 0xD1, 0x5C, 0x0F, 0xE7}
+
+var (
+	// ErrInvalidAnnouncement is returned for packets that are not a valid announcement.
+	ErrInvalidAnnouncement = errors.New("invalid announcement")
+	// ErrNameTooLong is returned when the name does not fit in an announcement.
+	ErrNameTooLong = errors.New("announcement name too long")
+)
+
+// Capabilities is a set of features of a node.
+type Capabilities uint32
+
+const (
+	// CapabilitySensor marks a node reporting measurements.
+	CapabilitySensor Capabilities = 1 << iota
+	// CapabilityActuator marks a node accepting commands.
+	CapabilityActuator
+	// CapabilityRelay marks a node forwarding the packets of other nodes.
+	CapabilityRelay
+	// CapabilityBattery marks a battery-powered node, which sleeps between transmissions
+	// and only listens shortly after sending.
+	CapabilityBattery
+	// CapabilityAckPayload marks a node reading commands from ACK payloads.
+	CapabilityAckPayload
+)
+
+// CapabilitiesApplication are the capabilities free for applications to define.
+const CapabilitiesApplication Capabilities = 0xFFFF0000
+
+// Has reports whether every capability of c is set.
+func (caps Capabilities) Has(c Capabilities) bool {
+	return caps&c == c
+}
+
+// Version is a firmware version.
+type Version struct {
+	Major, Minor, Patch byte
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Announcement describes a node.
+type Announcement struct {
+	// NodeID identifies the node in the network.
+	NodeID uint16
+	// Capabilities lists the features of the node.
+	Capabilities Capabilities
+	// Address is the RX address of the node, where it can be reached.
+	Address nrf24.Address
+	// AddressWidth is the width of Address (3 to 5 bytes).
+	AddressWidth byte
+	// Firmware is the version of the firmware of the node.
+	Firmware Version
+	// Name is an optional human-readable name, up to MaxNameSize bytes.
+	Name string
+}
+
+// Encode builds the packet of an announcement:
+//
+//	magic (1) | version (1) | node ID (2) | capabilities (4) | address width (1) | address (5) | firmware (3) | name
+//
+// Multi-byte fields are little-endian.
+func Encode(a Announcement) ([]byte, error) {
+	if len(a.Name) > MaxNameSize {
+		return nil, fmt.Errorf("%w: %d bytes, max is %d", ErrNameTooLong, len(a.Name), MaxNameSize)
+	}
+	width := a.AddressWidth
+	if width == 0 {
+		width = 5
+	}
+	if width < 3 || width > 5 {
+		return nil, fmt.Errorf("%w: address width %d", ErrInvalidAnnouncement, width)
+	}
+	raw := make([]byte, HeaderSize, HeaderSize+len(a.Name))
+	raw[0] = magic
+	raw[1] = ProtocolVersion
+	binary.LittleEndian.PutUint16(raw[2:], a.NodeID)
+	binary.LittleEndian.PutUint32(raw[4:], uint32(a.Capabilities))
+	raw[8] = width
+	copy(raw[9:9+width], a.Address[:width])
+	raw[14] = a.Firmware.Major
+	raw[15] = a.Firmware.Minor
+	raw[16] = a.Firmware.Patch
+	return append(raw, a.Name...), nil
+}
+
+// Decode parses the packet of an announcement.
+// The zero padding of fixed-size payloads is stripped from the name.
+func Decode(raw []byte) (Announcement, error) {
+	if len(raw) < HeaderSize || raw[0] != magic {
+		return Announcement{}, ErrInvalidAnnouncement
+	}
+	if raw[1] != ProtocolVersion {
+		return Announcement{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidAnnouncement, raw[1])
+	}
+	width := raw[8]
+	if width < 3 || width > 5 {
+		return Announcement{}, fmt.Errorf("%w: address width %d", ErrInvalidAnnouncement, width)
+	}
+	a := Announcement{
+		NodeID:       binary.LittleEndian.Uint16(raw[2:]),
+		Capabilities: Capabilities(binary.LittleEndian.Uint32(raw[4:])),
+		AddressWidth: width,
+		Firmware:     Version{raw[14], raw[15], raw[16]},
+		Name:         strings.TrimRight(string(raw[HeaderSize:]), "\x00"),
+	}
+	copy(a.Address[:width], raw[9:])
+	return a, nil
+}
+
+// Announcer broadcasts the announcement of a node.
+type Announcer struct {
+	tx    nrf24.Transmitter
+	raw   []byte
+	clock nrf24.Clock
+}
+
+// NewAnnouncer creates an announcer sending a on a radio.
+func NewAnnouncer(tx nrf24.Transmitter, a Announcement) (*Announcer, error) {
+	raw, err := Encode(a)
+	if err != nil {
+		return nil, err
+	}
+	return &Announcer{tx: tx, raw: raw, clock: nrf24.SystemClock}, nil
+}
+
+// SetClock sets the clock timing the announcements of Run, e.g. the virtual clock of a
+// test. Call it before Run.
+func (a *Announcer) SetClock(c nrf24.Clock) {
+	a.clock = c
+}
+
+// Announce sends the announcement once, without acknowledgement.
+func (a *Announcer) Announce() error {
+	return a.tx.TransmitNoAck(AnnounceAddress, a.raw)
+}
+
+// Run sends the announcement at once, then every interval until ctx is cancelled.
+// Every announcement is delayed by up to an eighth of interval at random, so that nodes
+// powered up together do not keep colliding. Announcements are not acknowledged: a lost
+// announcement is only made up for by the next one.
+// It returns nil when stopped by ctx.
+func (a *Announcer) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid announcement interval %v", interval)
+	}
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		a.Announce()
+		if !a.wait(ctx, interval+rand.N(interval/8+1)) {
+			return nil
+		}
+	}
+}
+
+// wait sleeps for d on the clock of the announcer, in steps of announceStep so that
+// ctx stops it promptly. It returns false if ctx is done.
+func (a *Announcer) wait(ctx context.Context, d time.Duration) bool {
+	deadline := a.clock.Now().Add(d)
+	for {
+		if ctx.Err() != nil {
+			return false
+		}
+		left := deadline.Sub(a.clock.Now())
+		if left <= 0 {
+			return true
+		}
+		a.clock.Sleep(min(left, announceStep))
+	}
+}
@@ -0,0 +1,176 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/nrf24test"
+	"github.com/michcald/nrf24/sim"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	a := Announcement{
+		NodeID:       0x0102,
+		Capabilities: CapabilitySensor | CapabilityBattery | 1<<16,
+		Address:      nrf24.Address{0xC1, 0xC2, 0xC3, 0xC4, 0xC5},
+		AddressWidth: 5,
+		Firmware:     Version{1, 2, 3},
+		Name:         "kitchen",
+	}
+	raw, err := Encode(a)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := []byte{0xA7, ProtocolVersion, 0x02, 0x01, 0x09, 0x00, 0x01, 0x00, 5, 0xC1, 0xC2, 0xC3, 0xC4, 0xC5, 1, 2, 3}
+	if !bytes.Equal(raw, append(want, "kitchen"...)) {
+		t.Errorf("Encode() = % X", raw)
+	}
+	got, err := Decode(raw)
+	if err != nil || got != a {
+		t.Errorf("Decode() = %+v, %v, want %+v", got, err, a)
+	}
+	if !got.Capabilities.Has(CapabilitySensor|CapabilityBattery) || got.Capabilities.Has(CapabilityRelay) {
+		t.Errorf("Unexpected capabilities %08X", got.Capabilities)
+	}
+	if s := got.Firmware.String(); s != "1.2.3" {
+		t.Errorf("Firmware = %q, want 1.2.3", s)
+	}
+
+	// Fixed-size payloads are padded with zeros, and short addresses leave bytes unused
+	raw, _ = Encode(Announcement{NodeID: 7, Address: nrf24.Address{1, 2, 3}, AddressWidth: 3})
+	got, err = Decode(append(raw, make([]byte, 32-len(raw))...))
+	if err != nil || got.NodeID != 7 || got.Name != "" || got.Address != (nrf24.Address{1, 2, 3}) {
+		t.Errorf("Decode(padded) = %+v, %v", got, err)
+	}
+
+	if _, err := Encode(Announcement{Name: "a name far too long"}); !errors.Is(err, ErrNameTooLong) {
+		t.Errorf("Expected ErrNameTooLong, got %v", err)
+	}
+	if _, err := Encode(Announcement{AddressWidth: 6}); !errors.Is(err, ErrInvalidAnnouncement) {
+		t.Errorf("Expected ErrInvalidAnnouncement for an address width of 6, got %v", err)
+	}
+	for _, raw := range [][]byte{
+		[]byte("hello"),
+		append([]byte{0x00}, want[1:]...),
+		append([]byte{0xA7, 99}, want[2:]...),
+	} {
+		if _, err := Decode(raw); !errors.Is(err, ErrInvalidAnnouncement) {
+			t.Errorf("Decode(% X): expected ErrInvalidAnnouncement, got %v", raw, err)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	clock := nrf24test.NewClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewRegistry()
+	r.SetClock(clock)
+	start := clock.Now()
+
+	r.Update(Announcement{NodeID: 2, Firmware: Version{1, 0, 0}})
+	clock.Advance(time.Minute)
+	r.Update(Announcement{NodeID: 1})
+	n := r.Update(Announcement{NodeID: 2, Firmware: Version{1, 1, 0}})
+	if n.Announcements != 2 || !n.FirstSeen.Equal(start) || !n.LastSeen.Equal(clock.Now()) || n.Firmware != (Version{1, 1, 0}) {
+		t.Errorf("Update() = %+v", n)
+	}
+	if _, err := r.Handle([]byte("noise")); !errors.Is(err, ErrInvalidAnnouncement) {
+		t.Errorf("Expected ErrInvalidAnnouncement, got %v", err)
+	}
+
+	nodes := r.Nodes()
+	if len(nodes) != 2 || nodes[0].NodeID != 1 || nodes[1].NodeID != 2 {
+		t.Fatalf("Nodes() = %+v, want nodes 1 and 2", nodes)
+	}
+	if _, ok := r.Node(3); ok {
+		t.Error("Expected node 3 to be unknown")
+	}
+
+	clock.Advance(time.Minute)
+	r.Update(Announcement{NodeID: 1})
+	if active := r.Active(30 * time.Second); len(active) != 1 || active[0].NodeID != 1 {
+		t.Errorf("Active() = %+v, want node 1", active)
+	}
+	if removed := r.Expire(30 * time.Second); removed != 1 {
+		t.Errorf("Expire() removed %d nodes, want 1", removed)
+	}
+	if _, ok := r.Node(2); ok {
+		t.Error("Expected node 2 to be expired")
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	air := sim.NewAir()
+	gatewayAddr := nrf24.Address{0x01, 0xD1, 0x5C, 0x0F, 0xE7}
+	gateway, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, RxAddr: gatewayAddr, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice(gateway) failed: %v", err)
+	}
+	nodeAddr := nrf24.Address{0xC1, 0xC2, 0xC3, 0xC4, 0xC5}
+	node, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, RxAddr: nodeAddr, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice(node) failed: %v", err)
+	}
+
+	// Pipes 2-5 need pipe 1 to share the high bytes of AnnounceAddress, like the gateway's do
+	if err := OpenPipe(node, 3); err == nil {
+		t.Error("Expected OpenPipe to fail when pipe 1 has other high bytes")
+	}
+
+	r := NewRegistry()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	others := make(chan []byte, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Listen(ctx, gateway, 2, func(pipe int, data []byte) { others <- data })
+	}()
+
+	announcer, err := NewAnnouncer(node, Announcement{NodeID: 42, Capabilities: CapabilitySensor, Address: nodeAddr, AddressWidth: 5, Name: "node"})
+	if err != nil {
+		t.Fatalf("NewAnnouncer failed: %v", err)
+	}
+	// Announcements every virtual hour take no real time
+	announcer.SetClock(nrf24test.NewClock(time.Now()))
+	runCtx, stop := context.WithCancel(ctx)
+	go announcer.Run(runCtx, time.Hour)
+
+	for {
+		if n, ok := r.Node(42); ok && n.Announcements >= 2 {
+			if n.Address != nodeAddr || n.Name != "node" {
+				t.Errorf("Node(42) = %+v", n)
+			}
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatal("Expected the node to be discovered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	stop()
+
+	// The gateway reaches the node at its announced address, and still gets its other packets
+	n, _ := r.Node(42)
+	if err := gateway.Transmit(n.Address, []byte("hello node")); err != nil {
+		t.Errorf("Transmit to the announced address failed: %v", err)
+	}
+	if err := node.Transmit(gatewayAddr, []byte("data")); err != nil {
+		t.Fatalf("Transmit to the gateway failed: %v", err)
+	}
+	select {
+	case data := <-others:
+		if string(data) != "data" {
+			t.Errorf("Handler got %q, want \"data\"", data)
+		}
+	case <-ctx.Done():
+		t.Fatal("Expected the handler to get the packets of pipe 1")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Listen failed: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/michcald/nrf24"
+)
+
+// Node is a node known to a Registry.
+type Node struct {
+	// Announcement is the last announcement of the node.
+	Announcement
+	// FirstSeen is the time the node was first heard from.
+	FirstSeen time.Time
+	// LastSeen is the time of the last announcement of the node.
+	LastSeen time.Time
+	// Announcements is the number of announcements received from the node.
+	Announcements int
+}
+
+// Registry keeps track of the nodes heard announcing themselves.
+// It is concurrent safe.
+type Registry struct {
+	mu    sync.Mutex
+	clock nrf24.Clock
+	nodes map[uint16]*Node
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{clock: nrf24.SystemClock, nodes: make(map[uint16]*Node)}
+}
+
+// SetClock sets the clock timestamping the announcements, e.g. the virtual clock of a test.
+func (r *Registry) SetClock(c nrf24.Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = c
+}
+
+// Handle decodes a packet received on AnnounceAddress and records the announcement.
+func (r *Registry) Handle(raw []byte) (Node, error) {
+	a, err := Decode(raw)
+	if err != nil {
+		return Node{}, err
+	}
+	return r.Update(a), nil
+}
+
+// Update records an announcement, and returns the node it describes.
+// A node announcing a new address or firmware replaces its previous announcement.
+func (r *Registry) Update(a Announcement) Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	n, ok := r.nodes[a.NodeID]
+	if !ok {
+		n = &Node{FirstSeen: now}
+		r.nodes[a.NodeID] = n
+	}
+	n.Announcement = a
+	n.LastSeen = now
+	n.Announcements++
+	return *n
+}
+
+// Node returns a node by ID.
+func (r *Registry) Node(id uint16) (Node, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, ok := r.nodes[id]
+	if !ok {
+		return Node{}, false
+	}
+	return *n, true
+}
+
+// Nodes returns the known nodes, by ID.
+func (r *Registry) Nodes() []Node {
+	return r.Active(0)
+}
+
+// Active returns the nodes seen within maxAge, by ID. A zero maxAge returns every node.
+func (r *Registry) Active(maxAge time.Duration) []Node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	out := make([]Node, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		if maxAge == 0 || now.Sub(n.LastSeen) <= maxAge {
+			out = append(out, *n)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NodeID < out[j].NodeID })
+	return out
+}
+
+// Expire forgets the nodes not seen within maxAge, and returns how many were removed.
+func (r *Registry) Expire(maxAge time.Duration) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	removed := 0
+	for id, n := range r.nodes {
+		if now.Sub(n.LastSeen) > maxAge {
+			delete(r.nodes, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// OpenPipe opens a data pipe of the radio on AnnounceAddress.
+// Pipes 2-5 only have their own LSByte and share the other bytes with pipe 1: they can only
+// be used when pipe 1 shares the high bytes of AnnounceAddress, otherwise use pipe 1. Pipe 0
+// is overwritten by the driver with the destination of every acknowledged transmission.
+func OpenPipe(radio nrf24.Radio, pipe int) error {
+	if err := radio.OpenRxPipe(pipe, AnnounceAddress[:]); err != nil {
+		return err
+	}
+	addr, err := radio.PipeAddress(pipe)
+	if err != nil {
+		return err
+	}
+	width := int(radio.RadioConfig().AddressWidth)
+	if width < 3 || width > 5 {
+		width = 5
+	}
+	if !bytes.Equal(addr[:width], AnnounceAddress[:width]) {
+		radio.CloseRxPipe(pipe)
+		return fmt.Errorf("pipe %d cannot receive announcements: address %v, want %v", pipe, addr, AnnounceAddress)
+	}
+	return nil
+}
+
+// Listen opens a pipe on AnnounceAddress (see OpenPipe) and records the announcements received
+// on it until ctx is cancelled. handler, if not nil, is given the packets received on the
+// other pipes. Listen becomes the only reader of the radio.
+// It returns nil when stopped by ctx.
+func (r *Registry) Listen(ctx context.Context, radio nrf24.Radio, pipe int, handler func(pipe int, data []byte)) error {
+	if err := OpenPipe(radio, pipe); err != nil {
+		return err
+	}
+	for {
+		data, p, err := radio.ReceiveBlockingWithPipe(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if p != pipe {
+			if handler != nil {
+				handler(p, data)
+			}
+			continue
+		}
+		// Ignore stray packets sent to the address
+		r.Handle(data)
+	}
+}
@@ -0,0 +1,212 @@
+package nrf24
+
+import "fmt"
+
+// --- Enhanced ShockBurst (ESB) on-air packet format ---
+//
+// On air, an ESB packet is laid out MSB first as:
+//
+//	| preamble (1 byte) | address (3-5 bytes) | PCF (9 bits) | payload (0-32 bytes) | CRC (1-2 bytes) |
+//
+// The packet control field (PCF) holds a 6-bit payload length, a 2-bit packet ID (PID)
+// and a NO_ACK flag. The CRC covers the address, the PCF and the payload.
+// Because the PCF is 9 bits long, the payload and CRC are not byte aligned.
+//
+// Addresses are transmitted most significant byte first, while the radio registers
+// (and the Address type) store them least significant byte first.
+
+// ESBPacket is an Enhanced ShockBurst packet decoded from a raw air capture.
+type ESBPacket struct {
+	// Address is the destination address in register order (LSByte first).
+	// Only the first AddressWidth bytes are meaningful.
+	Address Address
+	// AddressWidth is the address width in bytes (3 to 5).
+	AddressWidth byte
+	// PID is the 2-bit packet ID used by receivers to detect retransmissions.
+	PID byte
+	// NoAck is true when the transmitter asked the receiver not to acknowledge the packet.
+	NoAck bool
+	// Payload is the packet payload.
+	Payload []byte
+	// CRC is the checksum transmitted with the packet.
+	CRC uint16
+}
+
+const _ESB_PCF_BITS = 9
+
+// DecodeESBFrame searches a raw capture for a valid ESB packet.
+// The capture must start at (or one bit into) the destination address, which is what the
+// radio delivers in sniffer mode. Address widths from 5 down to 3 bytes are tried and the
+// candidate is accepted only when its CRC matches.
+// crc selects the CRC length used by the transmitter (CRCLength8 or CRCLength16).
+// It returns the decoded packet and true on success.
+func DecodeESBFrame(raw []byte, crc CRCLength) (ESBPacket, bool) {
+	if crc != CRCLength8 && crc != CRCLength16 {
+		return ESBPacket{}, false
+	}
+
+	// Depending on whether the real preamble was 0xAA or 0x55, the radio locks on either at
+	// the first address bit or one bit later (in which case the missing bit is 0).
+	var shifted [_MAX_PAYLOAD_BYTES + 8]byte
+	n := copy(shifted[:len(shifted)-1], raw)
+	shiftRightOne(shifted[:n+1])
+
+	for _, buf := range [][]byte{raw, shifted[:n+1]} {
+		for width := byte(5); width >= 3; width-- {
+			if p, ok := decodeESB(buf, width, crc); ok {
+				return p, true
+			}
+		}
+	}
+	return ESBPacket{}, false
+}
+
+// EncodeESBFrame builds the on-air bits of an ESB packet, from the first address bit to the
+// last CRC bit, padded with zero bits to a whole byte. It is the inverse of DecodeESBFrame.
+// The preamble is not included: on air it is 0xAA when the first address bit is 1 and 0x55 otherwise.
+// crc selects the CRC length (CRCLength8 or CRCLength16); p.CRC is ignored and computed.
+func EncodeESBFrame(p ESBPacket, crc CRCLength) ([]byte, error) {
+	if p.AddressWidth < 3 || p.AddressWidth > 5 {
+		return nil, fmt.Errorf("AddressWidth must be 3, 4, or 5")
+	}
+	if len(p.Payload) > _MAX_PAYLOAD_BYTES {
+		return nil, fmt.Errorf("%w: payload too large (%d bytes), limit is %d", ErrPkg, len(p.Payload), _MAX_PAYLOAD_BYTES)
+	}
+	crcBits := 8
+	switch crc {
+	case CRCLength8:
+	case CRCLength16:
+		crcBits = 16
+	default:
+		return nil, fmt.Errorf("ESB frames require an 8 or 16-bit CRC")
+	}
+
+	n := int(p.AddressWidth)*8 + _ESB_PCF_BITS + len(p.Payload)*8
+	buf := make([]byte, (n+crcBits+7)/8)
+	off := 0
+	put := func(v uint32, bits int) {
+		for i := bits - 1; i >= 0; i-- {
+			if v>>i&1 != 0 {
+				buf[off/8] |= 0x80 >> (off % 8)
+			}
+			off++
+		}
+	}
+	// The address goes MSByte first
+	for i := int(p.AddressWidth) - 1; i >= 0; i-- {
+		put(uint32(p.Address[i]), 8)
+	}
+	pcf := uint32(len(p.Payload))<<3 | uint32(p.PID&0x03)<<1
+	if p.NoAck {
+		pcf |= 1
+	}
+	put(pcf, _ESB_PCF_BITS)
+	for _, b := range p.Payload {
+		put(uint32(b), 8)
+	}
+	if crc == CRCLength16 {
+		put(uint32(crc16Bits(buf, n)), 16)
+	} else {
+		put(uint32(crc8Bits(buf, n)), 8)
+	}
+	return buf, nil
+}
+
+// decodeESB decodes a packet assuming the address starts at bit 0 of buf.
+func decodeESB(buf []byte, width byte, crc CRCLength) (ESBPacket, bool) {
+	totalBits := len(buf) * 8
+	addrBits := int(width) * 8
+	crcBits := 8
+	if crc == CRCLength16 {
+		crcBits = 16
+	}
+	if addrBits+_ESB_PCF_BITS+crcBits > totalBits {
+		return ESBPacket{}, false
+	}
+
+	pcf := getBits(buf, addrBits, _ESB_PCF_BITS)
+	size := int(pcf >> 3)
+	if size > _MAX_PAYLOAD_BYTES {
+		return ESBPacket{}, false
+	}
+	dataBits := addrBits + _ESB_PCF_BITS + size*8
+	if dataBits+crcBits > totalBits {
+		return ESBPacket{}, false
+	}
+
+	got := uint16(getBits(buf, dataBits, crcBits))
+	var want uint16
+	if crc == CRCLength16 {
+		want = crc16Bits(buf, dataBits)
+	} else {
+		want = uint16(crc8Bits(buf, dataBits))
+	}
+	if got != want {
+		return ESBPacket{}, false
+	}
+
+	p := ESBPacket{
+		AddressWidth: width,
+		PID:          byte(pcf>>1) & 0x03,
+		NoAck:        pcf&0x01 != 0,
+		Payload:      make([]byte, size),
+		CRC:          got,
+	}
+	// On air the address is MSByte first; store it LSByte first
+	for i := 0; i < int(width); i++ {
+		p.Address[int(width)-1-i] = buf[i]
+	}
+	for i := 0; i < size; i++ {
+		p.Payload[i] = byte(getBits(buf, addrBits+_ESB_PCF_BITS+i*8, 8))
+	}
+	return p, true
+}
+
+// getBits returns n bits (n <= 32) of buf starting at bit offset off, MSB first.
+func getBits(buf []byte, off, n int) uint32 {
+	var v uint32
+	for i := off; i < off+n; i++ {
+		v = v<<1 | uint32(buf[i/8]>>(7-i%8))&1
+	}
+	return v
+}
+
+// shiftRightOne shifts buf right by one bit, inserting a 0 bit at the start.
+func shiftRightOne(buf []byte) {
+	var carry byte
+	for i := range buf {
+		next := buf[i] & 0x01
+		buf[i] = buf[i]>>1 | carry<<7
+		carry = next
+	}
+}
+
+// crc16Bits computes the radio's CRC-16 (CCITT, polynomial 0x1021, initial value 0xFFFF)
+// over the first n bits of buf, MSB first.
+func crc16Bits(buf []byte, n int) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < n; i++ {
+		bit := uint16(buf[i/8]>>(7-i%8)) & 1
+		if (crc>>15)^bit != 0 {
+			crc = crc<<1 ^ 0x1021
+		} else {
+			crc <<= 1
+		}
+	}
+	return crc
+}
+
+// crc8Bits computes the radio's CRC-8 (polynomial 0x07, initial value 0xFF)
+// over the first n bits of buf, MSB first.
+func crc8Bits(buf []byte, n int) byte {
+	crc := byte(0xFF)
+	for i := 0; i < n; i++ {
+		bit := (buf[i/8] >> (7 - i%8)) & 1
+		if (crc>>7)^bit != 0 {
+			crc = crc<<1 ^ 0x07
+		} else {
+			crc <<= 1
+		}
+	}
+	return crc
+}
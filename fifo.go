@@ -0,0 +1,73 @@
+package nrf24
+
+import "context"
+
+// ReceivedPacket is a packet read by ReceiveAll.
+type ReceivedPacket struct {
+	// Pipe is the data pipe (0-5) the packet arrived on.
+	Pipe int
+	// Len is the length of the payload.
+	Len int
+	// Data holds the payload in its first Len bytes.
+	Data Packet
+}
+
+// Payload returns the payload of the packet.
+func (p *ReceivedPacket) Payload() []byte {
+	return p.Data[:p.Len]
+}
+
+// ReceiveAll drains the RX FIFO (up to 3 packets) into pkts, and returns the number of packets
+// read. Following the datasheet, it reads a packet, clears RX_DR, and checks FIFO_STATUS for
+// more, until the FIFO is empty or pkts is full.
+// overflow reports that the FIFO was full while draining: the radio may have dropped packets
+// (see also Stats.RxFIFOFull). Like ReceiveInto, ReceiveAll does not allocate.
+// This method is concurrent safe.
+func (d *Device) ReceiveAll(pkts []ReceivedPacket) (n int, overflow bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.receiving() {
+		return 0, false
+	}
+	d.verifyPeriodically()
+
+	for n < len(pkts) {
+		fifo := d.readRegister(_FIFO_STATUS)
+		if fifo&_RX_EMPTY != 0 {
+			break
+		}
+		if fifo&_RX_FULL != 0 {
+			overflow = true
+			d.stats.RxFIFOFull++
+		}
+		pipe, ok := d.available()
+		if !ok {
+			break
+		}
+		p := &pkts[n]
+		p.Len, ok = d.readPayload(p.Data[:])
+		if !ok {
+			// The FIFO was flushed
+			break
+		}
+		p.Pipe = pipe
+		d.received(pipe, p.Payload())
+		n++
+	}
+	return n, overflow
+}
+
+// ReceiveAllBlocking waits for at least one packet, like ReceiveBlocking, then drains the
+// RX FIFO like ReceiveAll.
+// This method is concurrent safe.
+func (d *Device) ReceiveAllBlocking(ctx context.Context, pkts []ReceivedPacket) (n int, overflow bool, err error) {
+	if len(pkts) == 0 {
+		return 0, false, nil
+	}
+	err = d.waitReceive(ctx, func() bool {
+		n, overflow = d.ReceiveAll(pkts)
+		return n > 0
+	})
+	return n, overflow, err
+}
@@ -0,0 +1,82 @@
+package nrf24_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/nrf24test"
+)
+
+func TestReceiveAll(t *testing.T) {
+	r := nrf24test.NewRadio()
+	dev, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+
+	var pkts [3]nrf24.ReceivedPacket
+	if n, _ := dev.ReceiveAll(pkts[:]); n != 0 {
+		t.Fatalf("Expected no packet, got %d", n)
+	}
+
+	// A burst fills the FIFO
+	writes := len(r.SPI.Writes())
+	for i := 0; i < 3; i++ {
+		r.SPI.QueueRx(i, fmt.Appendf(nil, "packet %d", i))
+	}
+	n, overflow := dev.ReceiveAll(pkts[:])
+	if n != 3 || !overflow {
+		t.Fatalf("ReceiveAll() = %d, %v, want 3, true", n, overflow)
+	}
+	for i, p := range pkts[:n] {
+		if want := fmt.Sprintf("packet %d", i); p.Pipe != i || string(p.Payload()) != want {
+			t.Errorf("Packet %d = %d %q, want %d %q", i, p.Pipe, p.Payload(), i, want)
+		}
+	}
+	if r.IRQ.Read() != nrf24.High {
+		t.Error("Expected IRQ to be released once the FIFO is drained")
+	}
+	if got := dev.Stats().RxFIFOFull; got != 1 {
+		t.Errorf("RxFIFOFull = %d, want 1", got)
+	}
+
+	// Only RX_DR is cleared, after reading each packet
+	for _, w := range r.SPI.Writes()[writes:] {
+		if w.Reg != 0x07 || w.Value[0] != nrf24test.RxDR {
+			t.Errorf("Expected only RX_DR to be cleared while receiving, got %v", w)
+		}
+	}
+
+	// pkts limits the number of packets read
+	r.SPI.QueueRx(1, []byte("a"))
+	r.SPI.QueueRx(1, []byte("b"))
+	if n, overflow := dev.ReceiveAll(pkts[:1]); n != 1 || overflow || string(pkts[0].Payload()) != "a" {
+		t.Errorf("ReceiveAll(1) = %d %q, %v, want 1 \"a\"", n, pkts[0].Payload(), overflow)
+	}
+	if n, _ := dev.ReceiveAll(pkts[:]); n != 1 || string(pkts[0].Payload()) != "b" {
+		t.Errorf("Expected the remaining packet, got %d %q", n, pkts[0].Payload())
+	}
+}
+
+func TestReceiveAllBlocking(t *testing.T) {
+	r := nrf24test.NewRadio()
+	dev, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.SPI.QueueRx(1, []byte("hello"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var pkts [3]nrf24.ReceivedPacket
+	n, _, err := dev.ReceiveAllBlocking(ctx, pkts[:])
+	if err != nil || n != 1 || string(pkts[0].Payload()) != "hello" {
+		t.Errorf("ReceiveAllBlocking() = %d %q, %v, want 1 \"hello\"", n, pkts[0].Payload(), err)
+	}
+}
@@ -0,0 +1,116 @@
+package nrf24
+
+import (
+	"bytes"
+	"time"
+)
+
+// FrameDirection tells whether a frame was received or transmitted.
+type FrameDirection uint8
+
+const (
+	// FrameRX is a frame received by the device.
+	FrameRX FrameDirection = iota
+	// FrameTX is a frame transmitted by the device.
+	FrameTX
+)
+
+func (f FrameDirection) String() string {
+	if f == FrameTX {
+		return "TX"
+	}
+	return "RX"
+}
+
+// Frame describes a single packet seen by a Device, either received or transmitted.
+type Frame struct {
+	Direction FrameDirection
+	// Timestamp is the time the frame was read from or sent to the radio.
+	Timestamp time.Time
+	// Pipe is the data pipe (0-5) a received frame arrived on. It is always 0 for transmitted frames.
+	Pipe int
+	// Address is the destination address of the frame.
+	Address Address
+	// AddressWidth is the number of meaningful bytes in Address (3 to 5).
+	AddressWidth byte
+	// Channel is the RF channel in use.
+	Channel byte
+	// DataRate is the air data rate in use.
+	DataRate DataRate
+	// Retransmits is the number of retransmissions needed to deliver a transmitted frame.
+	Retransmits byte
+	// NoAck is true for frames transmitted without requesting an acknowledgement.
+	NoAck bool
+	// Failed is true for transmitted frames that were not delivered (max retries or timeout).
+	Failed bool
+	// Payload holds the frame data.
+	Payload []byte
+}
+
+// FrameRecorder receives every frame seen by a Device.
+// RecordFrame is called synchronously while the device lock is held, so implementations must
+// return quickly and must not call back into the Device.
+type FrameRecorder interface {
+	RecordFrame(f Frame)
+}
+
+// SetFrameRecorder attaches a recorder that is given every received and transmitted frame.
+// Pass nil to detach the current recorder.
+// This method is concurrent safe.
+func (d *Device) SetFrameRecorder(r FrameRecorder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recorder = r
+}
+
+// pipeAddress returns the full address of an RX pipe.
+// Pipes 2-5 share the high bytes of pipe 1.
+// Call with lock held.
+func (d *Device) pipeAddress(pipe int) Address {
+	if pipe <= 1 {
+		return d.pipeAddrs[pipe]
+	}
+	addr := d.pipeAddrs[1]
+	addr[0] = d.pipeAddrs[pipe][0]
+	return addr
+}
+
+// recordRX hands a received frame to the recorder, if any.
+// Call with lock held.
+func (d *Device) recordRX(pipe int, payload []byte) {
+	if d.recorder == nil {
+		return
+	}
+	d.recorder.RecordFrame(Frame{
+		Direction:    FrameRX,
+		Timestamp:    d.config.Clock.Now(),
+		Pipe:         pipe,
+		Address:      d.pipeAddress(pipe),
+		AddressWidth: d.config.AddressWidth,
+		Channel:      d.config.ChannelNumber,
+		DataRate:     d.config.DataRate,
+		// The payload may be the buffer of ReceiveInto, which the caller reuses
+		Payload: bytes.Clone(payload),
+	})
+}
+
+// recordTX hands a transmitted frame to the recorder, if any.
+// Call with lock held.
+func (d *Device) recordTX(payload []byte, noAck bool, retransmits byte, err error) {
+	if d.recorder == nil {
+		return
+	}
+	f := Frame{
+		Direction:    FrameTX,
+		Timestamp:    d.config.Clock.Now(),
+		Address:      d.txAddr,
+		AddressWidth: d.config.AddressWidth,
+		Channel:      d.config.ChannelNumber,
+		DataRate:     d.config.DataRate,
+		Retransmits:  retransmits,
+		NoAck:        noAck,
+		Failed:       err != nil,
+		Payload:      bytes.Clone(payload),
+	}
+	d.recorder.RecordFrame(f)
+}
@@ -0,0 +1,59 @@
+package nrf24
+
+import (
+	"testing"
+)
+
+type frameLog struct {
+	frames []Frame
+}
+
+func (l *frameLog) RecordFrame(f Frame) { l.frames = append(l.frames, f) }
+
+func TestFrameRecorder(t *testing.T) {
+	mockSPI := &mockSPIConn{}
+	dev, _ := NewWithHardware(HardwareConfig{
+		RadioConfig: RadioConfig{
+			RxAddr:               Address{0xE7, 0xE7, 0xE7, 0xE7, 0xE7},
+			EnableDynamicPayload: true,
+		},
+		CE: &mockPin{},
+	}, mockSPI)
+
+	log := &frameLog{}
+	dev.SetFrameRecorder(log)
+
+	// Receive "ok" on pipe 1 (RX_P_NO = 001)
+	mockSPI.rxQueue = nil
+	mockSPI.queueRx([]byte{0x00, 0x42})
+	mockSPI.queueRx([]byte{0x42, 0x00})
+	mockSPI.queueRx([]byte{0x42, 0x02})
+	mockSPI.queueRx([]byte{0x42, 'o', 'k'})
+	mockSPI.queueRx([]byte{0x00, 0x00})
+	if _, ok := dev.Receive(); !ok {
+		t.Fatal("Expected Receive to return true")
+	}
+
+	// Transmit delivered after 3 retransmissions
+	for i := 0; i < 5; i++ {
+		mockSPI.queueRx([]byte{0})
+	}
+	mockSPI.queueRx([]byte{0, 0x20}) // TX_DS
+	mockSPI.queueRx([]byte{0})       // clearStatus
+	mockSPI.queueRx([]byte{0, 0x03}) // OBSERVE_TX
+	dest := Address{1, 2, 3, 4, 5}
+	if err := dev.Transmit(dest, []byte("hi")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+
+	if len(log.frames) != 2 {
+		t.Fatalf("Expected 2 recorded frames, got %d", len(log.frames))
+	}
+	rx, tx := log.frames[0], log.frames[1]
+	if rx.Direction != FrameRX || rx.Pipe != 1 || rx.Address != dev.config.RxAddr || string(rx.Payload) != "ok" {
+		t.Errorf("Unexpected RX frame: %+v", rx)
+	}
+	if tx.Direction != FrameTX || tx.Address != dest || tx.Retransmits != 3 || tx.Failed || string(tx.Payload) != "hi" {
+		t.Errorf("Unexpected TX frame: %+v", tx)
+	}
+}
@@ -0,0 +1,351 @@
+//go:build !tinygo
+
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/discovery"
+)
+
+// Client is a connection to a gateway daemon. It implements nrf24.Radio on the radio
+// of the daemon. All methods are concurrent safe.
+type Client struct {
+	conn net.Conn
+
+	wmu sync.Mutex
+	enc *json.Encoder
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan message
+
+	packets chan Packet
+	done    chan struct{}
+
+	// timeout is the longest wait for the reply to a request. Guarded by mu.
+	timeout time.Duration
+	// subscribed is set once the subscriptions were made, by autoSubscribe or by the user.
+	// Guarded by mu.
+	subscribed bool
+}
+
+// allPipes are the pipes autoSubscribe subscribes to.
+var allPipes = []int{0, 1, 2, 3, 4, 5}
+
+// DefaultTimeout is the longest a Client waits for the reply to a request, unless changed
+// with SetTimeout.
+const DefaultTimeout = 10 * time.Second
+
+var _ nrf24.Radio = (*Client)(nil)
+
+// Dial connects to the daemon listening on the Unix socket at path.
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient creates a client on an established connection to the daemon.
+func NewClient(conn net.Conn) *Client {
+	c := &Client{
+		conn:    conn,
+		enc:     json.NewEncoder(conn),
+		pending: make(map[uint64]chan message),
+		packets: make(chan Packet, packetQueue),
+		done:    make(chan struct{}),
+		timeout: DefaultTimeout,
+	}
+	go c.readLoop()
+	return c
+}
+
+// SetTimeout sets the longest wait for the reply to a request, after which the request fails
+// with ErrNoReply. A zero timeout waits forever.
+func (c *Client) SetTimeout(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = d
+}
+
+// Close closes the connection to the daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// readLoop dispatches the replies and packets sent by the daemon.
+func (c *Client) readLoop() {
+	defer close(c.done)
+
+	scanner := bufio.NewScanner(c.conn)
+	for scanner.Scan() {
+		var m message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			break
+		}
+		if m.Packet != nil {
+			select {
+			case c.packets <- *m.Packet:
+			default:
+				// Nobody is reading: drop the packet
+			}
+			continue
+		}
+		c.mu.Lock()
+		ch := c.pending[m.ID]
+		delete(c.pending, m.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- m
+		}
+	}
+	c.conn.Close()
+}
+
+// call sends a request and waits for its reply.
+func (c *Client) call(req request) (message, error) {
+	ch := make(chan message, 1)
+	c.mu.Lock()
+	c.nextID++
+	req.ID = c.nextID
+	c.pending[req.ID] = ch
+	timeout := c.timeout
+	c.mu.Unlock()
+
+	c.wmu.Lock()
+	err := c.enc.Encode(req)
+	c.wmu.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, req.ID)
+		c.mu.Unlock()
+		return message{}, ErrClosed
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case m := <-ch:
+		return m, m.err()
+	case <-c.done:
+		return message{}, ErrClosed
+	case <-expired:
+		c.mu.Lock()
+		delete(c.pending, req.ID)
+		c.mu.Unlock()
+		return message{}, fmt.Errorf("%w: %s after %v", ErrNoReply, req.Op, timeout)
+	}
+}
+
+// Transmit sends a message through the radio of the daemon.
+func (c *Client) Transmit(destAddr nrf24.Address, p []byte) error {
+	_, err := c.call(request{Op: opTransmit, Address: destAddr, Data: p})
+	return err
+}
+
+// TransmitNoAck sends a message without requesting an acknowledgement.
+func (c *Client) TransmitNoAck(destAddr nrf24.Address, p []byte) error {
+	_, err := c.call(request{Op: opTransmitNoAck, Address: destAddr, Data: p})
+	return err
+}
+
+// OpenRxPipe opens a data pipe on the radio of the daemon. See nrf24.Device.OpenRxPipe.
+// Unless Subscribe or Unsubscribe was called before, the first OpenRxPipe subscribes to
+// every pipe, as a Device receives them all.
+func (c *Client) OpenRxPipe(pipeID int, address []byte) error {
+	if _, err := c.call(request{Op: opOpenPipe, Pipe: pipeID, Data: address}); err != nil {
+		return err
+	}
+	return c.autoSubscribe()
+}
+
+// CloseRxPipe closes a data pipe on the radio of the daemon.
+func (c *Client) CloseRxPipe(pipeID int) error {
+	_, err := c.call(request{Op: opClosePipe, Pipe: pipeID})
+	return err
+}
+
+// WriteAckPayload queues a payload to be sent with the next ACK on a pipe.
+func (c *Client) WriteAckPayload(pipeID int, data []byte) error {
+	_, err := c.call(request{Op: opAckPayload, Pipe: pipeID, Data: data})
+	return err
+}
+
+// PipeAddress returns the address of a data pipe on the radio of the daemon.
+func (c *Client) PipeAddress(pipeID int) (nrf24.Address, error) {
+	m, err := c.call(request{Op: opPipeAddress, Pipe: pipeID})
+	if err != nil || m.Address == nil {
+		return nrf24.Address{}, err
+	}
+	return *m.Address, nil
+}
+
+// RadioConfig returns the settings of the radio of the daemon,
+// or the zero RadioConfig if the request fails.
+func (c *Client) RadioConfig() nrf24.RadioConfig {
+	m, err := c.call(request{Op: opConfig})
+	if err != nil || m.Config == nil {
+		return nrf24.RadioConfig{}
+	}
+	return *m.Config
+}
+
+// SetChannel changes the RF channel of the radio of the daemon.
+// It affects every client of the daemon.
+func (c *Client) SetChannel(channel byte) error {
+	_, err := c.call(request{Op: opSetChannel, Value: uint16(channel)})
+	return err
+}
+
+// SetDataRate changes the data rate of the radio of the daemon.
+func (c *Client) SetDataRate(rate nrf24.DataRate) error {
+	_, err := c.call(request{Op: opSetDataRate, Value: uint16(rate)})
+	return err
+}
+
+// SetPALevel changes the power amplifier level of the radio of the daemon.
+func (c *Client) SetPALevel(level nrf24.PALevel) error {
+	_, err := c.call(request{Op: opSetPALevel, Value: uint16(level)})
+	return err
+}
+
+// SetAutoRetransmit changes the auto-retransmit delay and count of the radio of the daemon.
+func (c *Client) SetAutoRetransmit(delay uint16, count byte) error {
+	_, err := c.call(request{Op: opSetRetransmit, Value: delay, Count: count})
+	return err
+}
+
+// SetAddressWidth changes the address width of the radio of the daemon.
+func (c *Client) SetAddressWidth(width byte) error {
+	_, err := c.call(request{Op: opSetAddrWidth, Value: uint16(width)})
+	return err
+}
+
+// PowerUp wakes the radio of the daemon from Power Down mode.
+// Like nrf24.Device.PowerUp it reports no error: a failed request is ignored.
+func (c *Client) PowerUp() {
+	c.call(request{Op: opPowerUp})
+}
+
+// PowerDown puts the radio of the daemon in Power Down mode.
+// A failed request is ignored.
+func (c *Client) PowerDown() {
+	c.call(request{Op: opPowerDown})
+}
+
+// FetchStats returns the traffic counters of the radio of the daemon.
+func (c *Client) FetchStats() (nrf24.Stats, error) {
+	m, err := c.call(request{Op: opStats})
+	if err != nil || m.Stats == nil {
+		return nrf24.Stats{}, err
+	}
+	return *m.Stats, nil
+}
+
+// Nodes returns the nodes discovered by the daemon, by ID.
+// It fails if the daemon does not record announcements (see Server.EnableDiscovery).
+func (c *Client) Nodes() ([]discovery.Node, error) {
+	m, err := c.call(request{Op: opNodes})
+	if err != nil {
+		return nil, err
+	}
+	return m.Nodes, nil
+}
+
+// Subscribe starts delivering the packets received on the given pipes to this client.
+// Every subscribed client gets its own copy of each packet.
+// Once Subscribe or Unsubscribe is called, the client no longer subscribes on its own.
+func (c *Client) Subscribe(pipes ...int) error {
+	c.setSubscribed()
+	_, err := c.call(request{Op: opSubscribe, Pipes: pipes})
+	return err
+}
+
+// Unsubscribe stops delivering the packets received on the given pipes.
+func (c *Client) Unsubscribe(pipes ...int) error {
+	c.setSubscribed()
+	_, err := c.call(request{Op: opUnsubscribe, Pipes: pipes})
+	return err
+}
+
+// setSubscribed records that the subscriptions were made.
+func (c *Client) setSubscribed() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribed = true
+}
+
+// autoSubscribe subscribes to every pipe, unless the subscriptions were already made, so that
+// code written for an nrf24.Radio receives through a Client like through a Device.
+func (c *Client) autoSubscribe() error {
+	c.mu.Lock()
+	subscribed := c.subscribed
+	c.mu.Unlock()
+	if subscribed {
+		return nil
+	}
+	if _, err := c.call(request{Op: opSubscribe, Pipes: allPipes}); err != nil {
+		return err
+	}
+	c.setSubscribed()
+	return nil
+}
+
+// Receive returns the next packet delivered to this client, if any, without blocking.
+// Unless Subscribe or Unsubscribe was called before, the first call subscribes to every pipe.
+func (c *Client) Receive() ([]byte, bool) {
+	data, _, ok := c.ReceiveWithPipe()
+	return data, ok
+}
+
+// ReceiveWithPipe is like Receive but also returns the data pipe the packet arrived on.
+func (c *Client) ReceiveWithPipe() ([]byte, int, bool) {
+	if err := c.autoSubscribe(); err != nil {
+		return nil, 0, false
+	}
+	select {
+	case p := <-c.packets:
+		return p.Data, p.Pipe, true
+	default:
+		return nil, 0, false
+	}
+}
+
+// ReceiveBlocking waits for a packet to be delivered or for the context to be cancelled.
+// Like Receive, the first call subscribes to every pipe unless Subscribe was called.
+func (c *Client) ReceiveBlocking(ctx context.Context) ([]byte, error) {
+	data, _, err := c.ReceiveBlockingWithPipe(ctx)
+	return data, err
+}
+
+// ReceiveBlockingWithPipe is like ReceiveBlocking but also returns the data pipe the packet arrived on.
+func (c *Client) ReceiveBlockingWithPipe(ctx context.Context) ([]byte, int, error) {
+	if err := c.autoSubscribe(); err != nil {
+		return nil, 0, err
+	}
+	select {
+	case p := <-c.packets:
+		return p.Data, p.Pipe, nil
+	case <-c.done:
+		// Deliver what was received before the connection closed
+		if data, pipe, ok := c.ReceiveWithPipe(); ok {
+			return data, pipe, nil
+		}
+		return nil, 0, ErrClosed
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+}
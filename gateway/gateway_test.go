@@ -0,0 +1,395 @@
+//go:build !tinygo
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/discovery"
+	"github.com/michcald/nrf24/radiohead"
+	"github.com/michcald/nrf24/sim"
+)
+
+var (
+	// daemonAddr shares its high bytes with discovery.AnnounceAddress, for the discovery pipe
+	daemonAddr = nrf24.Address{0xA1, 0xD1, 0x5C, 0x0F, 0xE7}
+	peerAddr   = nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}
+)
+
+// newRadios creates the radio of the daemon and a peer radio on the same air.
+func newRadios(t *testing.T) (dev, peer *nrf24.Device) {
+	t.Helper()
+	air := sim.NewAir()
+	dev, _, err := air.NewDevice(nrf24.RadioConfig{
+		ChannelNumber:        76,
+		RxAddr:               daemonAddr,
+		EnableDynamicPayload: true,
+	})
+	if err != nil {
+		t.Fatalf("NewDevice(daemon) failed: %v", err)
+	}
+	peer, _, err = air.NewDevice(nrf24.RadioConfig{
+		ChannelNumber:        76,
+		RxAddr:               peerAddr,
+		EnableDynamicPayload: true,
+	})
+	if err != nil {
+		t.Fatalf("NewDevice(peer) failed: %v", err)
+	}
+	return dev, peer
+}
+
+// startGateway runs a server on an emulated radio and returns a peer radio on the same air.
+func startGateway(t *testing.T) (socket string, peer *nrf24.Device) {
+	t.Helper()
+	dev, peer := newRadios(t)
+	srv := NewServer(dev)
+	if err := srv.EnableDiscovery(discovery.NewRegistry(), 2); err != nil {
+		t.Fatalf("EnableDiscovery failed: %v", err)
+	}
+	return serve(t, srv), peer
+}
+
+// serve runs a server until the end of the test and returns the path of its socket.
+func serve(t *testing.T, srv *Server) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "nrf24d.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe(ctx, socket) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("ListenAndServe failed: %v", err)
+		}
+	})
+
+	// Wait for the socket to appear
+	for i := 0; ; i++ {
+		c, err := Dial(socket)
+		if err == nil {
+			c.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return socket
+}
+
+func dial(t *testing.T, socket string) *Client {
+	t.Helper()
+	c, err := Dial(socket)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestSubscribe(t *testing.T) {
+	socket, peer := startGateway(t)
+	logger := dial(t, socket)
+	controller := dial(t, socket)
+	other := dial(t, socket)
+
+	if err := logger.Subscribe(1); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := controller.Subscribe(0, 1); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := other.Subscribe(2); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	if err := peer.Transmit(daemonAddr, []byte("hello")); err != nil {
+		t.Fatalf("peer Transmit failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, c := range []*Client{logger, controller} {
+		data, pipe, err := c.ReceiveBlockingWithPipe(ctx)
+		if err != nil {
+			t.Fatalf("ReceiveBlockingWithPipe failed: %v", err)
+		}
+		if string(data) != "hello" || pipe != 1 {
+			t.Errorf("Expected 'hello' on pipe 1, got %q on pipe %d", data, pipe)
+		}
+	}
+
+	// Unsubscribed pipes are not delivered
+	time.Sleep(20 * time.Millisecond)
+	if data, ok := other.Receive(); ok {
+		t.Errorf("Expected no packet for pipe 2 subscriber, got %q", data)
+	}
+}
+
+func TestTransmitAndStats(t *testing.T) {
+	socket, peer := startGateway(t)
+	c := dial(t, socket)
+
+	if err := c.Transmit(peerAddr, []byte("ping")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	data, ok := peer.Receive()
+	if !ok || string(data) != "ping" {
+		t.Fatalf("Expected peer to receive 'ping', got %q (%v)", data, ok)
+	}
+
+	err := c.Transmit(nrf24.Address{0x01, 0x02, 0x03, 0x04, 0x05}, []byte("nobody"))
+	if !errors.Is(err, nrf24.ErrMaxRetries) || !errors.Is(err, ErrRemote) {
+		t.Fatalf("Expected remote ErrMaxRetries, got %v", err)
+	}
+
+	stats, err := c.FetchStats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TxSuccess != 1 || stats.TxMaxRetries != 1 {
+		t.Errorf("Expected 1 success and 1 failure, got %+v", stats)
+	}
+}
+
+func TestPipesAndAckPayload(t *testing.T) {
+	socket, peer := startGateway(t)
+	c := dial(t, socket)
+
+	pipeAddr := []byte{0xC3, 0xC3, 0xC3, 0xC3, 0xC3}
+	if err := c.OpenRxPipe(1, pipeAddr); err != nil {
+		t.Fatalf("OpenRxPipe failed: %v", err)
+	}
+	if err := c.Subscribe(1); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := c.WriteAckPayload(1, []byte("ack")); err != nil {
+		t.Fatalf("WriteAckPayload failed: %v", err)
+	}
+
+	if err := peer.Transmit(nrf24.Address(pipeAddr), []byte("req")); err != nil {
+		t.Fatalf("peer Transmit failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := c.ReceiveBlocking(ctx)
+	if err != nil || string(data) != "req" {
+		t.Fatalf("Expected 'req', got %q (%v)", data, err)
+	}
+
+	if err := c.CloseRxPipe(6); err == nil {
+		t.Error("Expected an error closing an invalid pipe")
+	}
+	if err := c.Subscribe(7); err == nil {
+		t.Error("Expected an error subscribing to an invalid pipe")
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	socket, _ := startGateway(t)
+	c := dial(t, socket)
+	c.Close()
+
+	if err := c.Transmit(peerAddr, []byte("x")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if _, err := c.ReceiveBlocking(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestClientRadio(t *testing.T) {
+	socket, _ := startGateway(t)
+	var radio nrf24.Radio = dial(t, socket)
+
+	if got := radio.RadioConfig().ChannelNumber; got != 76 {
+		t.Errorf("ChannelNumber = %d, want 76", got)
+	}
+	if err := radio.SetChannel(90); err != nil {
+		t.Fatalf("SetChannel failed: %v", err)
+	}
+	if err := radio.SetPALevel(nrf24.PALevelLow); err != nil {
+		t.Fatalf("SetPALevel failed: %v", err)
+	}
+	if err := radio.SetAutoRetransmit(500, 5); err != nil {
+		t.Fatalf("SetAutoRetransmit failed: %v", err)
+	}
+	rc := radio.RadioConfig()
+	if rc.ChannelNumber != 90 || rc.PALevel != nrf24.PALevelLow || rc.AutoRetransmitDelay != 500 || rc.AutoRetransmitCount != 5 {
+		t.Errorf("RadioConfig() = %+v, want channel 90, PALevelLow, 500µs x5", rc)
+	}
+	if err := radio.SetChannel(200); !errors.Is(err, ErrRemote) {
+		t.Errorf("Expected ErrRemote for an invalid channel, got %v", err)
+	}
+
+	addr, err := radio.PipeAddress(1)
+	if err != nil || addr != daemonAddr {
+		t.Errorf("PipeAddress(1) = %X, %v, want %X", addr, err, daemonAddr)
+	}
+	radio.PowerDown()
+	radio.PowerUp()
+}
+
+func TestNodes(t *testing.T) {
+	socket, peer := startGateway(t)
+	c := dial(t, socket)
+
+	nodes, err := c.Nodes()
+	if err != nil || len(nodes) != 0 {
+		t.Fatalf("Nodes() = %v, %v, want no node", nodes, err)
+	}
+
+	a, err := discovery.NewAnnouncer(peer, discovery.Announcement{NodeID: 7, Address: peerAddr, Firmware: discovery.Version{Major: 2}})
+	if err != nil {
+		t.Fatalf("NewAnnouncer failed: %v", err)
+	}
+	if err := a.Announce(); err != nil {
+		t.Fatalf("Announce failed: %v", err)
+	}
+	for i := 0; ; i++ {
+		nodes, err = c.Nodes()
+		if err != nil {
+			t.Fatalf("Nodes failed: %v", err)
+		}
+		if len(nodes) == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("Expected the announcing node to be discovered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := nodes[0]; n.NodeID != 7 || n.Address != peerAddr || n.Firmware.Major != 2 || n.LastSeen.IsZero() {
+		t.Errorf("Nodes() = %+v", n)
+	}
+}
+
+func TestServerOverClient(t *testing.T) {
+	socket, peer := startGateway(t)
+	// A daemon fronting the radio of another daemon, through a client
+	c := dial(t, serve(t, NewServer(dial(t, socket))))
+
+	if err := c.Transmit(peerAddr, []byte("ping")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	if data, ok := peer.Receive(); !ok || string(data) != "ping" {
+		t.Fatalf("Expected peer to receive 'ping', got %q (%v)", data, ok)
+	}
+	stats, err := c.FetchStats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.TxSuccess != 1 {
+		t.Errorf("Expected 1 success, got %+v", stats)
+	}
+}
+
+// flakyRadio fails its first receive calls.
+type flakyRadio struct {
+	nrf24.Radio
+	failures int
+}
+
+func (r *flakyRadio) ReceiveBlockingWithPipe(ctx context.Context) ([]byte, int, error) {
+	if r.failures > 0 {
+		r.failures--
+		return nil, 0, errors.New("bus error")
+	}
+	return r.Radio.ReceiveBlockingWithPipe(ctx)
+}
+
+// warnCounter counts the warnings logged.
+type warnCounter struct {
+	warnings atomic.Int32
+}
+
+func (l *warnCounter) Debug(msg string) {}
+func (l *warnCounter) Info(msg string)  {}
+func (l *warnCounter) Warn(msg string)  { l.warnings.Add(1) }
+func (l *warnCounter) Error(msg string) {}
+
+func TestReceiveRetries(t *testing.T) {
+	dev, peer := newRadios(t)
+	srv := NewServer(&flakyRadio{Radio: dev, failures: 3})
+	logger := &warnCounter{}
+	srv.SetLogger(logger)
+	c := dial(t, serve(t, srv))
+	if err := c.Subscribe(1); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	if err := peer.Transmit(daemonAddr, []byte("hello")); err != nil {
+		t.Fatalf("peer Transmit failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := c.ReceiveBlocking(ctx)
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("Expected 'hello' after the receive errors, got %q (%v)", data, err)
+	}
+	if n := logger.warnings.Load(); n != 3 {
+		t.Errorf("Expected 3 logged warnings, got %d", n)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	conn, daemon := net.Pipe()
+	defer daemon.Close()
+	// A daemon reading requests and never replying
+	go io.Copy(io.Discard, daemon)
+
+	c := NewClient(conn)
+	defer c.Close()
+	c.SetTimeout(20 * time.Millisecond)
+	if err := c.Transmit(peerAddr, []byte("x")); !errors.Is(err, ErrNoReply) {
+		t.Errorf("Expected ErrNoReply, got %v", err)
+	}
+}
+
+func TestRadioHeadOverClient(t *testing.T) {
+	air := sim.NewAir()
+	dev, _, err := air.NewDevice(radiohead.RadioConfig())
+	if err != nil {
+		t.Fatalf("NewDevice(daemon) failed: %v", err)
+	}
+	peerDev, _, err := air.NewDevice(radiohead.RadioConfig())
+	if err != nil {
+		t.Fatalf("NewDevice(peer) failed: %v", err)
+	}
+
+	// Generic nrf24.Radio code receives through a client without subscribing
+	node := radiohead.NewNode(dial(t, serve(t, NewServer(dev))), 1)
+	peer := radiohead.NewNode(peerDev, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	// The first receive subscribes: wait for it before the peer sends
+	if _, ok := node.Receive(); ok {
+		t.Fatal("Expected no message yet")
+	}
+	if err := peer.SendTo([]byte("hello"), 1); err != nil {
+		t.Fatalf("peer SendTo failed: %v", err)
+	}
+	m, err := node.ReceiveBlocking(ctx)
+	if err != nil || string(m.Data) != "hello" || m.From != 2 {
+		t.Fatalf("Expected 'hello' from 2, got %+v (%v)", m, err)
+	}
+
+	if err := node.SendTo([]byte("reply"), 2); err != nil {
+		t.Fatalf("SendTo failed: %v", err)
+	}
+	m, err = peer.ReceiveBlocking(ctx)
+	if err != nil || string(m.Data) != "reply" || m.From != 1 {
+		t.Errorf("Expected 'reply' from 1, got %+v (%v)", m, err)
+	}
+}
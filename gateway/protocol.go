@@ -0,0 +1,118 @@
+//go:build !tinygo
+
+// Package gateway shares one nRF24L01+ radio between several local processes.
+//
+// A Server owns the radio and listens on a Unix domain socket; every Client
+// connected to it can transmit, manage pipes and ACK payloads, change the RF
+// settings, read the traffic counters, subscribe to the packets received on
+// any data pipe and query the nodes discovered by the daemon. A Client
+// implements nrf24.Radio, so code written against the interface runs unchanged
+// on the radio of the daemon.
+//
+// The protocol is newline-delimited JSON. A client sends requests carrying an id,
+// the server answers each request with a message carrying the same id, and
+// pushes received packets to subscribed clients as messages without an id.
+package gateway
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/discovery"
+)
+
+// Request operations.
+const (
+	opTransmit      = "transmit"
+	opTransmitNoAck = "transmit_noack"
+	opOpenPipe      = "open_pipe"
+	opClosePipe     = "close_pipe"
+	opAckPayload    = "ack_payload"
+	opPipeAddress   = "pipe_address"
+	opStats         = "stats"
+	opConfig        = "config"
+	opSetChannel    = "set_channel"
+	opSetDataRate   = "set_data_rate"
+	opSetPALevel    = "set_pa_level"
+	opSetRetransmit = "set_auto_retransmit"
+	opSetAddrWidth  = "set_address_width"
+	opPowerUp       = "power_up"
+	opPowerDown     = "power_down"
+	opNodes         = "nodes"
+	opSubscribe     = "subscribe"
+	opUnsubscribe   = "unsubscribe"
+)
+
+// request is sent by a client to the server.
+type request struct {
+	ID      uint64        `json:"id"`
+	Op      string        `json:"op"`
+	Address nrf24.Address `json:"address"`
+	Pipe    int           `json:"pipe,omitempty"`
+	Pipes   []int         `json:"pipes,omitempty"`
+	Data    []byte        `json:"data,omitempty"`
+	// Value is the setting of the set_* operations.
+	Value uint16 `json:"value,omitempty"`
+	// Count is the retransmission count of set_auto_retransmit.
+	Count byte `json:"count,omitempty"`
+}
+
+// message is sent by the server to a client: either the reply to a request or a received packet.
+type message struct {
+	ID      uint64             `json:"id,omitempty"`
+	Error   string             `json:"error,omitempty"`
+	Code    string             `json:"code,omitempty"`
+	Stats   *nrf24.Stats       `json:"stats,omitempty"`
+	Config  *nrf24.RadioConfig `json:"config,omitempty"`
+	Address *nrf24.Address     `json:"address,omitempty"`
+	Packet  *Packet            `json:"packet,omitempty"`
+	Nodes   []discovery.Node   `json:"nodes,omitempty"`
+}
+
+// Packet is a payload received by the radio of the daemon.
+type Packet struct {
+	// Pipe is the data pipe (0-5) the packet arrived on.
+	Pipe int `json:"pipe"`
+	// Data holds the payload.
+	Data []byte `json:"data"`
+}
+
+// errorCodes maps the driver errors that survive the trip to the client.
+var errorCodes = map[string]error{
+	"max_retries":    nrf24.ErrMaxRetries,
+	"timeout":        nrf24.ErrTimeout,
+	"sniffer_active": nrf24.ErrSnifferActive,
+}
+
+// ErrRemote is returned when the daemon fails to execute a request.
+var ErrRemote = errors.New("gateway request failed")
+
+// ErrClosed is returned by a Client once its connection is closed.
+var ErrClosed = errors.New("gateway connection closed")
+
+// ErrNoReply is returned by a Client when the daemon does not reply to a request in time.
+var ErrNoReply = errors.New("gateway did not reply")
+
+// errorMessage builds the reply to a failed request.
+func errorMessage(id uint64, err error) message {
+	m := message{ID: id, Error: err.Error()}
+	for code, target := range errorCodes {
+		if errors.Is(err, target) {
+			m.Code = code
+			break
+		}
+	}
+	return m
+}
+
+// err rebuilds the error carried by a reply, if any.
+func (m message) err() error {
+	if m.Error == "" {
+		return nil
+	}
+	if target, ok := errorCodes[m.Code]; ok {
+		return fmt.Errorf("%w: %w: %s", ErrRemote, target, m.Error)
+	}
+	return fmt.Errorf("%w: %s", ErrRemote, m.Error)
+}
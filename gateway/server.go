@@ -0,0 +1,323 @@
+//go:build !tinygo
+
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/discovery"
+)
+
+// packetQueue is the number of received packets buffered per client.
+// Packets are dropped for clients that fall further behind.
+const packetQueue = 64
+
+// Delays between receive attempts after the radio failed to receive.
+const (
+	minReceiveBackoff = 10 * time.Millisecond
+	maxReceiveBackoff = 5 * time.Second
+)
+
+// Server shares a radio with the clients connected to it.
+type Server struct {
+	dev nrf24.Radio
+
+	logger nrf24.Logger
+
+	mu      sync.Mutex
+	clients map[*serverConn]struct{}
+	// registry records the announcements received on discoveryPipe, if not nil
+	registry      *discovery.Registry
+	discoveryPipe int
+}
+
+// serverConn is a client connection on the server side.
+type serverConn struct {
+	conn net.Conn
+	// out carries the messages to write to the client.
+	out  chan message
+	done chan struct{}
+	// subscribed is the set of pipes the client receives packets from. Guarded by Server.mu.
+	subscribed [6]bool
+}
+
+// NewServer creates a server for a configured device.
+// The server becomes the only reader of the device: nothing else should call its Receive methods.
+func NewServer(dev nrf24.Radio) *Server {
+	return &Server{
+		dev:     dev,
+		clients: make(map[*serverConn]struct{}),
+	}
+}
+
+// SetLogger sets the logger receiving the errors of the radio. Errors are not logged by default.
+// Call it before Serve.
+func (s *Server) SetLogger(l nrf24.Logger) {
+	s.logger = l
+}
+
+// EnableDiscovery opens a pipe on discovery.AnnounceAddress (see discovery.OpenPipe) and
+// records the announcements received on it in r. Clients can query the registry with
+// Client.Nodes, and still subscribe to the announcements.
+func (s *Server) EnableDiscovery(r *discovery.Registry, pipe int) error {
+	if err := discovery.OpenPipe(s.dev, pipe); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.registry = r
+	s.discoveryPipe = pipe
+	return nil
+}
+
+// ListenAndServe listens on the Unix socket at path and serves clients until ctx is cancelled.
+// A stale socket file left by a previous run is removed first.
+func (s *Server) ListenAndServe(ctx context.Context, path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	return s.Serve(ctx, l)
+}
+
+// Serve accepts clients on l until ctx is cancelled, then closes l and every client connection.
+// It also runs the receive loop that forwards packets to subscribed clients.
+// It returns nil when stopped by ctx.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.receiveLoop(ctx)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var err error
+	for {
+		conn, aerr := l.Accept()
+		if aerr != nil {
+			if ctx.Err() == nil {
+				err = aerr
+			}
+			break
+		}
+		c := &serverConn{
+			conn: conn,
+			out:  make(chan message, packetQueue),
+			done: make(chan struct{}),
+		}
+		s.mu.Lock()
+		s.clients[c] = struct{}{}
+		s.mu.Unlock()
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.writeLoop(c)
+		}()
+		go func() {
+			defer wg.Done()
+			s.handle(ctx, c)
+		}()
+	}
+
+	cancel()
+	s.mu.Lock()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+	wg.Wait()
+	return err
+}
+
+// receiveLoop reads packets from the device and forwards them to the subscribed clients
+// until ctx is cancelled. Receive errors are logged and retried with an increasing delay.
+func (s *Server) receiveLoop(ctx context.Context) {
+	backoff := minReceiveBackoff
+	for {
+		data, pipe, err := s.dev.ReceiveBlockingWithPipe(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if s.logger != nil {
+				s.logger.Warn(fmt.Sprintf("gateway: receive failed, retrying in %v: %v", backoff, err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxReceiveBackoff)
+			continue
+		}
+		backoff = minReceiveBackoff
+		m := message{Packet: &Packet{Pipe: pipe, Data: data}}
+
+		s.mu.Lock()
+		if s.registry != nil && pipe == s.discoveryPipe {
+			// Ignore stray packets sent to the announcement address
+			s.registry.Handle(data)
+		}
+		for c := range s.clients {
+			if !c.subscribed[pipe] {
+				continue
+			}
+			select {
+			case c.out <- m:
+			default:
+				// Slow client: drop the packet rather than stall the radio
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// writeLoop writes the queued messages to the client until the connection is done.
+func (s *Server) writeLoop(c *serverConn) {
+	enc := json.NewEncoder(c.conn)
+	for {
+		select {
+		case m := <-c.out:
+			if err := enc.Encode(m); err != nil {
+				c.conn.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// handle reads and executes the requests of a client until it disconnects.
+func (s *Server) handle(ctx context.Context, c *serverConn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+		close(c.done)
+		c.conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(c.conn)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return
+		}
+		reply := s.execute(c, req)
+		select {
+		case c.out <- reply:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// execute runs a single request and returns its reply.
+func (s *Server) execute(c *serverConn, req request) message {
+	var err error
+	reply := message{ID: req.ID}
+
+	switch req.Op {
+	case opTransmit:
+		err = s.dev.Transmit(req.Address, req.Data)
+	case opTransmitNoAck:
+		err = s.dev.TransmitNoAck(req.Address, req.Data)
+	case opOpenPipe:
+		err = s.dev.OpenRxPipe(req.Pipe, req.Data)
+	case opClosePipe:
+		err = s.dev.CloseRxPipe(req.Pipe)
+	case opAckPayload:
+		err = s.dev.WriteAckPayload(req.Pipe, req.Data)
+	case opPipeAddress:
+		var addr nrf24.Address
+		addr, err = s.dev.PipeAddress(req.Pipe)
+		reply.Address = &addr
+	case opStats:
+		var stats nrf24.Stats
+		switch dev := s.dev.(type) {
+		case interface{ Stats() nrf24.Stats }:
+			stats = dev.Stats()
+		case interface{ FetchStats() (nrf24.Stats, error) }:
+			// e.g. a Client of another daemon
+			stats, err = dev.FetchStats()
+		default:
+			err = errors.New("radio has no traffic counters")
+		}
+		if err == nil {
+			reply.Stats = &stats
+		}
+	case opConfig:
+		config := s.dev.RadioConfig()
+		reply.Config = &config
+	case opSetChannel:
+		err = s.dev.SetChannel(byte(req.Value))
+	case opSetDataRate:
+		err = s.dev.SetDataRate(nrf24.DataRate(req.Value))
+	case opSetPALevel:
+		err = s.dev.SetPALevel(nrf24.PALevel(req.Value))
+	case opSetRetransmit:
+		err = s.dev.SetAutoRetransmit(req.Value, req.Count)
+	case opSetAddrWidth:
+		err = s.dev.SetAddressWidth(byte(req.Value))
+	case opPowerUp:
+		s.dev.PowerUp()
+	case opPowerDown:
+		s.dev.PowerDown()
+	case opNodes:
+		s.mu.Lock()
+		r := s.registry
+		s.mu.Unlock()
+		if r == nil {
+			err = errors.New("discovery not enabled")
+			break
+		}
+		reply.Nodes = r.Nodes()
+	case opSubscribe, opUnsubscribe:
+		err = s.subscribe(c, req.Pipes, req.Op == opSubscribe)
+	default:
+		err = fmt.Errorf("unknown operation %q", req.Op)
+	}
+
+	if err != nil {
+		return errorMessage(req.ID, err)
+	}
+	return reply
+}
+
+// subscribe adds or removes pipes from the subscriptions of a client.
+func (s *Server) subscribe(c *serverConn, pipes []int, on bool) error {
+	for _, p := range pipes {
+		if p < 0 || p > 5 {
+			return fmt.Errorf("invalid pipe %d: must be 0-5", p)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range pipes {
+		c.subscribed[p] = on
+	}
+	return nil
+}
@@ -0,0 +1,444 @@
+// Package chip models the register map, the SPI command set and the FIFOs of an
+// nRF24L01+. It is the radio behind the emulated radios of sim and the mock radio of
+// nrf24test, which only differ in the Medium carrying the transmitted packets.
+package chip
+
+import "bytes"
+
+// Register addresses and bits used by the model.
+const (
+	regConfig     = 0x00
+	regEnAA       = 0x01
+	regEnRxAddr   = 0x02
+	regSetupAW    = 0x03
+	regSetupRetr  = 0x04
+	regRFCh       = 0x05
+	regRFSetup    = 0x06
+	regStatus     = 0x07
+	regObserveTX  = 0x08
+	regRPD        = 0x09
+	regRxAddrP0   = 0x0A
+	regRxAddrP1   = 0x0B
+	regTxAddr     = 0x10
+	regRxPwP0     = 0x11
+	regFIFOStatus = 0x17
+	regDynPD      = 0x1C
+	regFeature    = 0x1D
+
+	bitPrimRX   = 1 << 0
+	bitPwrUp    = 1 << 1
+	bitEnDPL    = 1 << 2
+	bitEnAckPay = 1 << 1
+	bitRxDR     = 1 << 6
+	bitTxDS     = 1 << 5
+	bitMaxRT    = 1 << 4
+
+	// Flags are the interrupt flags of STATUS: RX_DR, TX_DS and MAX_RT.
+	Flags = bitRxDR | bitTxDS | bitMaxRT
+
+	fifoDepth = 3
+)
+
+// Packet is a payload of the TX FIFO.
+type Packet struct {
+	Data  []byte
+	NoAck bool
+}
+
+// Medium carries the packets transmitted by a Chip. Its methods are called by the
+// methods of the Chip, with the lock of the caller held.
+type Medium interface {
+	// Transmit sends a packet from c. It reports whether the packet was acknowledged,
+	// with the ACK payload if any, and whether the transmission completed at all: an
+	// incomplete transmission raises no interrupt and leaves the packet in the TX FIFO.
+	Transmit(c *Chip, p Packet) (acked bool, ackPayload []byte, done bool)
+	// Carrier reports whether a carrier is present on a channel, for RPD.
+	Carrier(channel byte) bool
+}
+
+type rxEntry struct {
+	pipe byte
+	data []byte
+}
+
+// Chip is the state of an nRF24L01+. As on the real chip, a packet is transmitted on a
+// rising edge of CE, or when a payload is written or the radio is powered up in PTX mode
+// while CE is high, and only if the radio is powered up in PTX mode.
+//
+// Chip is not concurrent safe: the callers serialize the calls of its methods.
+type Chip struct {
+	medium Medium
+
+	regs     [0x1E]byte
+	rxAddrP0 [5]byte
+	rxAddrP1 [5]byte
+	txAddr   [5]byte
+	ce       bool
+	rxFIFO   []rxEntry
+	txFIFO   []Packet
+	ackFIFO  [6][][]byte
+	reuse    bool
+	lastTX   *Packet
+}
+
+// New creates a chip in its power-on reset state, transmitting on m.
+func New(m Medium) *Chip {
+	c := &Chip{medium: m}
+	c.regs[regConfig] = 0x08
+	c.regs[regEnAA] = 0x3F
+	c.regs[regEnRxAddr] = 0x03
+	c.regs[regSetupAW] = 0x03
+	c.regs[regSetupRetr] = 0x03
+	c.regs[regRFCh] = 0x02
+	c.regs[regRFSetup] = 0x0E
+	c.regs[0x0C], c.regs[0x0D], c.regs[0x0E], c.regs[0x0F] = 0xC3, 0xC4, 0xC5, 0xC6
+	c.rxAddrP0 = [5]byte{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}
+	c.rxAddrP1 = [5]byte{0xC2, 0xC2, 0xC2, 0xC2, 0xC2}
+	c.txAddr = [5]byte{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}
+	return c
+}
+
+// Execute runs one SPI command: w is the MOSI data and out receives the MISO data,
+// starting with STATUS. w and out must not overlap.
+func (c *Chip) Execute(w, out []byte) {
+	if len(w) == 0 {
+		return
+	}
+	if len(out) > 0 {
+		out[0] = c.Status()
+		out = out[1:]
+	}
+	cmd, args := w[0], w[1:]
+
+	switch {
+	case cmd < 0x20: // R_REGISTER
+		reg := cmd & 0x1F
+		if addr := c.addrReg(reg); addr != nil {
+			copy(out, addr[:])
+			return
+		}
+		if len(out) > 0 {
+			out[0] = c.Register(reg)
+		}
+	case cmd < 0x40: // W_REGISTER
+		if len(args) > 0 {
+			c.writeReg(cmd&0x1F, args)
+		}
+	case cmd == 0x60: // R_RX_PL_WID
+		if len(out) > 0 && len(c.rxFIFO) > 0 {
+			out[0] = byte(len(c.rxFIFO[0].data))
+		}
+	case cmd == 0x61: // R_RX_PAYLOAD
+		if len(c.rxFIFO) > 0 {
+			copy(out, c.rxFIFO[0].data)
+			c.rxFIFO = c.rxFIFO[1:]
+		}
+	case cmd == 0xA0, cmd == 0xB0: // W_TX_PAYLOAD, W_TX_PAYLOAD_NOACK
+		if c.txLevel() < fifoDepth {
+			c.reuse = false
+			c.txFIFO = append(c.txFIFO, Packet{Data: bytes.Clone(args), NoAck: cmd == 0xB0})
+		}
+		if c.ce {
+			c.transmit()
+		}
+	case cmd >= 0xA8 && cmd <= 0xAD: // W_ACK_PAYLOAD
+		pipe := cmd & 0x07
+		if c.txLevel() < fifoDepth {
+			c.ackFIFO[pipe] = append(c.ackFIFO[pipe], bytes.Clone(args))
+		}
+	case cmd == 0xE1: // FLUSH_TX
+		c.txFIFO = nil
+		c.ackFIFO = [6][][]byte{}
+		c.reuse = false
+		c.lastTX = nil
+	case cmd == 0xE2: // FLUSH_RX
+		c.rxFIFO = nil
+	case cmd == 0xE3: // REUSE_TX_PL
+		c.reuse = len(c.txFIFO) > 0 || c.lastTX != nil
+	}
+}
+
+func (c *Chip) addrReg(reg byte) *[5]byte {
+	switch reg {
+	case regRxAddrP0:
+		return &c.rxAddrP0
+	case regRxAddrP1:
+		return &c.rxAddrP1
+	case regTxAddr:
+		return &c.txAddr
+	}
+	return nil
+}
+
+// Register returns the value of a register: the first byte for address registers.
+func (c *Chip) Register(reg byte) byte {
+	switch reg {
+	case regStatus:
+		return c.Status()
+	case regRxAddrP0, regRxAddrP1, regTxAddr:
+		return c.addrReg(reg)[0]
+	case regRPD:
+		if c.regs[regConfig]&bitPrimRX != 0 && c.medium.Carrier(c.regs[regRFCh]) {
+			return 1
+		}
+		return 0
+	case regFIFOStatus:
+		var v byte
+		if c.reuse {
+			v |= 1 << 6
+		}
+		if c.txLevel() >= fifoDepth {
+			v |= 1 << 5
+		}
+		if c.txLevel() == 0 {
+			v |= 1 << 4
+		}
+		if len(c.rxFIFO) >= fifoDepth {
+			v |= 1 << 1
+		}
+		if len(c.rxFIFO) == 0 {
+			v |= 1 << 0
+		}
+		return v
+	}
+	if int(reg) < len(c.regs) {
+		return c.regs[reg]
+	}
+	return 0
+}
+
+// RegisterBytes returns every byte of a register, e.g. the 5 bytes of TX_ADDR.
+func (c *Chip) RegisterBytes(reg byte) []byte {
+	if addr := c.addrReg(reg); addr != nil {
+		return bytes.Clone(addr[:])
+	}
+	return []byte{c.Register(reg)}
+}
+
+// SetRegister sets the value of a register, read-only ones included, as if the chip
+// had changed it. It has none of the side effects of a W_REGISTER command.
+func (c *Chip) SetRegister(reg byte, val []byte) {
+	if len(val) == 0 {
+		return
+	}
+	if addr := c.addrReg(reg); addr != nil {
+		copy(addr[:], val)
+		return
+	}
+	if int(reg) < len(c.regs) {
+		c.regs[reg] = val[0]
+	}
+}
+
+func (c *Chip) writeReg(reg byte, args []byte) {
+	if addr := c.addrReg(reg); addr != nil {
+		copy(addr[:], args)
+		return
+	}
+	switch reg {
+	case regStatus:
+		// Interrupt flags are cleared by writing 1
+		c.regs[regStatus] &^= args[0] & Flags
+	case regObserveTX, regRPD, regFIFOStatus:
+		// Read only
+	case regRFCh:
+		c.regs[regRFCh] = args[0] & 0x7F
+		// Changing channel resets the lost packet counter
+		c.regs[regObserveTX] &= 0x0F
+	default:
+		if int(reg) < len(c.regs) {
+			c.regs[reg] = args[0]
+		}
+	}
+	if reg == regConfig && c.ce {
+		c.transmit()
+	}
+}
+
+// txLevel returns the number of TX FIFO slots in use.
+// As on the chip, queued ACK payloads share the TX FIFO with outgoing packets.
+func (c *Chip) txLevel() int {
+	n := len(c.txFIFO)
+	for _, q := range c.ackFIFO {
+		n += len(q)
+	}
+	return n
+}
+
+// Status returns the STATUS register.
+func (c *Chip) Status() byte {
+	s := c.regs[regStatus] & Flags
+	if len(c.rxFIFO) > 0 {
+		s |= c.rxFIFO[0].pipe << 1
+	} else {
+		s |= 7 << 1
+	}
+	if c.txLevel() >= fifoDepth {
+		s |= 1
+	}
+	return s
+}
+
+// RaiseFlags raises interrupt flags of STATUS (see Flags).
+func (c *Chip) RaiseFlags(flags byte) {
+	c.regs[regStatus] |= flags & Flags
+}
+
+// IRQ reports whether an interrupt flag not masked in CONFIG is raised, i.e. whether
+// the IRQ pin is low.
+func (c *Chip) IRQ() bool {
+	return c.regs[regStatus]&^c.regs[regConfig]&Flags != 0
+}
+
+// CE returns the level of the CE input.
+func (c *Chip) CE() bool { return c.ce }
+
+// SetCE sets the level of the CE input, transmitting on a rising edge.
+func (c *Chip) SetCE(high bool) {
+	rising := high && !c.ce
+	c.ce = high
+	if rising {
+		c.transmit()
+	}
+}
+
+// Channel returns the RF channel.
+func (c *Chip) Channel() byte { return c.regs[regRFCh] }
+
+func (c *Chip) poweredTX() bool {
+	return c.regs[regConfig]&bitPwrUp != 0 && c.regs[regConfig]&bitPrimRX == 0
+}
+
+func (c *Chip) listening() bool {
+	return c.regs[regConfig]&bitPwrUp != 0 && c.regs[regConfig]&bitPrimRX != 0 && c.ce
+}
+
+func (c *Chip) addressWidth() int {
+	aw := int(c.regs[regSetupAW] & 0x03)
+	if aw == 0 {
+		return 2
+	}
+	return aw + 2
+}
+
+// pipeAddress returns the address of an RX pipe.
+func (c *Chip) pipeAddress(pipe int) [5]byte {
+	switch pipe {
+	case 0:
+		return c.rxAddrP0
+	case 1:
+		return c.rxAddrP1
+	}
+	addr := c.rxAddrP1
+	addr[0] = c.regs[regRxAddrP0+pipe]
+	return addr
+}
+
+// transmit sends the packet at the head of the TX FIFO, or the reused one.
+func (c *Chip) transmit() {
+	if !c.poweredTX() {
+		return
+	}
+	var p Packet
+	switch {
+	case len(c.txFIFO) > 0:
+		p = c.txFIFO[0]
+	case c.reuse && c.lastTX != nil:
+		p = *c.lastTX
+	default:
+		return
+	}
+
+	acked, ackPayload, done := c.medium.Transmit(c, p)
+	if !done {
+		return
+	}
+	width := c.addressWidth()
+	expectAck := !p.NoAck && c.regs[regEnAA]&0x01 != 0
+	// The ACK is received on pipe 0, which must hold the TX address
+	if expectAck && acked && !bytes.Equal(c.rxAddrP0[:width], c.txAddr[:width]) {
+		acked = false
+	}
+
+	if expectAck && !acked {
+		arc := c.regs[regSetupRetr] & 0x0F
+		plos := c.regs[regObserveTX] >> 4
+		if plos < 15 {
+			plos++
+		}
+		c.regs[regObserveTX] = plos<<4 | arc
+		c.regs[regStatus] |= bitMaxRT
+		return
+	}
+
+	c.regs[regObserveTX] &= 0xF0
+	c.regs[regStatus] |= bitTxDS
+	if expectAck && ackPayload != nil && len(c.rxFIFO) < fifoDepth {
+		c.rxFIFO = append(c.rxFIFO, rxEntry{pipe: 0, data: ackPayload})
+		c.regs[regStatus] |= bitRxDR
+	}
+	c.lastTX = &p
+	// A reused payload stays in the TX FIFO until FLUSH_TX or W_TX_PAYLOAD
+	if !c.reuse && len(c.txFIFO) > 0 {
+		c.txFIFO = c.txFIFO[1:]
+	}
+}
+
+// Receive delivers a packet transmitted by tx, if c listens on its channel, data rate
+// and address. It reports whether c acknowledges the packet, with the ACK payload it
+// had queued for the pipe.
+func (c *Chip) Receive(tx *Chip, p Packet) (acked bool, ackPayload []byte) {
+	width := tx.addressWidth()
+	if c == tx || !c.listening() || c.regs[regRFCh] != tx.regs[regRFCh] ||
+		c.regs[regRFSetup]&0x28 != tx.regs[regRFSetup]&0x28 || c.addressWidth() != width {
+		return false, nil
+	}
+	pipe := c.matchPipe(tx.txAddr[:width])
+	if pipe < 0 {
+		return false, nil
+	}
+	c.QueueRx(pipe, c.payloadFor(pipe, p.Data))
+	if p.NoAck || c.regs[regEnAA]&(1<<pipe) == 0 {
+		return false, nil
+	}
+	if c.regs[regFeature]&bitEnAckPay != 0 && len(c.ackFIFO[pipe]) > 0 {
+		ackPayload = c.ackFIFO[pipe][0]
+		c.ackFIFO[pipe] = c.ackFIFO[pipe][1:]
+	}
+	return true, ackPayload
+}
+
+// QueueRx puts a received packet in the RX FIFO and raises RX_DR.
+// It returns false if the RX FIFO is full, like the chip dropping the packet.
+func (c *Chip) QueueRx(pipe int, data []byte) bool {
+	if len(c.rxFIFO) >= fifoDepth {
+		return false
+	}
+	c.rxFIFO = append(c.rxFIFO, rxEntry{pipe: byte(pipe & 0x07), data: bytes.Clone(data)})
+	c.regs[regStatus] |= bitRxDR
+	return true
+}
+
+// matchPipe returns the enabled pipe listening on addr, or -1.
+func (c *Chip) matchPipe(addr []byte) int {
+	for pipe := 0; pipe < 6; pipe++ {
+		if c.regs[regEnRxAddr]&(1<<pipe) == 0 {
+			continue
+		}
+		pa := c.pipeAddress(pipe)
+		if bytes.Equal(pa[:len(addr)], addr) {
+			return pipe
+		}
+	}
+	return -1
+}
+
+// payloadFor sizes a received payload according to the pipe configuration.
+func (c *Chip) payloadFor(pipe int, data []byte) []byte {
+	if c.regs[regFeature]&bitEnDPL != 0 && c.regs[regDynPD]&(1<<pipe) != 0 {
+		return data
+	}
+	out := make([]byte, c.regs[regRxPwP0+pipe]&0x3F)
+	copy(out, data)
+	return out
+}
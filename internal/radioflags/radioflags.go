@@ -0,0 +1,256 @@
+//go:build !tinygo
+
+// Package radioflags provides the radio configuration flags shared by the nrf24 commands.
+package radioflags
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/sim"
+)
+
+// Flags holds the radio flags, one per Config field.
+type Flags struct {
+	Channel      uint
+	RxAddr       string
+	Dynamic      bool
+	PayloadSize  uint
+	DataRate     string
+	PALevel      string
+	RetryDelay   uint
+	RetryCount   uint
+	AddressWidth uint
+	CRC          string
+	CEPin        int
+	IRQPin       int
+	GPIOChip     string
+	CELine       string
+	IRQLine      string
+	CSPin        int
+	CSLine       string
+	SpiBus       string
+	SpiClock     int
+	NativeSPI    bool
+
+	Verbose bool
+
+	Sim         bool
+	SimPeer     string
+	SimInterval time.Duration
+}
+
+// Add registers the radio flags on fs.
+func Add(fs *flag.FlagSet) *Flags {
+	f := &Flags{}
+	fs.UintVar(&f.Channel, "channel", 76, "RF channel (0-124)")
+	fs.StringVar(&f.RxAddr, "rx-addr", "E7:E7:E7:E7:E7", "receive address of this radio")
+	fs.BoolVar(&f.Dynamic, "dynamic", true, "enable dynamic payload length")
+	fs.UintVar(&f.PayloadSize, "payload-size", 32, "payload size when dynamic payloads are disabled (1-32)")
+	fs.StringVar(&f.DataRate, "data-rate", "1mbps", "air data rate: 250kbps, 1mbps or 2mbps")
+	fs.StringVar(&f.PALevel, "pa-level", "max", "power amplifier level: low, high or max")
+	fs.UintVar(&f.RetryDelay, "retry-delay", 250, "auto-retransmit delay in microseconds (250-4000)")
+	fs.UintVar(&f.RetryCount, "retry-count", 3, "auto-retransmit count (1-15)")
+	fs.UintVar(&f.AddressWidth, "address-width", 5, "address width in bytes (3-5)")
+	fs.StringVar(&f.CRC, "crc", "16", "CRC length: 8 or 16")
+	fs.IntVar(&f.CEPin, "ce-pin", 25, "CE GPIO pin (BCM numbering)")
+	fs.IntVar(&f.IRQPin, "irq-pin", 0, "IRQ GPIO pin (BCM numbering), 0 to poll")
+	fs.StringVar(&f.GPIOChip, "gpio-chip", "", "GPIO character device for CE and IRQ instead of periph.io (e.g. gpiochip0)")
+	fs.StringVar(&f.CELine, "ce-line", "", "CE line name or offset on --gpio-chip (default --ce-pin)")
+	fs.StringVar(&f.IRQLine, "irq-line", "", "IRQ line name or offset on --gpio-chip (default --irq-pin)")
+	fs.IntVar(&f.CSPin, "cs-pin", 0, "GPIO chip select pin (BCM numbering), 0 for the hardware chip select of --spi-bus")
+	fs.StringVar(&f.CSLine, "cs-line", "", "chip select line name or offset on --gpio-chip (default --cs-pin)")
+	fs.StringVar(&f.SpiBus, "spi-bus", "/dev/spidev0.0", "SPI bus device")
+	fs.IntVar(&f.SpiClock, "spi-clock", 1000000, "SPI clock in Hz")
+	fs.BoolVar(&f.NativeSPI, "native-spi", false, "use spidev ioctls directly instead of periph.io for the SPI bus")
+	fs.BoolVar(&f.Verbose, "v", false, "print driver log messages")
+	fs.BoolVar(&f.Sim, "sim", false, "use an emulated radio instead of hardware")
+	fs.StringVar(&f.SimPeer, "sim-peer", "C2:C2:C2:C2:C2", "address of the simulated peer (with --sim)")
+	fs.DurationVar(&f.SimInterval, "sim-interval", 500*time.Millisecond, "how often the simulated peer transmits (with --sim)")
+	return f
+}
+
+// RadioConfig builds the radio configuration described by the flags.
+func (f *Flags) RadioConfig() (nrf24.RadioConfig, error) {
+	var c nrf24.RadioConfig
+
+	if f.Channel > 124 {
+		return c, fmt.Errorf("invalid --channel %d", f.Channel)
+	}
+	if !f.Dynamic && (f.PayloadSize == 0 || f.PayloadSize > 32) {
+		return c, fmt.Errorf("invalid --payload-size %d: expected 1 to 32", f.PayloadSize)
+	}
+	// The delay is programmed in steps of 250µs: other values would be rounded, or wrap
+	if f.RetryDelay < 250 || f.RetryDelay > 4000 || f.RetryDelay%250 != 0 {
+		return c, fmt.Errorf("invalid --retry-delay %d: expected a multiple of 250 from 250 to 4000", f.RetryDelay)
+	}
+	// The driver replaces a zero count with its default, and cannot be configured without
+	// retransmissions
+	if f.RetryCount == 0 || f.RetryCount > 15 {
+		return c, fmt.Errorf("invalid --retry-count %d: expected 1 to 15", f.RetryCount)
+	}
+	addr, err := nrf24.ParseAddress(f.RxAddr)
+	if err != nil {
+		return c, fmt.Errorf("invalid --rx-addr: %w", err)
+	}
+	rate, err := parseDataRate(f.DataRate)
+	if err != nil {
+		return c, err
+	}
+	pa, err := parsePALevel(f.PALevel)
+	if err != nil {
+		return c, err
+	}
+	crc, err := parseCRC(f.CRC)
+	if err != nil {
+		return c, err
+	}
+
+	c = nrf24.RadioConfig{
+		ChannelNumber:        byte(f.Channel),
+		RxAddr:               addr,
+		EnableDynamicPayload: f.Dynamic,
+		PayloadSize:          byte(f.PayloadSize),
+		EnableAutoAck:        true,
+		DataRate:             rate,
+		PALevel:              pa,
+		AutoRetransmitDelay:  uint16(f.RetryDelay),
+		AutoRetransmitCount:  byte(f.RetryCount),
+		AddressWidth:         byte(f.AddressWidth),
+		CRCLength:            crc,
+	}
+	return c, nil
+}
+
+// Open creates the radio described by the flags.
+// The returned function releases it (and stops the simulated peer, if any).
+func (f *Flags) Open(ctx context.Context) (*nrf24.Device, func(), error) {
+	rc, err := f.RadioConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !f.Verbose {
+		nrf24.SetLogger(nil)
+	}
+
+	if !f.Sim {
+		dev, err := nrf24.New(nrf24.Config{
+			RadioConfig: rc,
+			CEPin:       f.CEPin,
+			IRQPin:      f.IRQPin,
+			GPIOChip:    f.GPIOChip,
+			CELine:      f.CELine,
+			IRQLine:     f.IRQLine,
+			CSPin:       f.CSPin,
+			CSLine:      f.CSLine,
+			SpiBusPath:  f.SpiBus,
+			SpiClockHz:  f.SpiClock,
+			NativeSPI:   f.NativeSPI,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return dev, func() { dev.Close() }, nil
+	}
+
+	return f.openSim(ctx, rc)
+}
+
+// openSim creates an emulated radio, plus a peer on the same air that acknowledges packets
+// sent to --sim-peer and periodically transmits to this radio.
+func (f *Flags) openSim(ctx context.Context, rc nrf24.RadioConfig) (*nrf24.Device, func(), error) {
+	peerAddr, err := nrf24.ParseAddress(f.SimPeer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid --sim-peer: %w", err)
+	}
+
+	air := sim.NewAir()
+	// Wi-Fi channels 1 and 6 overlap nRF24 channels 2-22 and 27-47
+	for ch := byte(2); ch <= 22; ch++ {
+		air.SetNoise(ch, true)
+	}
+	for ch := byte(27); ch <= 47; ch++ {
+		air.SetNoise(ch, true)
+	}
+
+	dev, _, err := air.NewDevice(rc)
+	if err != nil {
+		return nil, nil, err
+	}
+	peerConfig := rc
+	peerConfig.RxAddr = peerAddr
+	peer, _, err := air.NewDevice(peerConfig)
+	if err != nil {
+		dev.Close()
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(f.SimInterval)
+		defer ticker.Stop()
+		for seq := 1; ; seq++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			// Drain whatever was sent to the peer, then say hello
+			for {
+				if _, ok := peer.Receive(); !ok {
+					break
+				}
+			}
+			peer.Transmit(rc.RxAddr, []byte("sim packet "+strconv.Itoa(seq)))
+		}
+	}()
+
+	cleanup := func() {
+		cancel()
+		<-done
+		peer.Close()
+		dev.Close()
+	}
+	return dev, cleanup, nil
+}
+
+func parseDataRate(s string) (nrf24.DataRate, error) {
+	for _, r := range []nrf24.DataRate{nrf24.DataRate250kbps, nrf24.DataRate1mbps, nrf24.DataRate2mbps} {
+		if strings.EqualFold(s, r.String()) {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid data rate %q: expected 250kbps, 1mbps or 2mbps", s)
+}
+
+func parsePALevel(s string) (nrf24.PALevel, error) {
+	switch strings.ToLower(s) {
+	case "min":
+		// PALevelMin is the zero value, which the driver replaces with its default
+		return 0, fmt.Errorf("PA level min is not supported: expected low, high or max")
+	case "low":
+		return nrf24.PALevelLow, nil
+	case "high":
+		return nrf24.PALevelHigh, nil
+	case "max":
+		return nrf24.PALevelMax, nil
+	}
+	return 0, fmt.Errorf("invalid PA level %q: expected low, high or max", s)
+}
+
+func parseCRC(s string) (nrf24.CRCLength, error) {
+	switch s {
+	case "8":
+		return nrf24.CRCLength8, nil
+	case "16":
+		return nrf24.CRCLength16, nil
+	}
+	return 0, fmt.Errorf("invalid CRC length %q: expected 8 or 16", s)
+}
@@ -0,0 +1,177 @@
+package nrf24
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNoRadio is returned by NewLink without a transmitting or a receiving radio.
+var ErrNoRadio = errors.New("link needs at least one transmitting and one receiving radio")
+
+// Link aggregates several radios into one full-duplex link: dedicated transmitting radios
+// send the packets, so that the receiving radios never leave RX mode and never drop a
+// packet arriving during a transmission.
+//
+// With several transmitting radios, packets are striped across them in turn (typically one
+// per channel, to a peer with a receiving radio on each channel), and concurrent Transmit
+// calls use the radios in parallel. A failed transmission is retried on the next radio.
+// Striped packets may arrive out of order.
+//
+// The transmitting radios stay in standby between transmissions, instead of returning to RX
+// mode like a Device does, so that they never acknowledge packets meant for the link.
+type Link struct {
+	tx   []*Device
+	rx   []*Device
+	next atomic.Uint32 // next transmitting radio
+	scan atomic.Uint32 // next receiving radio polled by Receive
+
+	mu      sync.Mutex
+	pending []linkPacket // packets read by ReceiveBlockingWithPipe after the first one
+}
+
+// linkPacket is a packet received by a radio of a link.
+type linkPacket struct {
+	data []byte
+	pipe int
+}
+
+var (
+	_ Transmitter = (*Link)(nil)
+	_ Receiver    = (*Link)(nil)
+)
+
+// NewLink creates a link transmitting with the tx radios and receiving with the rx radios.
+// A radio must not be in both lists. The tx radios leave RX mode for good.
+func NewLink(tx, rx []*Device) (*Link, error) {
+	if len(tx) == 0 || len(rx) == 0 {
+		return nil, fmt.Errorf("%w: %w", ErrPkg, ErrNoRadio)
+	}
+	for _, d := range tx {
+		if slices.Contains(rx, d) {
+			return nil, fmt.Errorf("%w: radio %s both transmits and receives", ErrPkg, d.config.Name)
+		}
+	}
+	for _, d := range tx {
+		d.mu.Lock()
+		d.txOnly = true
+		if d.beacon == nil && !d.sniffing {
+			d.stopListening()
+		}
+		d.mu.Unlock()
+	}
+	return &Link{tx: slices.Clone(tx), rx: slices.Clone(rx)}, nil
+}
+
+// Transmit sends a message with the next transmitting radio, and with the following ones
+// if it fails. It returns the error of the last radio if every radio failed.
+// This method is concurrent safe.
+func (l *Link) Transmit(destAddr Address, p []byte) error {
+	return l.transmit(func(d *Device) error { return d.Transmit(destAddr, p) })
+}
+
+// TransmitNoAck sends a message without requesting an acknowledgement, with the next
+// transmitting radio.
+// This method is concurrent safe.
+func (l *Link) TransmitNoAck(destAddr Address, p []byte) error {
+	return l.transmit(func(d *Device) error { return d.TransmitNoAck(destAddr, p) })
+}
+
+func (l *Link) transmit(send func(d *Device) error) error {
+	start := int(l.next.Add(1) - 1)
+	var err error
+	for i := range l.tx {
+		if err = send(l.tx[(start+i)%len(l.tx)]); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Receive returns the next packet received by any receiving radio.
+// It returns false if no packet is available.
+// This method is concurrent safe.
+func (l *Link) Receive() ([]byte, bool) {
+	data, _, ok := l.ReceiveWithPipe()
+	return data, ok
+}
+
+// ReceiveWithPipe is like Receive but also returns the data pipe of the receiving radio the
+// packet arrived on.
+// This method is concurrent safe.
+func (l *Link) ReceiveWithPipe() ([]byte, int, bool) {
+	l.mu.Lock()
+	if len(l.pending) > 0 {
+		p := l.pending[0]
+		l.pending = l.pending[1:]
+		l.mu.Unlock()
+		return p.data, p.pipe, true
+	}
+	l.mu.Unlock()
+
+	// Start from a different radio each time, so that a busy one does not starve the others
+	start := int(l.scan.Add(1) - 1)
+	for i := range l.rx {
+		if data, pipe, ok := l.rx[(start+i)%len(l.rx)].ReceiveWithPipe(); ok {
+			return data, pipe, true
+		}
+	}
+	return nil, 0, false
+}
+
+// ReceiveBlocking blocks until a receiving radio gets a packet or the context is cancelled.
+// This method is concurrent safe.
+func (l *Link) ReceiveBlocking(ctx context.Context) ([]byte, error) {
+	data, _, err := l.ReceiveBlockingWithPipe(ctx)
+	return data, err
+}
+
+// ReceiveBlockingWithPipe is like ReceiveBlocking but also returns the data pipe of the
+// receiving radio the packet arrived on.
+// This method is concurrent safe.
+func (l *Link) ReceiveBlockingWithPipe(ctx context.Context) ([]byte, int, error) {
+	if data, pipe, ok := l.ReceiveWithPipe(); ok {
+		return data, pipe, nil
+	}
+	if len(l.rx) == 1 {
+		return l.rx[0].ReceiveBlockingWithPipe(ctx)
+	}
+
+	type result struct {
+		linkPacket
+		err error
+	}
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan result, len(l.rx))
+	for _, d := range l.rx {
+		go func() {
+			data, pipe, err := d.ReceiveBlockingWithPipe(waitCtx)
+			results <- result{linkPacket{data, pipe}, err}
+		}()
+	}
+
+	first := <-results
+	cancel()
+	// Keep the packets the other radios read before noticing the cancellation
+	for range len(l.rx) - 1 {
+		if r := <-results; r.err == nil {
+			l.mu.Lock()
+			l.pending = append(l.pending, r.linkPacket)
+			l.mu.Unlock()
+		}
+	}
+	return first.data, first.pipe, first.err
+}
+
+// Close closes every radio of the link.
+func (l *Link) Close() error {
+	var errs []error
+	for _, d := range slices.Concat(l.tx, l.rx) {
+		errs = append(errs, d.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,170 @@
+package nrf24_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/sim"
+)
+
+var (
+	linkAddr = nrf24.Address{0xA1, 0xA1, 0xA1, 0xA1, 0xA1}
+	peerAddr = nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}
+	// txAddr is the RX address of the transmitting radios, which no peer uses
+	txAddr = nrf24.Address{0xC3, 0xC3, 0xC3, 0xC3, 0xC3}
+)
+
+func newDevice(t *testing.T, air *sim.Air, channel byte, addr nrf24.Address) *nrf24.Device {
+	t.Helper()
+	dev, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: channel, RxAddr: addr, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	return dev
+}
+
+func TestLinkFullDuplex(t *testing.T) {
+	air := sim.NewAir()
+	link, err := nrf24.NewLink(
+		[]*nrf24.Device{newDevice(t, air, 76, txAddr)},
+		[]*nrf24.Device{newDevice(t, air, 76, linkAddr)},
+	)
+	if err != nil {
+		t.Fatalf("NewLink failed: %v", err)
+	}
+	peer := newDevice(t, air, 76, peerAddr)
+
+	// A packet for the link waits in the RX radio while the link transmits
+	if err := peer.Transmit(linkAddr, []byte("sensor")); err != nil {
+		t.Fatalf("Peer Transmit failed: %v", err)
+	}
+	if err := link.Transmit(peerAddr, []byte("command")); err != nil {
+		t.Fatalf("Link Transmit failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := link.ReceiveBlocking(ctx)
+	if err != nil || string(data) != "sensor" {
+		t.Errorf("Link ReceiveBlocking() = %q, %v, want \"sensor\"", data, err)
+	}
+	data, err = peer.ReceiveBlocking(ctx)
+	if err != nil || string(data) != "command" {
+		t.Errorf("Peer ReceiveBlocking() = %q, %v, want \"command\"", data, err)
+	}
+
+	// The TX radio stays in standby and acknowledges nothing
+	if err := peer.Transmit(txAddr, []byte("lost")); !errors.Is(err, nrf24.ErrMaxRetries) {
+		t.Errorf("Expected ErrMaxRetries transmitting to the TX radio, got %v", err)
+	}
+
+	// The link is a Receiver, reporting the pipe of the RX radio
+	var r nrf24.Receiver = link
+	if err := peer.Transmit(linkAddr, []byte("pipe")); err != nil {
+		t.Fatalf("Peer Transmit failed: %v", err)
+	}
+	data, pipe, err := r.ReceiveBlockingWithPipe(ctx)
+	if err != nil || string(data) != "pipe" || pipe != 1 {
+		t.Errorf("Link ReceiveBlockingWithPipe() = %q, %d, %v, want \"pipe\" on pipe 1", data, pipe, err)
+	}
+}
+
+func TestLinkStriping(t *testing.T) {
+	air := sim.NewAir()
+	a, err := nrf24.NewLink(
+		[]*nrf24.Device{newDevice(t, air, 10, txAddr), newDevice(t, air, 20, txAddr)},
+		[]*nrf24.Device{newDevice(t, air, 30, linkAddr)},
+	)
+	if err != nil {
+		t.Fatalf("NewLink(a) failed: %v", err)
+	}
+	rx10, rx20 := newDevice(t, air, 10, peerAddr), newDevice(t, air, 20, peerAddr)
+	b, err := nrf24.NewLink([]*nrf24.Device{newDevice(t, air, 30, txAddr)}, []*nrf24.Device{rx10, rx20})
+	if err != nil {
+		t.Fatalf("NewLink(b) failed: %v", err)
+	}
+
+	// Each channel carries half of the packets
+	for i := 0; i < 4; i++ {
+		if err := a.Transmit(peerAddr, []byte("split")); err != nil {
+			t.Fatalf("Transmit %d failed: %v", i, err)
+		}
+	}
+	for _, d := range []*nrf24.Device{rx10, rx20} {
+		n := 0
+		for _, ok := d.Receive(); ok; _, ok = d.Receive() {
+			n++
+		}
+		if n != 2 {
+			t.Errorf("Expected 2 packets on channel %d, got %d", d.RadioConfig().ChannelNumber, n)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := a.Transmit(peerAddr, fmt.Appendf(nil, "packet %d", i)); err != nil {
+			t.Fatalf("Transmit %d failed: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		data, err := b.ReceiveBlocking(ctx)
+		if err != nil {
+			t.Fatalf("ReceiveBlocking %d failed: %v", i, err)
+		}
+		got[string(data)] = true
+	}
+	for i := 0; i < 4; i++ {
+		if !got[fmt.Sprintf("packet %d", i)] {
+			t.Errorf("Missing packet %d, got %v", i, got)
+		}
+	}
+	if _, ok := b.Receive(); ok {
+		t.Error("Expected no more packets")
+	}
+}
+
+func TestLinkFailover(t *testing.T) {
+	air := sim.NewAir()
+	// Nobody listens on channel 10: the radio on channel 20 delivers every packet
+	link, err := nrf24.NewLink(
+		[]*nrf24.Device{newDevice(t, air, 10, txAddr), newDevice(t, air, 20, txAddr)},
+		[]*nrf24.Device{newDevice(t, air, 30, linkAddr)},
+	)
+	if err != nil {
+		t.Fatalf("NewLink failed: %v", err)
+	}
+	peer := newDevice(t, air, 20, peerAddr)
+	for i := 0; i < 2; i++ {
+		if err := link.Transmit(peerAddr, []byte("hi")); err != nil {
+			t.Fatalf("Transmit %d failed: %v", i, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := peer.Receive(); !ok {
+			t.Errorf("Expected packet %d", i)
+		}
+	}
+
+	// Without any receiver, the error of the last radio is returned
+	if err := link.Transmit(nrf24.Address{1, 2, 3, 4, 5}, []byte("hi")); !errors.Is(err, nrf24.ErrMaxRetries) {
+		t.Errorf("Expected ErrMaxRetries, got %v", err)
+	}
+}
+
+func TestNewLinkErrors(t *testing.T) {
+	air := sim.NewAir()
+	d := newDevice(t, air, 76, linkAddr)
+	if _, err := nrf24.NewLink(nil, []*nrf24.Device{d}); !errors.Is(err, nrf24.ErrNoRadio) {
+		t.Errorf("Expected ErrNoRadio, got %v", err)
+	}
+	if _, err := nrf24.NewLink([]*nrf24.Device{d}, []*nrf24.Device{d}); err == nil {
+		t.Error("Expected an error for a radio both transmitting and receiving")
+	}
+}
@@ -0,0 +1,62 @@
+//go:build !tinygo
+
+package nrf24
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SlogLogger adapts a slog.Handler to Logger and StructuredLogger.
+type SlogLogger struct {
+	h slog.Handler
+}
+
+// NewSlogLogger creates a logger writing to a slog.Handler, e.g.
+//
+//	nrf24.NewSlogLogger(slog.NewJSONHandler(os.Stderr, nil))
+func NewSlogLogger(h slog.Handler) *SlogLogger {
+	return &SlogLogger{h: h}
+}
+
+// Log writes a message with its fields as slog attributes.
+func (l *SlogLogger) Log(level LogLevel, msg string, fields ...Field) {
+	ctx := context.Background()
+	sl := slogLevel(level)
+	if !l.h.Enabled(ctx, sl) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), sl, msg, 0)
+	for _, f := range fields {
+		switch v := f.Value.(type) {
+		case error:
+			r.AddAttrs(slog.Any(f.Key, v))
+		case fmt.Stringer:
+			// Addresses would otherwise be written as arrays by the JSON handler
+			r.AddAttrs(slog.String(f.Key, v.String()))
+		default:
+			r.AddAttrs(slog.Any(f.Key, v))
+		}
+	}
+	l.h.Handle(ctx, r)
+}
+
+func (l *SlogLogger) Debug(msg string) { l.Log(LogDebug, msg) }
+func (l *SlogLogger) Info(msg string)  { l.Log(LogInfo, msg) }
+func (l *SlogLogger) Warn(msg string)  { l.Log(LogWarn, msg) }
+func (l *SlogLogger) Error(msg string) { l.Log(LogError, msg) }
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogDebug:
+		return slog.LevelDebug
+	case LogInfo:
+		return slog.LevelInfo
+	case LogWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
@@ -1,5 +1,7 @@
 package nrf24
 
+import "strconv"
+
 // Logger defines the logging interface for simple string messages.
 // Using simple strings instead of formatted strings helps reduce binary size
 // and memory allocations on microcontrollers (TinyGo).
@@ -10,9 +12,34 @@ type Logger interface {
 	Error(msg string)
 }
 
+// LogLevel is the severity of a log message.
+type LogLevel int8
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// Field is a key/value pair attached to a log message, such as the device name,
+// the channel, an address, a pipe or an error.
+// Value is a string, an integer, an error or a value with a String method (Address).
+type Field struct {
+	Key   string
+	Value any
+}
+
+// StructuredLogger is a Logger that keeps the fields of a message separate.
+// Loggers implementing only Logger get the fields appended to the message as "key=value".
+type StructuredLogger interface {
+	Logger
+	Log(level LogLevel, msg string, fields ...Field)
+}
+
 var globalLogger Logger = &nopLogger{}
 
-// SetLogger sets the global logger instance.
+// SetLogger sets the global logger instance, used by the devices without their own logger.
 func SetLogger(l Logger) {
 	if l == nil {
 		globalLogger = &nopLogger{}
@@ -21,6 +48,56 @@ func SetLogger(l Logger) {
 	globalLogger = l
 }
 
+// logTo writes a message with fields to a logger.
+func logTo(l Logger, level LogLevel, msg string, fields []Field) {
+	if sl, ok := l.(StructuredLogger); ok {
+		sl.Log(level, msg, fields...)
+		return
+	}
+	if len(fields) > 0 {
+		msg = appendFields(msg, fields)
+	}
+	switch level {
+	case LogDebug:
+		l.Debug(msg)
+	case LogInfo:
+		l.Info(msg)
+	case LogWarn:
+		l.Warn(msg)
+	default:
+		l.Error(msg)
+	}
+}
+
+// appendFields formats fields as "msg key=value key=value" without the fmt package.
+func appendFields(msg string, fields []Field) string {
+	b := []byte(msg)
+	for _, f := range fields {
+		b = append(b, ' ')
+		b = append(b, f.Key...)
+		b = append(b, '=')
+		b = append(b, fieldString(f.Value)...)
+	}
+	return string(b)
+}
+
+func fieldString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case byte:
+		return strconv.Itoa(int(v))
+	case error:
+		return v.Error()
+	case interface{ String() string }:
+		return v.String()
+	default:
+		return "?"
+	}
+}
+
 // nopLogger is a logger that does nothing.
 type nopLogger struct{}
 
@@ -0,0 +1,65 @@
+package nrf24
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// recordLogger records the messages of the plain Logger interface.
+type recordLogger struct {
+	msgs []string
+}
+
+func (l *recordLogger) Debug(msg string) { l.msgs = append(l.msgs, "DEBUG "+msg) }
+func (l *recordLogger) Info(msg string)  { l.msgs = append(l.msgs, "INFO "+msg) }
+func (l *recordLogger) Warn(msg string)  { l.msgs = append(l.msgs, "WARN "+msg) }
+func (l *recordLogger) Error(msg string) { l.msgs = append(l.msgs, "ERROR "+msg) }
+
+func TestDeviceLogger(t *testing.T) {
+	log := &recordLogger{}
+	mockSPI := &mockSPIConn{}
+	dev, err := NewWithHardware(HardwareConfig{CE: &mockPin{}, Name: "radio1", Logger: log}, mockSPI)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+	if len(log.msgs) == 0 || !strings.HasPrefix(log.msgs[0], "INFO Initializing") {
+		t.Fatalf("Expected the initialization to be logged, got %q", log.msgs)
+	}
+	if want := "device=radio1 channel=0 address=00:00:00:00:00"; !strings.HasSuffix(log.msgs[len(log.msgs)-1], want) {
+		t.Errorf("Expected %q at the end of %q", want, log.msgs[len(log.msgs)-1])
+	}
+
+	// Ping without ACK: MAX_RT
+	log.msgs = nil
+	mockSPI.rxQueue = nil
+	for i := 0; i < 7; i++ {
+		mockSPI.queueRx([]byte{0})
+	}
+	mockSPI.queueRx([]byte{0, 0x10})
+	dev.Ping(t.Context(), Address{1, 2, 3, 4, 5})
+	want := "INFO Ping Failed device=radio1 address=01:02:03:04:05 error=nrf24dev: max retransmissions reached"
+	if len(log.msgs) != 1 || log.msgs[0] != want {
+		t.Errorf("Expected %q, got %q", want, log.msgs)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	logTo(l, LogDebug, "hidden", nil)
+	logTo(l, LogWarn, "Failed", []Field{{"device", "gw"}, {"pipe", 2}, {"address", Address{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}}, {"error", errors.New("boom")}})
+
+	got := buf.String()
+	if strings.Contains(got, "hidden") {
+		t.Errorf("Expected the debug message to be filtered, got %q", got)
+	}
+	for _, want := range []string{"level=WARN", "msg=Failed", "device=gw", "pipe=2", "address=E7:E7:E7:E7:E7", "error=boom"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+//go:build !tinygo
+
+// Package metrics exports the counters and settings of nrf24.Device radios in the
+// Prometheus text exposition format.
+//
+// Register the radios on a Collector and serve it as an http.Handler:
+//
+//	c := metrics.NewCollector()
+//	c.Register("gateway", dev)
+//	http.Handle("/metrics", c)
+//
+// Every metric carries a "radio" label with the name given to Register.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/michcald/nrf24"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector gathers the metrics of a set of radios on every scrape.
+type Collector struct {
+	mu     sync.Mutex
+	radios []radio
+}
+
+type radio struct {
+	name string
+	dev  *nrf24.Device
+}
+
+// NewCollector creates a collector without radios.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Register adds a radio to the collector. Registering a name again replaces its radio.
+// This method is concurrent safe.
+func (c *Collector) Register(name string, dev *nrf24.Device) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.radios = slices.DeleteFunc(c.radios, func(r radio) bool { return r.name == name })
+	c.radios = append(c.radios, radio{name: name, dev: dev})
+}
+
+// Unregister removes a radio from the collector.
+// This method is concurrent safe.
+func (c *Collector) Unregister(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.radios = slices.DeleteFunc(c.radios, func(r radio) bool { return r.name == name })
+}
+
+// Handler returns a handler serving the metrics of a single radio named "default".
+func Handler(dev *nrf24.Device) http.Handler {
+	c := NewCollector()
+	c.Register("default", dev)
+	return c
+}
+
+// ServeHTTP writes the metrics of every radio.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	c.WriteTo(w)
+}
+
+// snapshot is the state of a radio at scrape time.
+type snapshot struct {
+	name   string
+	stats  nrf24.Stats
+	config nrf24.RadioConfig
+}
+
+// WriteTo writes the metrics of every radio in the Prometheus text exposition format.
+// This method is concurrent safe.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	snaps := make([]snapshot, len(c.radios))
+	for i, r := range c.radios {
+		snaps[i] = snapshot{name: r.name, stats: r.dev.Stats(), config: r.dev.RadioConfig()}
+	}
+	c.mu.Unlock()
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	e := &encoder{w: cw}
+
+	e.family("nrf24_tx_success_total", "counter", "Packets delivered (acknowledged, or sent without ACK).")
+	for _, s := range snaps {
+		e.sample("nrf24_tx_success_total", s.name, nil, float64(s.stats.TxSuccess))
+	}
+	e.family("nrf24_tx_max_retries_total", "counter", "Packets dropped after the maximum number of retransmissions (MAX_RT).")
+	for _, s := range snaps {
+		e.sample("nrf24_tx_max_retries_total", s.name, nil, float64(s.stats.TxMaxRetries))
+	}
+	e.family("nrf24_tx_timeouts_total", "counter", "Transmissions the radio never completed.")
+	for _, s := range snaps {
+		e.sample("nrf24_tx_timeouts_total", s.name, nil, float64(s.stats.TxTimeouts))
+	}
+
+	e.family("nrf24_tx_retransmissions", "histogram", "Hardware retransmissions per transmission (acknowledged or dropped after MAX_RT).")
+	for _, s := range snaps {
+		var cumulative uint64
+		for n, count := range s.stats.RetransmitCounts {
+			cumulative += count
+			e.sample("nrf24_tx_retransmissions_bucket", s.name, []string{"le", strconv.Itoa(n)}, float64(cumulative))
+		}
+		e.sample("nrf24_tx_retransmissions_bucket", s.name, []string{"le", "+Inf"}, float64(cumulative))
+		e.sample("nrf24_tx_retransmissions_sum", s.name, nil, float64(s.stats.Retransmits))
+		e.sample("nrf24_tx_retransmissions_count", s.name, nil, float64(cumulative))
+	}
+
+	e.family("nrf24_rx_packets_total", "counter", "Packets received on each data pipe.")
+	for _, s := range snaps {
+		for pipe, count := range s.stats.RxPackets {
+			e.sample("nrf24_rx_packets_total", s.name, []string{"pipe", strconv.Itoa(pipe)}, float64(count))
+		}
+	}
+	e.family("nrf24_rx_fifo_overflows_total", "counter", "Packets read while the RX FIFO was full (received packets were dropped).")
+	for _, s := range snaps {
+		e.sample("nrf24_rx_fifo_overflows_total", s.name, nil, float64(s.stats.RxFIFOFull))
+	}
+
+	e.family("nrf24_carrier_checks_total", "counter", "Carrier detections performed.")
+	for _, s := range snaps {
+		e.sample("nrf24_carrier_checks_total", s.name, nil, float64(s.stats.CarrierChecks))
+	}
+	e.family("nrf24_carrier_detected_total", "counter", "Carrier detections that found a carrier (> -64dBm).")
+	for _, s := range snaps {
+		e.sample("nrf24_carrier_detected_total", s.name, nil, float64(s.stats.CarrierDetected))
+	}
+
+	e.family("nrf24_channel", "gauge", "Current radio channel (2400 + channel MHz).")
+	for _, s := range snaps {
+		e.sample("nrf24_channel", s.name, nil, float64(s.config.ChannelNumber))
+	}
+	e.family("nrf24_data_rate_bps", "gauge", "Current air data rate in bits per second.")
+	for _, s := range snaps {
+		e.sample("nrf24_data_rate_bps", s.name, nil, dataRateBPS(s.config.DataRate))
+	}
+	e.family("nrf24_pa_level_dbm", "gauge", "Current power amplifier level in dBm.")
+	for _, s := range snaps {
+		e.sample("nrf24_pa_level_dbm", s.name, nil, paLevelDBm(s.config.PALevel))
+	}
+
+	if err := cw.w.Flush(); err != nil && e.err == nil {
+		e.err = err
+	}
+	return cw.n, e.err
+}
+
+func dataRateBPS(rate nrf24.DataRate) float64 {
+	switch rate {
+	case nrf24.DataRate1mbps:
+		return 1e6
+	case nrf24.DataRate2mbps:
+		return 2e6
+	default:
+		return 250e3
+	}
+}
+
+func paLevelDBm(level nrf24.PALevel) float64 {
+	switch level {
+	case nrf24.PALevelMin:
+		return -18
+	case nrf24.PALevelLow:
+		return -12
+	case nrf24.PALevelHigh:
+		return -6
+	default:
+		return 0
+	}
+}
+
+// encoder writes metric families, keeping the first write error.
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) family(name, typ, help string) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+}
+
+// sample writes a sample with the radio label and the extra label name/value pairs.
+func (e *encoder) sample(name, radio string, labels []string, value float64) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, "%s{radio=%s", name, quote(radio))
+	for i := 0; i+1 < len(labels) && e.err == nil; i += 2 {
+		_, e.err = fmt.Fprintf(e.w, ",%s=%s", labels[i], quote(labels[i+1]))
+	}
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, "} %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+	}
+}
+
+// labelEscaper escapes label values as the exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+// countWriter counts the bytes written for WriteTo.
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
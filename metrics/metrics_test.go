@@ -0,0 +1,82 @@
+//go:build !tinygo
+
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/sim"
+)
+
+func TestCollector(t *testing.T) {
+	air := sim.NewAir()
+	tx, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, DataRate: nrf24.DataRate1mbps, PALevel: nrf24.PALevelLow})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	rxAddr := nrf24.Address{1, 2, 3, 4, 5}
+	rx, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, DataRate: nrf24.DataRate1mbps, RxAddr: rxAddr})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+
+	for range 2 {
+		if err := tx.Transmit(rxAddr, []byte("ping")); err != nil {
+			t.Fatalf("Transmit failed: %v", err)
+		}
+	}
+	if err := tx.Transmit(nrf24.Address{9, 9, 9, 9, 9}, []byte("lost")); err == nil {
+		t.Fatal("Expected Transmit to an absent radio to fail")
+	}
+	rx.Receive()
+	tx.IsCarrierDetected()
+
+	c := NewCollector()
+	c.Register("tx", tx)
+	c.Register("rx", rx)
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Expected content type %q, got %q", ContentType, ct)
+	}
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		"# TYPE nrf24_tx_success_total counter\n",
+		`nrf24_tx_success_total{radio="tx"} 2`,
+		`nrf24_tx_max_retries_total{radio="tx"} 1`,
+		`nrf24_tx_retransmissions_bucket{radio="tx",le="0"} 2`,
+		`nrf24_tx_retransmissions_bucket{radio="tx",le="3"} 3`,
+		`nrf24_tx_retransmissions_bucket{radio="tx",le="+Inf"} 3`,
+		`nrf24_tx_retransmissions_sum{radio="tx"} 3`,
+		`nrf24_tx_retransmissions_count{radio="tx"} 3`,
+		`nrf24_rx_packets_total{radio="rx",pipe="1"} 1`,
+		`nrf24_carrier_checks_total{radio="tx"} 1`,
+		`nrf24_channel{radio="rx"} 76`,
+		`nrf24_data_rate_bps{radio="rx"} 1e+06`,
+		`nrf24_pa_level_dbm{radio="tx"} -12`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected %q in:\n%s", want, body)
+		}
+	}
+
+	c.Unregister("rx")
+	var sb strings.Builder
+	if _, err := c.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if strings.Contains(sb.String(), `radio="rx"`) {
+		t.Error("Expected the unregistered radio to be gone")
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	if got := quote("a\"b\\c\nd"); got != `"a\"b\\c\nd"` {
+		t.Errorf("quote() = %s", got)
+	}
+}
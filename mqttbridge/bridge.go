@@ -0,0 +1,288 @@
+//go:build !tinygo
+
+// Package mqttbridge connects an nRF24L01+ radio to an MQTT broker.
+//
+// Received packets are published on <prefix>/rx/<key>, where the key is the data pipe
+// or the pipe address. Messages published on <prefix>/tx/<address> are transmitted to
+// that address, and messages published on <prefix>/ack/<pipe> are queued as the ACK
+// payload of that pipe. The bridge keeps <prefix>/status retained as "online", and its
+// last will turns it to "offline" if the bridge dies.
+package mqttbridge
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/mqttbridge/mqtt"
+)
+
+// Encoding is the representation of packet payloads in MQTT messages.
+type Encoding uint8
+
+const (
+	// EncodingRaw carries the payload bytes unchanged.
+	EncodingRaw Encoding = iota
+	// EncodingHex carries the payload as a hex string.
+	EncodingHex
+	// EncodingJSON carries a JSON object. Received packets are published as
+	// {"pipe":1,"address":"E7:E7:E7:E7:E7","data":"<hex>","time":"..."}, and outbound
+	// messages are expected as {"data":"<hex>","noack":false}.
+	EncodingJSON
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingHex:
+		return "hex"
+	case EncodingJSON:
+		return "json"
+	default:
+		return "raw"
+	}
+}
+
+// ParseEncoding parses "raw", "hex" or "json".
+func ParseEncoding(s string) (Encoding, error) {
+	switch s {
+	case "raw":
+		return EncodingRaw, nil
+	case "hex":
+		return EncodingHex, nil
+	case "json":
+		return EncodingJSON, nil
+	}
+	return 0, fmt.Errorf("invalid encoding %q: expected raw, hex or json", s)
+}
+
+// TopicKey selects how received packets are mapped to topics.
+type TopicKey uint8
+
+const (
+	// KeyPipe publishes received packets on <prefix>/rx/<pipe>.
+	KeyPipe TopicKey = iota
+	// KeyAddress publishes received packets on <prefix>/rx/<pipe address>.
+	// In a star network where every node transmits to its own pipe, this identifies the sender.
+	KeyAddress
+)
+
+// Config configures a Bridge.
+type Config struct {
+	// Broker is the host:port of the MQTT broker.
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// Prefix is the root of every topic. Defaults to "nrf24".
+	Prefix string
+	// Encoding is the payload representation, in both directions.
+	Encoding Encoding
+	// KeyBy selects the topic of received packets.
+	KeyBy TopicKey
+	// QoS is the quality of service (0 or 1) of publications and subscriptions.
+	QoS byte
+	// KeepAlive is the MQTT keep alive interval. Defaults to 30 seconds.
+	KeepAlive time.Duration
+}
+
+// Bridge forwards packets between a radio and an MQTT broker.
+type Bridge struct {
+	dev    nrf24.Radio
+	config Config
+}
+
+// New creates a bridge for a configured device.
+// The bridge becomes the only reader of the device: nothing else should call its Receive methods.
+func New(dev nrf24.Radio, config Config) (*Bridge, error) {
+	if config.Broker == "" {
+		return nil, errors.New("mqttbridge: Broker is required")
+	}
+	if config.QoS > 1 {
+		return nil, fmt.Errorf("mqttbridge: QoS %d not supported, must be 0 or 1", config.QoS)
+	}
+	if config.Prefix == "" {
+		config.Prefix = "nrf24"
+	}
+	config.Prefix = strings.TrimSuffix(config.Prefix, "/")
+	if config.ClientID == "" {
+		config.ClientID = "nrf24-bridge"
+	}
+	if config.KeepAlive == 0 {
+		config.KeepAlive = 30 * time.Second
+	}
+	return &Bridge{dev: dev, config: config}, nil
+}
+
+func (b *Bridge) topic(parts ...string) string {
+	return b.config.Prefix + "/" + strings.Join(parts, "/")
+}
+
+// Run connects to the broker and forwards packets until ctx is cancelled or the
+// connection is lost. It returns nil when stopped by ctx; callers wanting to
+// reconnect after a failure simply call Run again.
+func (b *Bridge) Run(ctx context.Context) error {
+	status := b.topic("status")
+	client, err := mqtt.Dial(ctx, b.config.Broker, mqtt.Options{
+		ClientID:  b.config.ClientID,
+		Username:  b.config.Username,
+		Password:  b.config.Password,
+		KeepAlive: b.config.KeepAlive,
+		Will:      &mqtt.Message{Topic: status, Payload: []byte("offline"), QoS: b.config.QoS, Retain: true},
+	})
+	if err != nil {
+		return fmt.Errorf("mqttbridge: %w", err)
+	}
+	defer client.Close()
+
+	for _, filter := range []string{b.topic("tx", "+"), b.topic("ack", "+")} {
+		if err := client.Subscribe(filter, b.config.QoS); err != nil {
+			return fmt.Errorf("mqttbridge: %w", err)
+		}
+	}
+	if err := client.Publish(mqtt.Message{Topic: status, Payload: []byte("online"), QoS: b.config.QoS, Retain: true}); err != nil {
+		return fmt.Errorf("mqttbridge: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rxDone := make(chan error, 1)
+	go func() { rxDone <- b.forwardReceived(ctx, client) }()
+
+	for {
+		select {
+		case m, ok := <-client.Messages():
+			if !ok {
+				cancel()
+				<-rxDone
+				return fmt.Errorf("mqttbridge: %w: %w", mqtt.ErrClosed, client.Err())
+			}
+			if err := b.command(m); err != nil {
+				client.Publish(mqtt.Message{Topic: b.topic("error"), Payload: []byte(err.Error())})
+			}
+		case err := <-rxDone:
+			if ctx.Err() != nil {
+				// Stopped by the caller: announce a clean shutdown
+				client.Publish(mqtt.Message{Topic: status, Payload: []byte("offline"), QoS: b.config.QoS, Retain: true})
+				client.Disconnect()
+				return nil
+			}
+			return fmt.Errorf("mqttbridge: %w", err)
+		}
+	}
+}
+
+// forwardReceived publishes the packets received by the device.
+func (b *Bridge) forwardReceived(ctx context.Context, client *mqtt.Client) error {
+	for {
+		data, pipe, err := b.dev.ReceiveBlockingWithPipe(ctx)
+		if err != nil {
+			return err
+		}
+		addr, err := b.dev.PipeAddress(pipe)
+		if err != nil {
+			return err
+		}
+
+		key := strconv.Itoa(pipe)
+		if b.config.KeyBy == KeyAddress {
+			key = addr.String()
+		}
+
+		var payload []byte
+		switch b.config.Encoding {
+		case EncodingRaw:
+			payload = data
+		case EncodingHex:
+			payload = []byte(hex.EncodeToString(data))
+		case EncodingJSON:
+			payload, _ = json.Marshal(rxMessage{
+				Pipe:    pipe,
+				Address: addr.String(),
+				Data:    hex.EncodeToString(data),
+				Time:    time.Now(),
+			})
+		}
+
+		if err := client.Publish(mqtt.Message{Topic: b.topic("rx", key), Payload: payload, QoS: b.config.QoS}); err != nil {
+			return err
+		}
+	}
+}
+
+// rxMessage is the JSON representation of a received packet.
+type rxMessage struct {
+	Pipe    int       `json:"pipe"`
+	Address string    `json:"address"`
+	Data    string    `json:"data"`
+	Time    time.Time `json:"time"`
+}
+
+// txMessage is the JSON representation of an outbound packet.
+type txMessage struct {
+	Data  string `json:"data"`
+	NoAck bool   `json:"noack"`
+}
+
+// command executes a message received on a command topic.
+func (b *Bridge) command(m mqtt.Message) error {
+	kind, target, ok := strings.Cut(strings.TrimPrefix(m.Topic, b.config.Prefix+"/"), "/")
+	if !ok {
+		return fmt.Errorf("unexpected topic %q", m.Topic)
+	}
+
+	data, noAck, err := b.decode(m.Payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", m.Topic, err)
+	}
+
+	switch kind {
+	case "tx":
+		addr, err := nrf24.ParseAddress(target)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Topic, err)
+		}
+		if noAck {
+			err = b.dev.TransmitNoAck(addr, data)
+		} else {
+			err = b.dev.Transmit(addr, data)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Topic, err)
+		}
+	case "ack":
+		pipe, err := strconv.Atoi(target)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pipe %q", m.Topic, target)
+		}
+		if err := b.dev.WriteAckPayload(pipe, data); err != nil {
+			return fmt.Errorf("%s: %w", m.Topic, err)
+		}
+	default:
+		return fmt.Errorf("unexpected topic %q", m.Topic)
+	}
+	return nil
+}
+
+// decode extracts the packet data from an outbound message.
+func (b *Bridge) decode(payload []byte) ([]byte, bool, error) {
+	switch b.config.Encoding {
+	case EncodingHex:
+		data, err := hex.DecodeString(strings.TrimSpace(string(payload)))
+		return data, false, err
+	case EncodingJSON:
+		var m txMessage
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return nil, false, err
+		}
+		data, err := hex.DecodeString(m.Data)
+		return data, m.NoAck, err
+	default:
+		return payload, false, nil
+	}
+}
@@ -0,0 +1,174 @@
+//go:build !tinygo
+
+package mqttbridge
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/mqttbridge/mqtt"
+	"github.com/michcald/nrf24/sim"
+)
+
+var (
+	bridgeAddr = nrf24.Address{0xA1, 0xA1, 0xA1, 0xA1, 0xA1}
+	peerAddr   = nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}
+)
+
+// startBridge runs a bridge on an emulated radio against an in-process broker.
+// It returns a client of the broker subscribed to every topic, a peer radio and the radio of the bridge.
+func startBridge(t *testing.T, config Config) (*mqtt.Client, *nrf24.Device, *sim.Radio) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	broker := mqtt.NewBroker()
+	go broker.Serve(l)
+	t.Cleanup(func() {
+		l.Close()
+		broker.Close()
+	})
+
+	air := sim.NewAir()
+	dev, radio, err := air.NewDevice(nrf24.RadioConfig{
+		ChannelNumber:        76,
+		RxAddr:               bridgeAddr,
+		EnableDynamicPayload: true,
+	})
+	if err != nil {
+		t.Fatalf("NewDevice(bridge) failed: %v", err)
+	}
+	peer, _, err := air.NewDevice(nrf24.RadioConfig{
+		ChannelNumber:        76,
+		RxAddr:               peerAddr,
+		EnableDynamicPayload: true,
+	})
+	if err != nil {
+		t.Fatalf("NewDevice(peer) failed: %v", err)
+	}
+
+	watcher, err := mqtt.Dial(context.Background(), l.Addr().String(), mqtt.Options{ClientID: "watcher"})
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { watcher.Close() })
+	if err := watcher.Subscribe("nrf24/#", 1); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	config.Broker = l.Addr().String()
+	b, err := New(dev, config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run failed: %v", err)
+		}
+	})
+
+	if m := expect(t, watcher, "nrf24/status"); string(m.Payload) != "online" {
+		t.Fatalf("Expected status 'online', got %q", m.Payload)
+	}
+	return watcher, peer, radio
+}
+
+// expect waits for the next message on a topic, skipping others.
+func expect(t *testing.T, c *mqtt.Client, topic string) mqtt.Message {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case m := <-c.Messages():
+			if m.Topic == topic {
+				return m
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for a message on %s", topic)
+		}
+	}
+}
+
+func TestReceivedByPipe(t *testing.T) {
+	watcher, peer, _ := startBridge(t, Config{Encoding: EncodingHex, QoS: 1})
+
+	if err := peer.Transmit(bridgeAddr, []byte{0xCA, 0xFE}); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	if m := expect(t, watcher, "nrf24/rx/1"); string(m.Payload) != "cafe" {
+		t.Errorf("Expected 'cafe', got %q", m.Payload)
+	}
+}
+
+func TestReceivedByAddressJSON(t *testing.T) {
+	watcher, peer, _ := startBridge(t, Config{Encoding: EncodingJSON, KeyBy: KeyAddress})
+
+	if err := peer.Transmit(bridgeAddr, []byte("hi")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	m := expect(t, watcher, "nrf24/rx/"+bridgeAddr.String())
+	var rx rxMessage
+	if err := json.Unmarshal(m.Payload, &rx); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if rx.Pipe != 1 || rx.Address != bridgeAddr.String() || rx.Data != "6869" {
+		t.Errorf("Unexpected message %+v", rx)
+	}
+}
+
+func TestTransmitCommand(t *testing.T) {
+	watcher, peer, _ := startBridge(t, Config{})
+
+	if err := watcher.Publish(mqtt.Message{Topic: "nrf24/tx/" + peerAddr.String(), Payload: []byte("on"), QoS: 1}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := peer.ReceiveBlocking(ctx)
+	if err != nil || string(data) != "on" {
+		t.Fatalf("Expected peer to receive 'on', got %q (%v)", data, err)
+	}
+
+	// Failures are reported on the error topic
+	watcher.Publish(mqtt.Message{Topic: "nrf24/tx/01:02:03:04:05", Payload: []byte("lost")})
+	expect(t, watcher, "nrf24/error")
+}
+
+func TestAckCommand(t *testing.T) {
+	watcher, _, radio := startBridge(t, Config{Encoding: EncodingHex})
+
+	if err := watcher.Publish(mqtt.Message{Topic: "nrf24/ack/1", Payload: []byte("0102"), QoS: 1}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	// The payload waits in the TX FIFO for the next packet on pipe 1
+	const fifoStatus, txEmpty = 0x17, 0x10
+	for i := 0; radio.Register(fifoStatus)&txEmpty != 0; i++ {
+		if i == 100 {
+			t.Fatal("Expected the ACK payload to be queued")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Invalid pipes are reported on the error topic
+	watcher.Publish(mqtt.Message{Topic: "nrf24/ack/9", Payload: []byte("00")})
+	expect(t, watcher, "nrf24/error")
+}
+
+func TestNewValidation(t *testing.T) {
+	if _, err := New(nil, Config{Broker: "localhost:1883", QoS: 2}); err == nil {
+		t.Error("Expected an error for QoS 2")
+	}
+	if _, err := New(nil, Config{}); err == nil {
+		t.Error("Expected an error without a broker")
+	}
+}
@@ -0,0 +1,248 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+)
+
+// Broker is an in-process MQTT broker.
+type Broker struct {
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	retained map[string]Message
+	closed   bool
+}
+
+// session is a client connected to the broker.
+type session struct {
+	conn net.Conn
+	wmu  sync.Mutex
+	// Guarded by Broker.mu.
+	subs   map[string]byte
+	nextID uint16
+}
+
+// NewBroker creates a broker with no clients.
+func NewBroker() *Broker {
+	return &Broker{
+		sessions: make(map[*session]struct{}),
+		retained: make(map[string]Message),
+	}
+}
+
+// Serve accepts clients on l until it is closed.
+func (b *Broker) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go b.ServeConn(conn)
+	}
+}
+
+// Close disconnects every client.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.sessions {
+		s.conn.Close()
+	}
+}
+
+// ServeConn serves a single client until it disconnects.
+func (b *Broker) ServeConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	p, err := readPacket(r)
+	if err != nil || p.kind != typeConnect {
+		return
+	}
+	will, ok := parseConnect(p)
+	if !ok {
+		writePacket(conn, typeConnack, 0, []byte{0, 1}) // unacceptable protocol version
+		return
+	}
+
+	s := &session{conn: conn, subs: make(map[string]byte)}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.sessions[s] = struct{}{}
+	b.mu.Unlock()
+
+	clean := b.serveSession(r, s)
+
+	b.mu.Lock()
+	delete(b.sessions, s)
+	b.mu.Unlock()
+	if !clean && will != nil {
+		b.publish(*will)
+	}
+}
+
+// serveSession processes the packets of a connected client.
+// It returns true if the client disconnected cleanly.
+func (b *Broker) serveSession(r *bufio.Reader, s *session) bool {
+	if s.write(typeConnack, 0, []byte{0, 0}) != nil {
+		return false
+	}
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return false
+		}
+		switch p.kind {
+		case typePublish:
+			m, id, err := decodePublish(p)
+			if err != nil {
+				return false
+			}
+			b.publish(m)
+			if m.QoS > 0 {
+				s.write(typePuback, 0, binary.BigEndian.AppendUint16(nil, id))
+			}
+		case typeSubscribe:
+			if !b.subscribe(s, p) {
+				return false
+			}
+		case typePingreq:
+			s.write(typePingresp, 0, nil)
+		case typePuback:
+		case typeDisconnect:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// parseConnect validates a CONNECT packet and returns its will, if any.
+func parseConnect(p packet) (*Message, bool) {
+	r := reader{b: p.body}
+	name := r.string()
+	level := r.byte()
+	flags := r.byte()
+	r.uint16() // keep alive
+	r.string() // client id
+	var will *Message
+	if flags&flagWill != 0 {
+		will = &Message{
+			Topic:   r.string(),
+			Payload: append([]byte(nil), r.bytes()...),
+			QoS:     min((flags>>3)&0x03, 1),
+			Retain:  flags&flagWillRetain != 0,
+		}
+	}
+	if r.err != nil || name != "MQTT" || level != 4 {
+		return nil, false
+	}
+	return will, true
+}
+
+// subscribe handles a SUBSCRIBE packet and sends the matching retained messages.
+func (b *Broker) subscribe(s *session, p packet) bool {
+	r := reader{b: p.body}
+	id := r.uint16()
+	var filters []string
+	var granted []byte
+	for r.err == nil && len(r.b) > 0 {
+		f := r.string()
+		qos := min(r.byte(), 1)
+		filters = append(filters, f)
+		granted = append(granted, qos)
+	}
+	if r.err != nil || len(filters) == 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	for i, f := range filters {
+		s.subs[f] = granted[i]
+	}
+	var retained []Message
+	for _, m := range b.retained {
+		for i, f := range filters {
+			if Match(f, m.Topic) {
+				m.QoS = min(m.QoS, granted[i])
+				retained = append(retained, m)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	s.write(typeSuback, 0, append(binary.BigEndian.AppendUint16(nil, id), granted...))
+	for _, m := range retained {
+		b.deliver(s, m)
+	}
+	return true
+}
+
+// publish stores a retained message and forwards a message to the matching subscribers.
+func (b *Broker) publish(m Message) {
+	b.mu.Lock()
+	if m.Retain {
+		if len(m.Payload) == 0 {
+			delete(b.retained, m.Topic)
+		} else {
+			b.retained[m.Topic] = m
+		}
+	}
+	type target struct {
+		s   *session
+		qos byte
+	}
+	var targets []target
+	for s := range b.sessions {
+		qos, ok := byte(0), false
+		for f, q := range s.subs {
+			if Match(f, m.Topic) {
+				qos, ok = max(qos, q), true
+			}
+		}
+		if ok {
+			targets = append(targets, target{s, min(qos, m.QoS)})
+		}
+	}
+	b.mu.Unlock()
+
+	// Forwarded messages are not retained copies
+	m.Retain = false
+	for _, t := range targets {
+		m.QoS = t.qos
+		b.deliver(t.s, m)
+	}
+}
+
+// deliver sends a PUBLISH to a session.
+func (b *Broker) deliver(s *session, m Message) {
+	var id uint16
+	if m.QoS > 0 {
+		b.mu.Lock()
+		s.nextID++
+		if s.nextID == 0 {
+			s.nextID = 1
+		}
+		id = s.nextID
+		b.mu.Unlock()
+	}
+	flags, body := encodePublish(m, id)
+	s.write(typePublish, flags, body)
+}
+
+func (s *session) write(kind, flags byte, body []byte) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	return writePacket(s.conn, kind, flags, body)
+}
@@ -0,0 +1,267 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Options configures a client connection.
+type Options struct {
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive is the keep alive interval announced to the broker. Zero disables it.
+	KeepAlive time.Duration
+	// Will is published by the broker if the client disconnects without calling Disconnect.
+	Will *Message
+}
+
+// Client is a connection to an MQTT broker.
+// All methods are concurrent safe.
+type Client struct {
+	conn net.Conn
+
+	wmu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint16
+	pending map[uint16]chan packet
+	err     error
+
+	messages chan Message
+	done     chan struct{}
+}
+
+// Dial connects to the broker at a TCP address.
+func Dial(ctx context.Context, addr string, opts Options) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Connect(conn, opts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Connect performs the MQTT handshake on an established connection.
+func Connect(conn net.Conn, opts Options) (*Client, error) {
+	flags := byte(flagCleanSession)
+	body := appendString(nil, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+	if opts.Will != nil {
+		flags |= flagWill | opts.Will.QoS<<3
+		if opts.Will.Retain {
+			flags |= flagWillRetain
+		}
+	}
+	if opts.Username != "" {
+		flags |= flagUsername
+	}
+	if opts.Password != "" {
+		flags |= flagPassword
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = appendString(body, opts.ClientID)
+	if opts.Will != nil {
+		body = appendString(body, opts.Will.Topic)
+		body = appendBytes(body, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+	}
+	if opts.Password != "" {
+		body = appendString(body, opts.Password)
+	}
+	if err := writePacket(conn, typeConnect, 0, body); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	p, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if p.kind != typeConnack || len(p.body) != 2 {
+		return nil, fmt.Errorf("%w: expected CONNACK", ErrProtocol)
+	}
+	if p.body[1] != 0 {
+		return nil, fmt.Errorf("%w: return code %d", ErrRefused, p.body[1])
+	}
+
+	c := &Client{
+		conn:     conn,
+		pending:  make(map[uint16]chan packet),
+		messages: make(chan Message, 64),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop(r)
+	if opts.KeepAlive > 0 {
+		go c.keepAlive(opts.KeepAlive)
+	}
+	return c, nil
+}
+
+// Messages returns the channel delivering the messages of the subscribed topics.
+// It is closed when the connection ends.
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// Done returns a channel closed when the connection ends.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error that ended the connection, if any.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Disconnect ends the session cleanly: the broker discards the will.
+func (c *Client) Disconnect() error {
+	c.write(typeDisconnect, 0, nil)
+	return c.conn.Close()
+}
+
+// Close drops the connection without a DISCONNECT: the broker publishes the will.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Publish sends a message. With QoS 1 it waits for the broker to acknowledge it.
+func (c *Client) Publish(m Message) error {
+	if m.QoS > 1 {
+		return fmt.Errorf("%w: QoS %d not supported", ErrProtocol, m.QoS)
+	}
+	var id uint16
+	var ack chan packet
+	if m.QoS > 0 {
+		id, ack = c.track()
+	}
+	flags, body := encodePublish(m, id)
+	if err := c.write(typePublish, flags, body); err != nil {
+		return err
+	}
+	if ack == nil {
+		return nil
+	}
+	return c.wait(ack)
+}
+
+// Subscribe subscribes to a topic filter and waits for the broker to acknowledge it.
+func (c *Client) Subscribe(filter string, qos byte) error {
+	id, ack := c.track()
+	body := binary.BigEndian.AppendUint16(nil, id)
+	body = appendString(body, filter)
+	body = append(body, qos)
+	if err := c.write(typeSubscribe, 0x02, body); err != nil {
+		return err
+	}
+	return c.wait(ack)
+}
+
+// track allocates a packet identifier and the channel receiving its acknowledgement.
+func (c *Client) track() (uint16, chan packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	ch := make(chan packet, 1)
+	c.pending[c.nextID] = ch
+	return c.nextID, ch
+}
+
+func (c *Client) wait(ack chan packet) error {
+	select {
+	case p := <-ack:
+		if p.kind == typeSuback && len(p.body) > 2 && p.body[2] == 0x80 {
+			return fmt.Errorf("%w: subscription refused", ErrRefused)
+		}
+		return nil
+	case <-c.done:
+		return ErrClosed
+	}
+}
+
+func (c *Client) write(kind, flags byte, body []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := writePacket(c.conn, kind, flags, body); err != nil {
+		return fmt.Errorf("%w: %w", ErrClosed, err)
+	}
+	return nil
+}
+
+func (c *Client) keepAlive(interval time.Duration) {
+	t := time.NewTicker(interval / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if c.write(typePingreq, 0, nil) != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	err := c.read(r)
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	c.conn.Close()
+	close(c.done)
+	close(c.messages)
+}
+
+func (c *Client) read(r *bufio.Reader) error {
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return err
+		}
+		switch p.kind {
+		case typePublish:
+			m, id, err := decodePublish(p)
+			if err != nil {
+				return err
+			}
+			c.messages <- m
+			if m.QoS > 0 {
+				c.write(typePuback, 0, binary.BigEndian.AppendUint16(nil, id))
+			}
+		case typePuback, typeSuback:
+			if len(p.body) < 2 {
+				return fmt.Errorf("%w: truncated acknowledgement", ErrProtocol)
+			}
+			id := binary.BigEndian.Uint16(p.body)
+			c.mu.Lock()
+			ch := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- p
+			}
+		case typePingresp:
+		default:
+			return fmt.Errorf("%w: unexpected packet type %d", ErrProtocol, p.kind)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package mqtt
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func startBroker(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	b := NewBroker()
+	go b.Serve(l)
+	t.Cleanup(func() {
+		l.Close()
+		b.Close()
+	})
+	return l.Addr().String()
+}
+
+func connect(t *testing.T, addr string, opts Options) *Client {
+	t.Helper()
+	c, err := Dial(context.Background(), addr, opts)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func next(t *testing.T, c *Client) Message {
+	t.Helper()
+	select {
+	case m := <-c.Messages():
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a message")
+		return Message{}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a/b/c", true},
+		{"a/#", "a", true},
+		{"+/b", "a/b", true},
+		{"a/b/c", "a/b", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	addr := startBroker(t)
+	sub := connect(t, addr, Options{ClientID: "sub"})
+	pub := connect(t, addr, Options{ClientID: "pub", KeepAlive: time.Second})
+
+	if err := sub.Subscribe("home/+/temp", 1); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	for _, qos := range []byte{0, 1} {
+		if err := pub.Publish(Message{Topic: "home/kitchen/temp", Payload: []byte("21.5"), QoS: qos}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		m := next(t, sub)
+		if m.Topic != "home/kitchen/temp" || string(m.Payload) != "21.5" || m.QoS != qos {
+			t.Errorf("Unexpected message %+v", m)
+		}
+	}
+}
+
+func TestRetainedAndWill(t *testing.T) {
+	addr := startBroker(t)
+	dev := connect(t, addr, Options{
+		ClientID: "dev",
+		Will:     &Message{Topic: "dev/status", Payload: []byte("offline"), Retain: true},
+	})
+	if err := dev.Publish(Message{Topic: "dev/status", Payload: []byte("online"), QoS: 1, Retain: true}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	watcher := connect(t, addr, Options{ClientID: "watcher"})
+	if err := watcher.Subscribe("dev/status", 0); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if m := next(t, watcher); string(m.Payload) != "online" || !m.Retain {
+		t.Errorf("Expected retained 'online', got %+v", m)
+	}
+
+	// Dropping the connection publishes the will
+	dev.Close()
+	if m := next(t, watcher); string(m.Payload) != "offline" {
+		t.Errorf("Expected will 'offline', got %+v", m)
+	}
+}
+
+func TestDisconnectDiscardsWill(t *testing.T) {
+	addr := startBroker(t)
+	watcher := connect(t, addr, Options{ClientID: "watcher"})
+	if err := watcher.Subscribe("#", 0); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	dev := connect(t, addr, Options{
+		ClientID: "dev",
+		Will:     &Message{Topic: "dev/status", Payload: []byte("offline")},
+	})
+	dev.Disconnect()
+
+	select {
+	case m := <-watcher.Messages():
+		t.Errorf("Expected no will after a clean disconnect, got %+v", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
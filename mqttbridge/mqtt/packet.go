@@ -0,0 +1,215 @@
+// Package mqtt is a minimal MQTT 3.1.1 client and broker.
+//
+// It supports what the nrf24 bridges need and nothing more: QoS 0 and 1,
+// retained messages, last will, keep alive and clean sessions. The broker runs
+// in-process and is meant for tests and small local setups.
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Control packet types.
+const (
+	typeConnect    = 1
+	typeConnack    = 2
+	typePublish    = 3
+	typePuback     = 4
+	typeSubscribe  = 8
+	typeSuback     = 9
+	typePingreq    = 12
+	typePingresp   = 13
+	typeDisconnect = 14
+)
+
+// CONNECT flags.
+const (
+	flagCleanSession = 0x02
+	flagWill         = 0x04
+	flagWillRetain   = 0x20
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+)
+
+// maxRemaining is the largest remaining length the protocol can encode.
+const maxRemaining = 268435455
+
+var (
+	// ErrProtocol is returned when the peer sends a malformed packet.
+	ErrProtocol = errors.New("mqtt protocol error")
+	// ErrRefused is returned when the broker refuses a connection.
+	ErrRefused = errors.New("mqtt connection refused")
+	// ErrClosed is returned by a Client once its connection is closed.
+	ErrClosed = errors.New("mqtt connection closed")
+)
+
+// Message is an application message.
+type Message struct {
+	Topic   string
+	Payload []byte
+	// QoS is the quality of service: 0 (at most once) or 1 (at least once).
+	QoS    byte
+	Retain bool
+}
+
+// packet is a raw control packet.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	var length, shift int
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+		if shift > 21 {
+			return packet{}, fmt.Errorf("%w: remaining length too long", ErrProtocol)
+		}
+	}
+	p := packet{kind: header >> 4, flags: header & 0x0F, body: make([]byte, length)}
+	if _, err := io.ReadFull(r, p.body); err != nil {
+		return packet{}, err
+	}
+	return p, nil
+}
+
+func writePacket(w io.Writer, kind, flags byte, body []byte) error {
+	if len(body) > maxRemaining {
+		return fmt.Errorf("%w: packet too large", ErrProtocol)
+	}
+	buf := make([]byte, 0, len(body)+5)
+	buf = append(buf, kind<<4|flags)
+	n := len(body)
+	for {
+		b := byte(n & 0x7F)
+		n >>= 7
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	buf = append(buf, body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b []byte, v []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(v)))
+	return append(b, v...)
+}
+
+// reader decodes the fields of a packet body.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) uint16() uint16 {
+	if r.err != nil || len(r.b) < 2 {
+		r.err = fmt.Errorf("%w: truncated packet", ErrProtocol)
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil || len(r.b) < 1 {
+		r.err = fmt.Errorf("%w: truncated packet", ErrProtocol)
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := int(r.uint16())
+	if r.err != nil || len(r.b) < n {
+		r.err = fmt.Errorf("%w: truncated packet", ErrProtocol)
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) string() string {
+	return string(r.bytes())
+}
+
+// encodePublish builds the flags and body of a PUBLISH packet.
+func encodePublish(m Message, id uint16) (byte, []byte) {
+	flags := m.QoS << 1
+	if m.Retain {
+		flags |= 0x01
+	}
+	body := appendString(nil, m.Topic)
+	if m.QoS > 0 {
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	return flags, append(body, m.Payload...)
+}
+
+// decodePublish parses a PUBLISH packet.
+func decodePublish(p packet) (Message, uint16, error) {
+	m := Message{QoS: (p.flags >> 1) & 0x03, Retain: p.flags&0x01 != 0}
+	if m.QoS > 1 {
+		return m, 0, fmt.Errorf("%w: QoS %d not supported", ErrProtocol, m.QoS)
+	}
+	r := reader{b: p.body}
+	m.Topic = r.string()
+	var id uint16
+	if m.QoS > 0 {
+		id = r.uint16()
+	}
+	if r.err != nil {
+		return m, 0, r.err
+	}
+	m.Payload = append([]byte(nil), r.b...)
+	return m, id, nil
+}
+
+// Match reports whether a topic matches a subscription filter with + and # wildcards.
+func Match(filter, topic string) bool {
+	fl := strings.Split(filter, "/")
+	tl := strings.Split(topic, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(tl) {
+			return false
+		}
+		if f != "+" && f != tl[i] {
+			return false
+		}
+	}
+	return len(fl) == len(tl)
+}
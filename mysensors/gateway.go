@@ -0,0 +1,403 @@
+//go:build !tinygo
+
+package mysensors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/michcald/nrf24"
+)
+
+// broadcastPipe receives the messages sent to BroadcastID.
+const broadcastPipe = 2
+
+// replyDelay gives a node time to switch back to RX after a transmission before it
+// is sent a reply. Replies to nodes without an ID are broadcasts, which are not retried.
+const replyDelay = 5 * time.Millisecond
+
+// GatewayConfig configures a Gateway.
+type GatewayConfig struct {
+	// AssignIDs makes the gateway answer ID requests itself, with the lowest unused ID.
+	// When false, ID requests are forwarded to the controllers, which answer them.
+	AssignIDs bool
+}
+
+// Node describes a node of the network, as learnt from its messages and presentation.
+type Node struct {
+	ID            byte
+	SketchName    string
+	SketchVersion string
+	// Sensors maps the sensor IDs presented by the node to their presentation type.
+	Sensors map[byte]byte
+}
+
+// Gateway bridges a MySensors radio network to controllers using the serial protocol.
+type Gateway struct {
+	dev    nrf24.Radio
+	config GatewayConfig
+
+	mu          sync.Mutex
+	nodes       map[byte]*Node
+	routes      map[byte]byte
+	controllers map[io.Writer]*sync.Mutex
+
+	// replies counts the replies scheduled by reply and not sent yet
+	replies sync.WaitGroup
+}
+
+// NewGateway creates a gateway on a device configured with RadioConfig(GatewayID).
+// It opens the broadcast pipe, and becomes the only reader of the device.
+func NewGateway(dev nrf24.Radio, config GatewayConfig) (*Gateway, error) {
+	if err := dev.OpenRxPipe(broadcastPipe, []byte{BroadcastID}); err != nil {
+		return nil, err
+	}
+	return &Gateway{
+		dev:         dev,
+		config:      config,
+		nodes:       make(map[byte]*Node),
+		routes:      make(map[byte]byte),
+		controllers: make(map[io.Writer]*sync.Mutex),
+	}, nil
+}
+
+// ListenAndServe serves controllers on a TCP address, like the MySensors Ethernet
+// gateway (which listens on port 5003).
+func (g *Gateway) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return g.Serve(ctx, l)
+}
+
+// Serve relays messages between the radio and the controllers accepted on l until
+// ctx is cancelled. It returns nil when stopped by ctx.
+func (g *Gateway) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.receiveLoop(ctx)
+	}()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	conns := make(map[net.Conn]struct{})
+	var connsMu sync.Mutex
+	var err error
+	for {
+		conn, aerr := l.Accept()
+		if aerr != nil {
+			if ctx.Err() == nil {
+				err = aerr
+			}
+			break
+		}
+		connsMu.Lock()
+		conns[conn] = struct{}{}
+		connsMu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.serveController(ctx, conn)
+			connsMu.Lock()
+			delete(conns, conn)
+			connsMu.Unlock()
+			conn.Close()
+		}()
+	}
+
+	cancel()
+	connsMu.Lock()
+	for conn := range conns {
+		conn.Close()
+	}
+	connsMu.Unlock()
+	wg.Wait()
+	g.replies.Wait()
+	return err
+}
+
+// ServeSerial relays messages between the radio and a single controller connected
+// through a serial port (or any stream), like the MySensors Serial gateway.
+// It returns when ctx is cancelled or the stream ends.
+func (g *Gateway) ServeSerial(ctx context.Context, port io.ReadWriter) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.receiveLoop(ctx)
+	}()
+	err := g.serveController(ctx, port)
+	cancel()
+	<-done
+	g.replies.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// serveController announces the gateway to a controller and executes its messages.
+func (g *Gateway) serveController(ctx context.Context, rw io.ReadWriter) error {
+	g.mu.Lock()
+	g.controllers[rw] = &sync.Mutex{}
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.controllers, rw)
+		g.mu.Unlock()
+	}()
+
+	g.writeTo(rw, g.internal(GatewayID, InternalGatewayReady, "Gateway startup complete."))
+
+	scanner := bufio.NewScanner(rw)
+	for scanner.Scan() && ctx.Err() == nil {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		m, err := ParseSerial(scanner.Text())
+		if err != nil {
+			g.log(err.Error())
+			continue
+		}
+		g.fromController(rw, m)
+	}
+	return scanner.Err()
+}
+
+// fromController executes a message sent by a controller.
+func (g *Gateway) fromController(w io.Writer, m Message) {
+	m.Sender = GatewayID
+	if m.Destination != GatewayID {
+		if err := g.Send(m); err != nil {
+			g.log(fmt.Sprintf("!send to node %d failed: %v", m.Destination, err))
+		}
+		return
+	}
+
+	// Messages for the gateway itself
+	if m.Command != CommandInternal {
+		return
+	}
+	switch m.Type {
+	case InternalVersion:
+		g.writeTo(w, g.internal(GatewayID, InternalVersion, LibraryVersion))
+	case InternalHeartbeatRequest:
+		g.writeTo(w, g.internal(GatewayID, InternalHeartbeatResponse, "0"))
+	case InternalPresentation:
+		g.writeTo(w, Message{Command: CommandPresentation, Type: PresentationRepeaterNode, Sensor: NodeSensorID, Payload: []byte(LibraryVersion)})
+	case InternalDiscoverRequest:
+		m.Destination = BroadcastID
+		if err := g.Send(m); err != nil {
+			g.log(fmt.Sprintf("!discover broadcast failed: %v", err))
+		}
+	}
+}
+
+// receiveLoop reads radio messages until ctx is cancelled.
+func (g *Gateway) receiveLoop(ctx context.Context) {
+	for {
+		data, err := g.dev.ReceiveBlocking(ctx)
+		if err != nil {
+			return
+		}
+		m, err := DecodeMessage(data)
+		if err != nil {
+			g.log("!" + err.Error())
+			continue
+		}
+		g.fromRadio(m)
+	}
+}
+
+// fromRadio handles a message received from the radio network.
+func (g *Gateway) fromRadio(m Message) {
+	g.learn(m)
+
+	if m.Command == CommandInternal && m.Sender != GatewayID {
+		switch m.Type {
+		case InternalFindParentRequest:
+			// The gateway is always at distance 0 from itself
+			g.reply(g.internal(m.Sender, InternalFindParentResponse, "0"))
+			return
+		case InternalIDRequest:
+			if g.config.AssignIDs {
+				g.assignID()
+				return
+			}
+		case InternalPing:
+			g.reply(g.internal(m.Sender, InternalPong, "1"))
+			return
+		}
+	}
+
+	if m.Destination != GatewayID && m.Destination != BroadcastID {
+		return
+	}
+	g.broadcast(m)
+	if m.RequestEcho && m.Destination == GatewayID {
+		echo := m
+		echo.Destination, echo.Sender = m.Sender, GatewayID
+		echo.RequestEcho, echo.Echo = false, true
+		g.reply(echo)
+	}
+}
+
+// reply sends a message to the node that just transmitted, after replyDelay. The receive
+// loop goes on meanwhile.
+func (g *Gateway) reply(m Message) {
+	g.replies.Add(1)
+	time.AfterFunc(replyDelay, func() {
+		defer g.replies.Done()
+		if err := g.Send(m); err != nil {
+			g.log(fmt.Sprintf("!reply to node %d failed: %v", m.Destination, err))
+		}
+	})
+}
+
+// learn updates the routing table and the node descriptions from a received message.
+func (g *Gateway) learn(m Message) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if m.Sender == AutoID || m.Sender == GatewayID {
+		return
+	}
+	g.routes[m.Sender] = m.Last
+	n := g.nodes[m.Sender]
+	if n == nil {
+		n = &Node{ID: m.Sender, Sensors: make(map[byte]byte)}
+		g.nodes[m.Sender] = n
+	}
+	switch {
+	case m.Command == CommandPresentation && m.Sensor != NodeSensorID:
+		n.Sensors[m.Sensor] = m.Type
+	case m.Command == CommandInternal && m.Type == InternalSketchName:
+		n.SketchName = m.Value()
+	case m.Command == CommandInternal && m.Type == InternalSketchVersion:
+		n.SketchVersion = m.Value()
+	}
+}
+
+// assignID answers an ID request with the lowest unused node ID.
+func (g *Gateway) assignID() {
+	g.mu.Lock()
+	id := 0
+	for i := 1; i < AutoID; i++ {
+		if g.nodes[byte(i)] == nil {
+			id = i
+			// Reserve the ID until the node shows up
+			g.nodes[byte(i)] = &Node{ID: byte(i), Sensors: make(map[byte]byte)}
+			break
+		}
+	}
+	g.mu.Unlock()
+
+	if id == 0 {
+		g.log("!no free node ID")
+		return
+	}
+	g.reply(g.internal(AutoID, InternalIDResponse, strconv.Itoa(id)))
+}
+
+// Nodes returns the nodes seen by the gateway, sorted by ID.
+// This method is concurrent safe.
+func (g *Gateway) Nodes() []Node {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	nodes := make([]Node, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		c := *n
+		c.Sensors = make(map[byte]byte, len(n.Sensors))
+		for k, v := range n.Sensors {
+			c.Sensors[k] = v
+		}
+		nodes = append(nodes, c)
+	}
+	slices.SortFunc(nodes, func(a, b Node) int { return int(a.ID) - int(b.ID) })
+	return nodes
+}
+
+// Send transmits a message from the gateway to the radio network.
+// Messages for nodes behind a repeater are sent to the repeater; broadcasts are sent
+// without acknowledgement.
+// This method is concurrent safe.
+func (g *Gateway) Send(m Message) error {
+	m.Last = GatewayID
+	data, err := m.Encode()
+	if err != nil {
+		return err
+	}
+	if m.Destination == BroadcastID {
+		return g.dev.TransmitNoAck(NodeAddress(BroadcastID), data)
+	}
+
+	g.mu.Lock()
+	hop, ok := g.routes[m.Destination]
+	g.mu.Unlock()
+	if !ok {
+		hop = m.Destination
+	}
+	return g.dev.Transmit(NodeAddress(hop), data)
+}
+
+// internal builds an internal message from the gateway.
+func (g *Gateway) internal(dest byte, typ byte, value string) Message {
+	m := Message{Sender: GatewayID, Destination: dest, Command: CommandInternal, Type: typ, Sensor: NodeSensorID}
+	m.SetString(value)
+	return m
+}
+
+// log sends a log message to the controllers.
+func (g *Gateway) log(text string) {
+	if len(text) > MaxPayloadSize {
+		text = text[:MaxPayloadSize]
+	}
+	g.broadcast(g.internal(GatewayID, InternalLogMessage, text))
+}
+
+// broadcast writes a message to every controller. The controllers are written to without
+// holding mu, so that a stalled one does not block the radio.
+func (g *Gateway) broadcast(m Message) {
+	g.mu.Lock()
+	controllers := maps.Clone(g.controllers)
+	g.mu.Unlock()
+
+	line := m.SerialString() + "\n"
+	for w, wmu := range controllers {
+		wmu.Lock()
+		io.WriteString(w, line)
+		wmu.Unlock()
+	}
+}
+
+// writeTo writes a message to a single controller.
+func (g *Gateway) writeTo(w io.Writer, m Message) {
+	g.mu.Lock()
+	wmu := g.controllers[w]
+	g.mu.Unlock()
+	if wmu == nil {
+		return
+	}
+	wmu.Lock()
+	defer wmu.Unlock()
+	io.WriteString(w, m.SerialString()+"\n")
+}
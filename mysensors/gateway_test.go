@@ -0,0 +1,271 @@
+//go:build !tinygo
+
+package mysensors
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/sim"
+)
+
+// controller is a connection to the gateway, as Home Assistant would open.
+type controller struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *controller) send(line string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(line + "\n")); err != nil {
+		c.t.Fatalf("Write failed: %v", err)
+	}
+}
+
+// expect reads lines until one starts with prefix.
+func (c *controller) expect(prefix string) string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("Expected a line starting with %q: %v", prefix, err)
+		}
+		if line = strings.TrimSuffix(line, "\n"); strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+}
+
+// network is the air of a gateway, to add nodes to.
+type network struct {
+	*sim.Air
+	gateway *sim.Radio
+}
+
+// node is a node radio of a network.
+type node struct {
+	*nrf24.Device
+	gateway *sim.Radio
+}
+
+// startGateway runs a gateway on an emulated radio, connects a controller to it and
+// returns the network to add nodes to.
+func startGateway(t *testing.T, config GatewayConfig) (*network, *controller) {
+	t.Helper()
+	air := sim.NewAir()
+	dev, radio, err := air.NewDevice(RadioConfig(GatewayID))
+	if err != nil {
+		t.Fatalf("NewDevice(gateway) failed: %v", err)
+	}
+	g, err := NewGateway(dev, config)
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- g.Serve(ctx, l) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	})
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := &controller{t: t, conn: conn, r: bufio.NewReader(conn)}
+	c.expect("0;255;3;0;14;Gateway startup complete.")
+	return &network{Air: air, gateway: radio}, c
+}
+
+// newNode adds a node radio to the network.
+func newNode(t *testing.T, n *network, id byte) *node {
+	t.Helper()
+	dev, _, err := n.NewDevice(RadioConfig(id))
+	if err != nil {
+		t.Fatalf("NewDevice(node %d) failed: %v", id, err)
+	}
+	if err := dev.OpenRxPipe(broadcastPipe, []byte{BroadcastID}); err != nil {
+		t.Fatalf("OpenRxPipe failed: %v", err)
+	}
+	return &node{Device: dev, gateway: n.gateway}
+}
+
+func sendFromNode(t *testing.T, n *node, m Message) {
+	t.Helper()
+	data, err := m.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	// The emulated radios transmit instantly: wait for the gateway to be done sending
+	// its previous reply and back in RX mode, as the retransmit delay does on real radios
+	const config, primRX = 0x00, 0x01
+	for deadline := time.Now().Add(time.Second); n.gateway.Register(config)&primRX == 0 || n.gateway.CE().Read() != nrf24.High; {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the gateway to return to RX mode")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	dest := m.Destination
+	if dest == BroadcastID {
+		err = n.TransmitNoAck(NodeAddress(dest), data)
+	} else {
+		err = n.Transmit(NodeAddress(dest), data)
+	}
+	if err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+}
+
+func receiveOnNode(t *testing.T, n *node) Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := n.ReceiveBlocking(ctx)
+	if err != nil {
+		t.Fatalf("ReceiveBlocking failed: %v", err)
+	}
+	m, err := DecodeMessage(data)
+	if err != nil {
+		t.Fatalf("DecodeMessage failed: %v", err)
+	}
+	return m
+}
+
+func TestNodeToController(t *testing.T) {
+	air, c := startGateway(t, GatewayConfig{})
+	node := newNode(t, air, 5)
+
+	sendFromNode(t, node, Message{Last: 5, Sender: 5, Destination: GatewayID, Command: CommandSet, Type: 0, Sensor: 1,
+		PayloadType: PayloadFloat32, Payload: []byte{0x00, 0x00, 0xAC, 0x41, 1}})
+	c.expect("5;1;1;0;0;21.5")
+}
+
+func TestControllerToNode(t *testing.T) {
+	air, c := startGateway(t, GatewayConfig{})
+	node := newNode(t, air, 5)
+
+	c.send("5;2;1;0;2;1")
+	m := receiveOnNode(t, node)
+	if m.Sender != GatewayID || m.Destination != 5 || m.Sensor != 2 || m.Command != CommandSet || m.Value() != "1" {
+		t.Errorf("Unexpected message %v", m)
+	}
+
+	// Unreachable nodes are reported as log messages
+	c.send("9;1;1;0;2;1")
+	c.expect("0;255;3;0;9;!send to node 9")
+}
+
+func TestEcho(t *testing.T) {
+	air, c := startGateway(t, GatewayConfig{})
+	node := newNode(t, air, 5)
+
+	sendFromNode(t, node, Message{Last: 5, Sender: 5, Destination: GatewayID, Command: CommandSet, RequestEcho: true,
+		Type: 2, Sensor: 1, Payload: []byte("1")})
+	c.expect("5;1;1;0;2;1")
+
+	m := receiveOnNode(t, node)
+	if !m.Echo || m.RequestEcho || m.Destination != 5 {
+		t.Errorf("Expected an echo to node 5, got %v", m)
+	}
+}
+
+func TestFindParentAndIDAssignment(t *testing.T) {
+	air, _ := startGateway(t, GatewayConfig{AssignIDs: true})
+	node := newNode(t, air, AutoID)
+
+	find := Message{Last: AutoID, Sender: AutoID, Destination: BroadcastID, Command: CommandInternal,
+		Type: InternalFindParentRequest, Sensor: NodeSensorID}
+	sendFromNode(t, node, find)
+	if m := receiveOnNode(t, node); m.Type != InternalFindParentResponse || m.Value() != "0" {
+		t.Errorf("Expected a find parent response at distance 0, got %v", m)
+	}
+
+	req := Message{Last: AutoID, Sender: AutoID, Destination: GatewayID, Command: CommandInternal,
+		Type: InternalIDRequest, Sensor: NodeSensorID}
+	sendFromNode(t, node, req)
+	if m := receiveOnNode(t, node); m.Type != InternalIDResponse || m.Value() != "1" {
+		t.Errorf("Expected ID 1, got %v", m)
+	}
+	sendFromNode(t, node, req)
+	if m := receiveOnNode(t, node); m.Type != InternalIDResponse || m.Value() != "2" {
+		t.Errorf("Expected ID 2, got %v", m)
+	}
+}
+
+func TestPresentation(t *testing.T) {
+	air, c := startGateway(t, GatewayConfig{})
+	node := newNode(t, air, 7)
+
+	name := Message{Last: 7, Sender: 7, Destination: GatewayID, Command: CommandInternal, Type: InternalSketchName, Sensor: NodeSensorID}
+	name.SetString("Kitchen")
+	sendFromNode(t, node, name)
+	c.expect("7;255;3;0;11;Kitchen")
+
+	sendFromNode(t, node, Message{Last: 7, Sender: 7, Destination: GatewayID, Command: CommandPresentation, Type: 6, Sensor: 1})
+	c.expect("7;1;0;0;6;")
+
+	c.send("0;255;3;0;2;")
+	c.expect("0;255;3;0;2;" + LibraryVersion)
+}
+
+// stalledController is a controller that stops reading: writes block until release is closed.
+type stalledController struct {
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (s *stalledController) Write(p []byte) (int, error) {
+	close(s.writing)
+	<-s.release
+	return len(p), nil
+}
+
+func TestStalledController(t *testing.T) {
+	dev, _, err := sim.NewAir().NewDevice(RadioConfig(GatewayID))
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	g, err := NewGateway(dev, GatewayConfig{})
+	if err != nil {
+		t.Fatalf("NewGateway failed: %v", err)
+	}
+	stalled := &stalledController{writing: make(chan struct{}), release: make(chan struct{})}
+	defer close(stalled.release)
+	g.mu.Lock()
+	g.controllers[stalled] = &sync.Mutex{}
+	g.mu.Unlock()
+
+	go g.fromRadio(Message{Last: 5, Sender: 5, Destination: GatewayID, Command: CommandSet, Sensor: 1})
+	<-stalled.writing
+
+	// The gateway keeps learning from the radio while a controller does not read
+	done := make(chan struct{})
+	go func() {
+		g.learn(Message{Last: 6, Sender: 6, Destination: GatewayID, Command: CommandSet, Sensor: 1})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected learn not to wait for the stalled controller")
+	}
+}
@@ -0,0 +1,332 @@
+// Package mysensors implements the MySensors protocol (version 2) on top of an nrf24.Radio.
+//
+// It provides the radio message format, the text format of the Serial and Ethernet
+// gateways ("node;sensor;command;ack;type;payload"), and a Gateway that bridges the
+// radio network to controllers such as Home Assistant over TCP or a serial port.
+package mysensors
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/michcald/nrf24"
+)
+
+// ProtocolVersion is the version carried in the header of every radio message.
+const ProtocolVersion = 2
+
+// LibraryVersion is the MySensors library version reported by the gateway.
+const LibraryVersion = "2.3.2"
+
+// Special node and sensor IDs.
+const (
+	// GatewayID is the node ID of the gateway.
+	GatewayID = 0
+	// AutoID is the node ID of a node that has not been assigned an ID yet.
+	AutoID = 255
+	// BroadcastID is the destination of broadcast messages.
+	BroadcastID = 255
+	// NodeSensorID is the sensor ID used for messages about the node itself.
+	NodeSensorID = 255
+)
+
+// HeaderSize is the size of the radio message header.
+const HeaderSize = 7
+
+// MaxPayloadSize is the largest payload that fits in a radio message.
+const MaxPayloadSize = 32 - HeaderSize
+
+// Command is the message command.
+type Command uint8
+
+const (
+	CommandPresentation Command = 0
+	CommandSet          Command = 1
+	CommandReq          Command = 2
+	CommandInternal     Command = 3
+	CommandStream       Command = 4
+)
+
+func (c Command) String() string {
+	switch c {
+	case CommandPresentation:
+		return "PRESENTATION"
+	case CommandSet:
+		return "SET"
+	case CommandReq:
+		return "REQ"
+	case CommandInternal:
+		return "INTERNAL"
+	case CommandStream:
+		return "STREAM"
+	default:
+		return fmt.Sprintf("Command(%d)", uint8(c))
+	}
+}
+
+// PayloadType is the encoding of a radio message payload.
+type PayloadType uint8
+
+const (
+	PayloadString  PayloadType = 0
+	PayloadByte    PayloadType = 1
+	PayloadInt16   PayloadType = 2
+	PayloadUint16  PayloadType = 3
+	PayloadInt32   PayloadType = 4
+	PayloadUint32  PayloadType = 5
+	PayloadCustom  PayloadType = 6
+	PayloadFloat32 PayloadType = 7
+)
+
+// Internal message types (Type of CommandInternal messages).
+const (
+	InternalBatteryLevel         = 0
+	InternalTime                 = 1
+	InternalVersion              = 2
+	InternalIDRequest            = 3
+	InternalIDResponse           = 4
+	InternalInclusionMode        = 5
+	InternalConfig               = 6
+	InternalFindParentRequest    = 7
+	InternalFindParentResponse   = 8
+	InternalLogMessage           = 9
+	InternalChildren             = 10
+	InternalSketchName           = 11
+	InternalSketchVersion        = 12
+	InternalReboot               = 13
+	InternalGatewayReady         = 14
+	InternalSigningPresentation  = 15
+	InternalNonceRequest         = 16
+	InternalNonceResponse        = 17
+	InternalHeartbeatRequest     = 18
+	InternalPresentation         = 19
+	InternalDiscoverRequest      = 20
+	InternalDiscoverResponse     = 21
+	InternalHeartbeatResponse    = 22
+	InternalLocked               = 23
+	InternalPing                 = 24
+	InternalPong                 = 25
+	InternalRegistrationRequest  = 26
+	InternalRegistrationResponse = 27
+	InternalDebug                = 28
+)
+
+// Presentation types of nodes (Type of CommandPresentation messages on NodeSensorID).
+const (
+	PresentationNode         = 17
+	PresentationRepeaterNode = 18
+)
+
+// ErrInvalidMessage is returned when a message cannot be decoded or encoded.
+var ErrInvalidMessage = errors.New("invalid mysensors message")
+
+// Message is a MySensors message.
+type Message struct {
+	// Last is the node the message was last sent by (the sender, or the repeater forwarding it).
+	Last byte
+	// Sender is the node that created the message.
+	Sender byte
+	// Destination is the final recipient of the message.
+	Destination byte
+	// Version is the protocol version; zero means ProtocolVersion when encoding.
+	Version byte
+	// Signed marks a message carrying a signature.
+	Signed  bool
+	Command Command
+	// RequestEcho asks the destination to send the message back with Echo set.
+	// It is the "ack" field of the serial format.
+	RequestEcho bool
+	// Echo marks a message sent back in reply to RequestEcho.
+	Echo        bool
+	PayloadType PayloadType
+	// Type is the variable, presentation or internal type, depending on Command.
+	Type   byte
+	Sensor byte
+	// Payload holds the binary payload, encoded according to PayloadType.
+	Payload []byte
+}
+
+// DecodeMessage parses a radio message.
+func DecodeMessage(raw []byte) (Message, error) {
+	if len(raw) < HeaderSize {
+		return Message{}, fmt.Errorf("%w: %d bytes is shorter than the header", ErrInvalidMessage, len(raw))
+	}
+	m := Message{
+		Last:        raw[0],
+		Sender:      raw[1],
+		Destination: raw[2],
+		Version:     raw[3] & 0x03,
+		Signed:      raw[3]&0x04 != 0,
+		Command:     Command(raw[4] & 0x07),
+		RequestEcho: raw[4]&0x08 != 0,
+		Echo:        raw[4]&0x10 != 0,
+		PayloadType: PayloadType(raw[4] >> 5),
+		Type:        raw[5],
+		Sensor:      raw[6],
+	}
+	n := int(raw[3] >> 3)
+	if n > MaxPayloadSize || HeaderSize+n > len(raw) {
+		return Message{}, fmt.Errorf("%w: payload length %d exceeds the packet", ErrInvalidMessage, n)
+	}
+	m.Payload = append([]byte(nil), raw[HeaderSize:HeaderSize+n]...)
+	return m, nil
+}
+
+// Encode builds the radio representation of the message.
+func (m Message) Encode() ([]byte, error) {
+	if len(m.Payload) > MaxPayloadSize {
+		return nil, fmt.Errorf("%w: payload of %d bytes exceeds %d", ErrInvalidMessage, len(m.Payload), MaxPayloadSize)
+	}
+	version := m.Version
+	if version == 0 {
+		version = ProtocolVersion
+	}
+	b := make([]byte, HeaderSize, HeaderSize+len(m.Payload))
+	b[0] = m.Last
+	b[1] = m.Sender
+	b[2] = m.Destination
+	b[3] = version&0x03 | byte(len(m.Payload))<<3
+	if m.Signed {
+		b[3] |= 0x04
+	}
+	b[4] = byte(m.Command)&0x07 | byte(m.PayloadType)<<5
+	if m.RequestEcho {
+		b[4] |= 0x08
+	}
+	if m.Echo {
+		b[4] |= 0x10
+	}
+	b[5] = m.Type
+	b[6] = m.Sensor
+	return append(b, m.Payload...), nil
+}
+
+// SetString sets a string payload.
+func (m *Message) SetString(s string) {
+	m.PayloadType = PayloadString
+	m.Payload = []byte(s)
+}
+
+// Value returns the payload as text, the way the serial gateway prints it.
+func (m Message) Value() string {
+	p := m.Payload
+	switch m.PayloadType {
+	case PayloadString:
+		return string(p)
+	case PayloadByte:
+		if len(p) >= 1 {
+			return strconv.Itoa(int(p[0]))
+		}
+	case PayloadInt16:
+		if len(p) >= 2 {
+			return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(p))))
+		}
+	case PayloadUint16:
+		if len(p) >= 2 {
+			return strconv.Itoa(int(binary.LittleEndian.Uint16(p)))
+		}
+	case PayloadInt32:
+		if len(p) >= 4 {
+			return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(p))))
+		}
+	case PayloadUint32:
+		if len(p) >= 4 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(p)), 10)
+		}
+	case PayloadFloat32:
+		if len(p) >= 4 {
+			f := math.Float32frombits(binary.LittleEndian.Uint32(p))
+			// The byte following the value is the number of decimals, when present
+			prec := -1
+			if len(p) >= 5 {
+				prec = int(p[4])
+			}
+			return strconv.FormatFloat(float64(f), 'f', prec, 32)
+		}
+	}
+	return strings.ToUpper(hex.EncodeToString(p))
+}
+
+// ParseSerial parses a line of the serial gateway protocol:
+// "node-id;child-sensor-id;command;ack;type;payload".
+// As in the MySensors gateway, the payload is kept as a string, except for
+// CommandStream messages whose payload is hex encoded.
+func ParseSerial(line string) (Message, error) {
+	fields := strings.SplitN(strings.TrimRight(line, "\r\n"), ";", 6)
+	if len(fields) != 6 {
+		return Message{}, fmt.Errorf("%w: %q: expected 6 fields", ErrInvalidMessage, line)
+	}
+	var nums [5]byte
+	for i := range nums {
+		v, err := strconv.ParseUint(fields[i], 10, 8)
+		if err != nil {
+			return Message{}, fmt.Errorf("%w: %q: field %d: %w", ErrInvalidMessage, line, i+1, err)
+		}
+		nums[i] = byte(v)
+	}
+	m := Message{
+		Destination: nums[0],
+		Sensor:      nums[1],
+		Command:     Command(nums[2]),
+		RequestEcho: nums[3] != 0,
+		Type:        nums[4],
+	}
+	if m.Command > CommandStream {
+		return Message{}, fmt.Errorf("%w: %q: unknown command %d", ErrInvalidMessage, line, nums[2])
+	}
+	if m.Command == CommandStream {
+		p, err := hex.DecodeString(fields[5])
+		if err != nil {
+			return Message{}, fmt.Errorf("%w: %q: %w", ErrInvalidMessage, line, err)
+		}
+		m.PayloadType, m.Payload = PayloadCustom, p
+	} else {
+		m.SetString(fields[5])
+	}
+	if len(m.Payload) > MaxPayloadSize {
+		return Message{}, fmt.Errorf("%w: %q: payload exceeds %d bytes", ErrInvalidMessage, line, MaxPayloadSize)
+	}
+	return m, nil
+}
+
+// SerialString formats a message for a controller, without the trailing newline.
+// The node ID is the sender for messages coming from the radio network.
+func (m Message) SerialString() string {
+	ack := 0
+	if m.Echo {
+		ack = 1
+	}
+	return fmt.Sprintf("%d;%d;%d;%d;%d;%s", m.Sender, m.Sensor, m.Command, ack, m.Type, m.Value())
+}
+
+func (m Message) String() string {
+	return fmt.Sprintf("%d->%d (last %d) sensor=%d %s type=%d echo=%v/%v %q",
+		m.Sender, m.Destination, m.Last, m.Sensor, m.Command, m.Type, m.RequestEcho, m.Echo, m.Value())
+}
+
+// NodeAddress returns the radio address of a node (255 is the broadcast address).
+func NodeAddress(id byte) nrf24.Address {
+	return nrf24.Address{id, 0xFC, 0xE1, 0xA8, 0xA8}
+}
+
+// RadioConfig returns the radio configuration of the MySensors defaults for a node:
+// channel 76, 250kbps, dynamic payloads and the node address on pipe 1.
+func RadioConfig(id byte) nrf24.RadioConfig {
+	return nrf24.RadioConfig{
+		ChannelNumber:        76,
+		RxAddr:               NodeAddress(id),
+		EnableDynamicPayload: true,
+		EnableAutoAck:        true,
+		DataRate:             nrf24.DataRate250kbps,
+		PALevel:              nrf24.PALevelHigh,
+		AutoRetransmitDelay:  1500,
+		AutoRetransmitCount:  15,
+		AddressWidth:         5,
+		CRCLength:            nrf24.CRCLength16,
+	}
+}
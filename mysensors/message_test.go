@@ -0,0 +1,98 @@
+package mysensors
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	m := Message{
+		Last:        3,
+		Sender:      5,
+		Destination: 0,
+		Command:     CommandSet,
+		RequestEcho: true,
+		PayloadType: PayloadInt16,
+		Type:        2,
+		Sensor:      1,
+		Payload:     []byte{0xFE, 0xFF},
+	}
+	raw, err := m.Encode()
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	want := []byte{3, 5, 0, 2<<3 | ProtocolVersion, 0x08 | byte(CommandSet) | byte(PayloadInt16)<<5, 2, 1, 0xFE, 0xFF}
+	if !bytes.Equal(raw, want) {
+		t.Fatalf("Encode() = % X, want % X", raw, want)
+	}
+
+	got, err := DecodeMessage(raw)
+	if err != nil {
+		t.Fatalf("DecodeMessage failed: %v", err)
+	}
+	if got.Sender != 5 || got.Last != 3 || !got.RequestEcho || got.Echo || got.Version != ProtocolVersion {
+		t.Errorf("Unexpected header %+v", got)
+	}
+	if got.Value() != "-2" {
+		t.Errorf("Value() = %q, want -2", got.Value())
+	}
+
+	if _, err := DecodeMessage(raw[:HeaderSize+1]); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("Expected ErrInvalidMessage for a truncated payload, got %v", err)
+	}
+	if _, err := (Message{Payload: make([]byte, MaxPayloadSize+1)}).Encode(); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("Expected ErrInvalidMessage for an oversized payload, got %v", err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		typ     PayloadType
+		payload []byte
+		want    string
+	}{
+		{PayloadString, []byte("on"), "on"},
+		{PayloadByte, []byte{200}, "200"},
+		{PayloadUint16, []byte{0x34, 0x12}, "4660"},
+		{PayloadInt32, []byte{0xFF, 0xFF, 0xFF, 0xFF}, "-1"},
+		{PayloadUint32, []byte{0xFF, 0xFF, 0xFF, 0xFF}, "4294967295"},
+		{PayloadFloat32, []byte{0x00, 0x00, 0xAC, 0x41, 1}, "21.5"},
+		{PayloadCustom, []byte{0xAB, 0x01}, "AB01"},
+	}
+	for _, tt := range tests {
+		m := Message{PayloadType: tt.typ, Payload: tt.payload}
+		if got := m.Value(); got != tt.want {
+			t.Errorf("Value(%d, % X) = %q, want %q", tt.typ, tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestSerial(t *testing.T) {
+	m, err := ParseSerial("12;6;1;1;2;1\n")
+	if err != nil {
+		t.Fatalf("ParseSerial failed: %v", err)
+	}
+	if m.Destination != 12 || m.Sensor != 6 || m.Command != CommandSet || !m.RequestEcho || m.Type != 2 || m.Value() != "1" {
+		t.Errorf("Unexpected message %+v", m)
+	}
+
+	m, err = ParseSerial("12;255;4;0;0;0A0B")
+	if err != nil {
+		t.Fatalf("ParseSerial failed: %v", err)
+	}
+	if m.PayloadType != PayloadCustom || !bytes.Equal(m.Payload, []byte{0x0A, 0x0B}) {
+		t.Errorf("Expected a hex decoded stream payload, got %+v", m)
+	}
+
+	for _, line := range []string{"1;2;3", "1;2;9;0;0;x", "300;1;1;0;0;x"} {
+		if _, err := ParseSerial(line); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("ParseSerial(%q): expected ErrInvalidMessage, got %v", line, err)
+		}
+	}
+
+	out := Message{Sender: 12, Sensor: 6, Command: CommandSet, Echo: true, Type: 2, PayloadType: PayloadByte, Payload: []byte{1}}
+	if got := out.SerialString(); got != "12;6;1;1;2;1" {
+		t.Errorf("SerialString() = %q", got)
+	}
+}
@@ -2,9 +2,11 @@ package nrf24
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 )
@@ -24,6 +26,21 @@ func (a Address) String() string {
 	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X", a[0], a[1], a[2], a[3], a[4])
 }
 
+// ParseAddress parses an address in the format produced by Address.String ("E7:E7:E7:E7:E7").
+// The colons are optional ("E7E7E7E7E7"). Bytes are in register order (LSByte first).
+// Shorter addresses (3 or 4 bytes) leave the remaining bytes zero.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	h := strings.ReplaceAll(s, ":", "")
+	if len(h)%2 != 0 || len(h) < 6 || len(h) > 10 {
+		return a, fmt.Errorf("invalid address %q: expected 3 to 5 hex bytes", s)
+	}
+	if _, err := hex.Decode(a[:], []byte(h)); err != nil {
+		return a, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+	return a, nil
+}
+
 type (
 	DataRate  byte
 	PALevel   byte
@@ -109,6 +126,7 @@ const (
 	_RX_ADDR_P0  = 0x0A
 	_RX_ADDR_P1  = 0x0B
 	_TX_ADDR_REG = 0x10
+	_FIFO_STATUS = 0x17
 	_RX_PW_P0    = 0x11 // Receive Payload Width for Data Pipe 0
 	_RX_PW_P1    = 0x12 // Receive Payload Width for Data Pipe 1
 	//_RX_PW_P2 = 0x13
@@ -126,11 +144,13 @@ const (
 	_W_TX_PAYLOAD_NOACK = 0xB0
 	_FLUSH_TX     = 0xE1
 	_FLUSH_RX     = 0xE2
+	_REUSE_TX_PL  = 0xE3
 	_NOP          = 0xFF
 )
 
 // NRF24 Register Bit Definitions
 const (
+	_MASK_TX_DS = 1 << 5 // CONFIG: TX_DS not reflected on the IRQ pin
 	_PWR_UP  = 1 << 1
 	_PRIM_RX = 1 << 0
 	_RX_DR   = 1 << 6
@@ -138,7 +158,8 @@ const (
 	_MAX_RT  = 1 << 4
 	_EN_CRC  = 1 << 3
 	_CRCO    = 1 << 2
-	// _RX_EMPTY = 1 << 0
+	_RX_EMPTY = 1 << 0
+	_RX_FULL = 1 << 1
 
 	_SETUP_RETR = 0x04
 	_EN_AA      = 0x01 // Auto Ack
@@ -205,6 +226,20 @@ type HardwareConfig struct {
 	// IRQ is the Interrupt Request pin interface.
 	// Optional. If not provided, polling is used.
 	IRQ Pin
+	// Name identifies the device in log messages (field "device").
+	// Optional.
+	Name string
+	// Logger receives the log messages of the device.
+	// Optional. If not provided, the global logger set with SetLogger is used.
+	Logger Logger
+	// Clock provides the time and the delays of the device.
+	// Optional. If not provided, SystemClock is used.
+	Clock Clock
+	// VerifyInterval enables the periodic verification of the configuration registers
+	// cached by the driver (see Device.VerifyRegisters): transmissions and receptions first
+	// verify them if the interval elapsed since the last verification.
+	// Optional. If not provided, the registers are only verified by VerifyRegisters.
+	VerifyInterval time.Duration
 }
 
 type Device struct {
@@ -212,8 +247,41 @@ type Device struct {
 	conn    SPI
 	irqChan chan struct{}
 	nrfPort io.Closer
+	// gpioLines holds the GPIO lines requested by New, released by Close
+	gpioLines []io.Closer
 	mu      sync.Mutex
 	scratch [33]byte // Max payload (32) + 1 status byte
+	// sniffing is true while the radio is in promiscuous sniffer mode
+	sniffing bool
+	// snifferPipes is the pipe configuration StopSniffer restores
+	snifferPipes pipeState
+	// txAddr is the current target address
+	txAddr Address
+	// pipeAddrs holds the address of each RX pipe (only the LSB for pipes 2-5)
+	pipeAddrs [6]Address
+	recorder  FrameRecorder
+	observer  Observer
+	// mode is the current operating mode of the radio
+	mode Mode
+	// ceHigh is the current level of the CE pin
+	ceHigh bool
+	stats     Stats
+	// shadow caches the configuration registers, see register
+	shadow shadow
+	// lastVerify is the time of the last verification of the shadow
+	lastVerify time.Time
+	// txAddrSet is true once txAddr and RX_ADDR_P0 are known to hold the target address
+	txAddrSet bool
+	// beacon is the running beacon, if any
+	beacon *beacon
+	// beaconLoads counts the loads of the beacon, so that runBeacon notices a reload made
+	// while it does not hold the lock
+	beaconLoads uint64
+	// batch queues register writes for connections performing batches, see beginBatch
+	batch writeBatch
+	// txOnly keeps the radio in standby after transmissions instead of returning to RX mode,
+	// for the transmitting radios of a Link
+	txOnly bool
 }
 
 // NewWithHardware creates and initializes a new NRF24L01 driver with the provided hardware interfaces.
@@ -250,11 +318,16 @@ func NewWithHardware(c HardwareConfig, conn SPI) (*Device, error) {
 	if c.CE == nil {
 		return nil, fmt.Errorf("CE pin not configured")
 	}
+	if c.Clock == nil {
+		c.Clock = SystemClock
+	}
 
 	dev := &Device{
 		config: c,
 		conn:   conn,
 	}
+	// Reset value of RX_ADDR_P0, until the first transmission overwrites it
+	dev.pipeAddrs[0] = Address{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}
 
 	// --- Hardware Initialization ---
 
@@ -262,7 +335,7 @@ func NewWithHardware(c HardwareConfig, conn SPI) (*Device, error) {
 		return nil, fmt.Errorf("channel number must be between 0 and 124")
 	}
 
-	globalLogger.Info("Initializing NRF24L01 SPI communication...")
+	dev.log(LogInfo, "Initializing NRF24L01 SPI communication...")
 
 	// Setup CE
 	dev.config.CE.Out(Low)
@@ -292,30 +365,59 @@ func NewWithHardware(c HardwareConfig, conn SPI) (*Device, error) {
 	dev.flushTX()
 	dev.flushRX()
 
+	dev.configureRadio()
+
+	// 10. Verify Connection
+	// Read back the channel to ensure SPI write/read is working
+	readChannel := dev.readRegister(_RF_CH)
+	if readChannel != dev.config.ChannelNumber {
+		dev.Close()
+		return nil, fmt.Errorf("failed to verify NRF24L01 connection: check wiring/power")
+	}
+	dev.lastVerify = dev.config.Clock.Now()
+
+	dev.mode = ModeRX
+	dev.log(LogInfo, "NRF24L01 initialized and powered up. Ready to operate.",
+		Field{"channel", int(dev.config.ChannelNumber)}, Field{"address", dev.config.RxAddr})
+
+	// Set CE high to start listening ONLY after full configuration
+	dev.setCE(true)
+
+	return dev, nil
+}
+
+// configureRadio powers up the radio and programs every configuration register
+// (RF, addressing, auto-ack and payload settings) from the current config.
+// Call with lock held and CE low.
+func (d *Device) configureRadio() {
+	d.txAddrSet = false
 	var configValue byte = _PWR_UP | _PRIM_RX // Power up and set as primary receiver
-	switch dev.config.CRCLength {
+	switch d.config.CRCLength {
 	case CRCLength8:
 		configValue |= _EN_CRC
 	case CRCLength16:
 		configValue |= _EN_CRC | _CRCO
 	}
-	dev.writeRegister(_CONFIG, configValue)
-	time.Sleep(5 * time.Millisecond)
+	d.writeRegister(_CONFIG, configValue)
+	d.config.Clock.Sleep(5 * time.Millisecond)
+
+	d.beginBatch()
+	defer d.endBatch()
 
 	// 7. Set RF parameters
-	dev.writeRegister(_RF_CH, dev.config.ChannelNumber)
+	d.writeRegister(_RF_CH, d.config.ChannelNumber)
 
 	// Set Address Width
-	dev.writeRegister(_SETUP_AW, dev.config.AddressWidth-2)
+	d.writeRegister(_SETUP_AW, d.config.AddressWidth-2)
 
 	// Set Auto Retransmit Delay and Count
-	ard := (dev.config.AutoRetransmitDelay/250 - 1) & 0x0F
-	arc := dev.config.AutoRetransmitCount & 0x0F
-	dev.writeRegister(_SETUP_RETR, (byte(ard)<<4)|byte(arc))
+	ard := (d.config.AutoRetransmitDelay/250 - 1) & 0x0F
+	arc := d.config.AutoRetransmitCount & 0x0F
+	d.writeRegister(_SETUP_RETR, (byte(ard)<<4)|byte(arc))
 
 	// Set Data Rate and Power Level
 	var rfSetup byte
-	switch dev.config.DataRate {
+	switch d.config.DataRate {
 	case DataRate1mbps:
 		// 00001000, RF_DR_HIGH = 0, RF_DR_LOW = 0
 	case DataRate2mbps:
@@ -323,7 +425,7 @@ func NewWithHardware(c HardwareConfig, conn SPI) (*Device, error) {
 	case DataRate250kbps:
 		rfSetup |= 1 << 5 // RF_DR_LOW
 	}
-	switch dev.config.PALevel {
+	switch d.config.PALevel {
 	case PALevelMin:
 		// 0
 	case PALevelLow:
@@ -333,52 +435,38 @@ func NewWithHardware(c HardwareConfig, conn SPI) (*Device, error) {
 	case PALevelMax:
 		rfSetup |= 3 << 1
 	}
-	dev.writeRegister(_RF_SETUP, rfSetup)
+	d.writeRegister(_RF_SETUP, rfSetup)
 
 	// 8. Configure Auto Ack and Pipes
-	if dev.config.EnableAutoAck {
-		dev.writeRegister(_EN_AA, _ERX_P0|_ERX_P1)
+	if d.config.EnableAutoAck {
+		d.writeRegister(_EN_AA, _ERX_P0|_ERX_P1)
 	} else {
-		dev.writeRegister(_EN_AA, 0)
+		d.writeRegister(_EN_AA, 0)
 	}
-	dev.writeRegister(_EN_RXADDR, _ERX_P0|_ERX_P1)
+	d.writeRegister(_EN_RXADDR, _ERX_P0|_ERX_P1)
 
 	// 9. Set Addresses and Payload Sizes
-	dev.writeRegisterN(_RX_ADDR_P1, dev.config.RxAddr[:])
+	d.writeRegisterN(_RX_ADDR_P1, d.config.RxAddr[:])
+	d.pipeAddrs[1] = d.config.RxAddr
 
 	// Always enable Dynamic ACK feature to support TransmitNoAck
 	featureVal := byte(_EN_DYN_ACK)
 
-	if dev.config.EnableDynamicPayload {
+	if d.config.EnableDynamicPayload {
 		// Enable dynamic payload length (DPL) and ACK payloads on all pipes
 		featureVal |= _EN_DPL | _EN_ACK_PAY
-		dev.writeRegister(_FEATURE, featureVal)
+		d.writeRegister(_FEATURE, featureVal)
 		// Enable dynamic payload on data pipes 0 and 1
-		dev.writeRegister(_DYNPD, _ERX_P0|_ERX_P1)
+		d.writeRegister(_DYNPD, _ERX_P0|_ERX_P1)
 	} else {
 		// Disable dynamic payload features
-		dev.writeRegister(_FEATURE, featureVal)
+		d.writeRegister(_FEATURE, featureVal)
 		// Disable dynamic payload on all pipes
-		dev.writeRegister(_DYNPD, 0)
+		d.writeRegister(_DYNPD, 0)
 		// Set payload width for pipes 0 and 1
-		dev.writeRegister(_RX_PW_P0, dev.config.PayloadSize)
-		dev.writeRegister(_RX_PW_P1, dev.config.PayloadSize)
-	}
-
-	// 10. Verify Connection
-	// Read back the channel to ensure SPI write/read is working
-	readChannel := dev.readRegister(_RF_CH)
-	if readChannel != dev.config.ChannelNumber {
-		dev.Close()
-		return nil, fmt.Errorf("failed to verify NRF24L01 connection: check wiring/power")
+		d.writeRegister(_RX_PW_P0, d.config.PayloadSize)
+		d.writeRegister(_RX_PW_P1, d.config.PayloadSize)
 	}
-
-	globalLogger.Info("NRF24L01 initialized and powered up. Ready to operate.")
-
-	// Set CE high to start listening ONLY after full configuration
-	dev.setCE(true)
-
-	return dev, nil
 }
 
 func (d *Device) String() string {
@@ -402,28 +490,58 @@ func (dev *Device) Close() error {
 	dev.mu.Lock()
 	defer dev.mu.Unlock()
 
+	// Stop the beacon goroutine, if any
+	dev.beacon = nil
+	dev.setCE(false)
+
 	// 1. Power down
 	// We duplicate logic here to avoid deadlock if we called PowerDown() which locks
-	dev.writeRegister(_CONFIG, dev.readRegister(_CONFIG)&^byte(_PWR_UP))
-	globalLogger.Info("NRF24L01 powered down.")
+	dev.writeRegister(_CONFIG, dev.register(_CONFIG)&^byte(_PWR_UP))
+	dev.setMode(ModePowerDown)
+	dev.log(LogInfo, "NRF24L01 powered down.")
 
 	// 2. Clean up SPI
 	if dev.nrfPort != nil {
 		if err := dev.nrfPort.Close(); err != nil {
-			globalLogger.Warn("Failed to close SPI port")
+			dev.log(LogWarn, "Failed to close SPI port", Field{"error", err})
 		}
-		globalLogger.Info("SPI bus closed.")
+		dev.log(LogInfo, "SPI bus closed.")
 	}
 
 	// 3. Clean up GPIO
 	if dev.config.IRQ != nil {
 		dev.config.IRQ.Unwatch()
 	}
-	globalLogger.Info("GPIO interface closed.")
+	for _, l := range dev.gpioLines {
+		l.Close()
+	}
+	dev.log(LogInfo, "GPIO interface closed.")
 
 	return nil
 }
 
+// SetLogger sets the logger of the device, replacing HardwareConfig.Logger.
+// A nil logger makes the device use the global logger again.
+// This method is concurrent safe.
+func (d *Device) SetLogger(l Logger) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.config.Logger = l
+}
+
+// log writes a message to the logger of the device, with the device name as first field.
+// Call with lock held (or before the device is shared).
+func (d *Device) log(level LogLevel, msg string, fields ...Field) {
+	l := d.config.Logger
+	if l == nil {
+		l = globalLogger
+	}
+	if d.config.Name != "" {
+		fields = append([]Field{{"device", d.config.Name}}, fields...)
+	}
+	logTo(l, level, msg, fields)
+}
+
 // --- NRF24L01 Core Functions (SPI interaction) ---
 
 func (d *Device) spiTransfer(len int) (status byte, response []byte) {
@@ -431,7 +549,7 @@ func (d *Device) spiTransfer(len int) (status byte, response []byte) {
 	// We use the same slice for read and write
 	slice := d.scratch[:len]
 	if err := d.conn.Tx(slice, slice); err != nil {
-		globalLogger.Error("SPI Transfer Error")
+		d.log(LogError, "SPI Transfer Error", Field{"error", err})
 		return 0, nil
 	}
 
@@ -441,26 +559,36 @@ func (d *Device) spiTransfer(len int) (status byte, response []byte) {
 	return 0, nil
 }
 
+// writeRegister writes a register, and records the value in the shadow.
 func (d *Device) writeRegister(reg, val byte) {
 	d.scratch[0] = _W_REGISTER | reg
 	d.scratch[1] = val
-	d.spiTransfer(2)
+	d.updateShadow(reg, val, d.transferWrite(2))
 }
 
+// readRegister reads a register from the radio. See register for the cached value.
 func (d *Device) readRegister(reg byte) byte {
+	val, _ := d.readRegisterOK(reg)
+	return val
+}
+
+// readRegisterOK is like readRegister but also reports whether the SPI transaction succeeded.
+func (d *Device) readRegisterOK(reg byte) (byte, bool) {
 	d.scratch[0] = reg
 	d.scratch[1] = _NOP
 	_, data := d.spiTransfer(2)
 	if len(data) > 0 {
-		return data[0]
+		return data[0], true
 	}
-	return 0
+	return 0, false
 }
 
-func (d *Device) writeRegisterN(reg byte, data []byte) {
+// writeRegisterN writes a multi-byte register and reports whether the SPI transaction succeeded
+// (or was queued, see beginBatch).
+func (d *Device) writeRegisterN(reg byte, data []byte) bool {
 	d.scratch[0] = _W_REGISTER | reg
 	copy(d.scratch[1:], data)
-	d.spiTransfer(1 + len(data))
+	return d.transferWrite(1 + len(data))
 }
 
 func (d *Device) flushTX() {
@@ -478,6 +606,7 @@ func (d *Device) clearStatus() {
 }
 
 func (d *Device) setCE(level bool) {
+	d.ceHigh = level
 	if level {
 		d.config.CE.Out(High)
 	} else {
@@ -488,13 +617,22 @@ func (d *Device) setCE(level bool) {
 // setTargetAddress is for changing dynamically the target address to send messages to
 func (d *Device) setTargetAddress(addr Address) {
 	d.setCE(false) // Ensure we are in standby
-	d.writeRegisterN(_TX_ADDR_REG, addr[:])
+	// Nothing to write when transmitting to the same address again
+	if d.txAddrSet && d.txAddr == addr && d.pipeAddrs[0] == addr {
+		return
+	}
+	d.beginBatch()
+	ok := d.writeRegisterN(_TX_ADDR_REG, addr[:])
+	d.txAddr = addr
 
 	// If using Auto-Ack (EN_AA), you MUST also update RX_ADDR_P0
 	// to match TX_ADDR, because the ACK comes back to P0.
-	d.writeRegisterN(_RX_ADDR_P0, addr[:])
+	ok = d.writeRegisterN(_RX_ADDR_P0, addr[:]) && ok
+	ok = d.endBatch() && ok
+	d.pipeAddrs[0] = addr
+	d.txAddrSet = ok
 
-	time.Sleep(time.Millisecond)
+	d.config.Clock.Sleep(time.Millisecond)
 }
 
 // --- NRF24L01 Configuration ---
@@ -524,6 +662,11 @@ func (d *Device) OpenRxPipe(pipeID int, address []byte) error {
 		// Register is 0x0A (P0) or 0x0B (P1)
 		reg := byte(_RX_ADDR_P0 + pipeID)
 		d.writeRegisterN(reg, address[:d.config.AddressWidth])
+		if pipeID == 0 {
+			d.txAddrSet = false
+		}
+		d.pipeAddrs[pipeID] = Address{}
+		copy(d.pipeAddrs[pipeID][:], address[:d.config.AddressWidth])
 	} else {
 		// Pipes 2-5 require 1 byte (LSB)
 		if len(address) == 0 {
@@ -533,19 +676,20 @@ func (d *Device) OpenRxPipe(pipeID int, address []byte) error {
 		// Register is 0x0C (P2) ... 0x0F (P5)
 		reg := byte(_RX_ADDR_P0 + pipeID)
 		d.writeRegister(reg, address[0])
+		d.pipeAddrs[pipeID] = Address{address[0]}
 	}
 
 	// 2. Configure Payload
 	if d.config.EnableDynamicPayload {
 		// Enable DYNPD bit for this pipe
-		d.writeRegister(_DYNPD, d.readRegister(_DYNPD)|(1<<pipeID))
+		d.writeRegister(_DYNPD, d.register(_DYNPD)|(1<<pipeID))
 		// Ensure feature is on (should be already from Start, but safe to check)
-		if d.readRegister(_FEATURE)&_EN_DPL == 0 {
-			d.writeRegister(_FEATURE, d.readRegister(_FEATURE)|_EN_DPL)
+		if d.register(_FEATURE)&_EN_DPL == 0 {
+			d.writeRegister(_FEATURE, d.register(_FEATURE)|_EN_DPL)
 		}
 	} else {
 		// Disable DYNPD bit for this pipe
-		d.writeRegister(_DYNPD, d.readRegister(_DYNPD)&^(1<<pipeID))
+		d.writeRegister(_DYNPD, d.register(_DYNPD)&^(1<<pipeID))
 		// Set Static Payload Width
 		// Register is 0x11 (P0) ... 0x16 (P5)
 		reg := byte(_RX_PW_P0 + pipeID)
@@ -553,18 +697,31 @@ func (d *Device) OpenRxPipe(pipeID int, address []byte) error {
 	}
 
 	// 3. Enable Pipe in EN_RXADDR
-	d.writeRegister(_EN_RXADDR, d.readRegister(_EN_RXADDR)|(1<<pipeID))
+	d.writeRegister(_EN_RXADDR, d.register(_EN_RXADDR)|(1<<pipeID))
 
 	// 4. Configure Auto-Ack
 	if d.config.EnableAutoAck {
-		d.writeRegister(_EN_AA, d.readRegister(_EN_AA)|(1<<pipeID))
+		d.writeRegister(_EN_AA, d.register(_EN_AA)|(1<<pipeID))
 	} else {
-		d.writeRegister(_EN_AA, d.readRegister(_EN_AA)&^(1<<pipeID))
+		d.writeRegister(_EN_AA, d.register(_EN_AA)&^(1<<pipeID))
 	}
 
 	return nil
 }
 
+// PipeAddress returns the full address of a data pipe (0-5).
+// Pipes 2-5 report their own LSByte followed by the high bytes shared with pipe 1.
+// This method is concurrent safe.
+func (d *Device) PipeAddress(pipeID int) (Address, error) {
+	if pipeID < 0 || pipeID > 5 {
+		return Address{}, fmt.Errorf("pipeID must be between 0 and 5")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pipeAddress(pipeID), nil
+}
+
 // CloseRxPipe disables a specific data pipe (0-5).
 // This method is concurrent safe.
 func (d *Device) CloseRxPipe(pipeID int) error {
@@ -576,9 +733,9 @@ func (d *Device) CloseRxPipe(pipeID int) error {
 	defer d.mu.Unlock()
 
 	// Clear bit in EN_RXADDR
-	d.writeRegister(_EN_RXADDR, d.readRegister(_EN_RXADDR)&^(1<<pipeID))
+	d.writeRegister(_EN_RXADDR, d.register(_EN_RXADDR)&^(1<<pipeID))
 	// Clear bit in EN_AA
-	d.writeRegister(_EN_AA, d.readRegister(_EN_AA)&^(1<<pipeID))
+	d.writeRegister(_EN_AA, d.register(_EN_AA)&^(1<<pipeID))
 
 	return nil
 }
@@ -607,7 +764,57 @@ func (d *Device) IsCarrierDetected() bool {
 	defer d.mu.Unlock()
 
 	// Bit 0 of RPD register
-	return (d.readRegister(_RPD) & 0x01) != 0
+	detected := (d.readRegister(_RPD) & 0x01) != 0
+	d.countCarrier(detected)
+	return detected
+}
+
+// ScanChannel listens on another channel for the given dwell time and reports whether a
+// carrier (> -64dBm) was detected there. The radio returns to its configured channel, and to
+// its previous mode, afterwards. It fails while sniffing or sending a beacon.
+// Calling it for every channel in a loop produces a simple spectrum scan.
+// This method is concurrent safe.
+func (d *Device) ScanChannel(channel byte, dwell time.Duration) (bool, error) {
+	if channel > 124 {
+		return false, fmt.Errorf("channel number must be between 0 and 124")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.sniffing {
+		return false, fmt.Errorf("%w: %w", ErrPkg, ErrSnifferActive)
+	}
+	if d.beacon != nil {
+		return false, fmt.Errorf("%w: %w", ErrPkg, ErrBeaconActive)
+	}
+
+	ceHigh, mode := d.ceHigh, d.mode
+	config := d.register(_CONFIG)
+	d.setCE(false)
+	// With PRIM_RX cleared, raising CE would transmit the content of the TX FIFO
+	if config&_PRIM_RX == 0 {
+		d.writeRegister(_CONFIG, config|_PRIM_RX)
+	}
+	d.writeRegister(_RF_CH, channel)
+	d.setCE(true)
+	d.setMode(ModeRX)
+	// RX settling time (130us) plus the time needed to latch RPD (40us)
+	if dwell < 170*time.Microsecond {
+		dwell = 170 * time.Microsecond
+	}
+	d.config.Clock.Sleep(dwell)
+	// RPD is latched when the receiver is disabled
+	d.setCE(false)
+	detected := (d.readRegister(_RPD) & 0x01) != 0
+	d.countCarrier(detected)
+
+	d.writeRegister(_RF_CH, d.config.ChannelNumber)
+	if config&_PRIM_RX == 0 {
+		d.writeRegister(_CONFIG, config)
+	}
+	d.setCE(ceHigh)
+	d.setMode(mode)
+	return detected, nil
 }
 
 // FlushTX clears the transmit FIFO buffer.
@@ -635,6 +842,15 @@ func (d *Device) GetStatus() byte {
 	return d.readRegister(_STATUS)
 }
 
+// RadioConfig returns the current radio settings, including the changes made with
+// SetChannel, SetDataRate, SetPALevel, SetAutoRetransmit and SetAddressWidth.
+// This method is concurrent safe.
+func (d *Device) RadioConfig() RadioConfig {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.config.RadioConfig
+}
+
 // SetChannel changes the radio channel (frequency).
 // channel must be between 0 and 124.
 // This method is concurrent safe.
@@ -647,6 +863,7 @@ func (d *Device) SetChannel(channel byte) error {
 
 	d.writeRegister(_RF_CH, channel)
 	d.config.ChannelNumber = channel
+	d.configChanged()
 	return nil
 }
 
@@ -691,6 +908,7 @@ func (d *Device) SetAutoRetransmit(delay uint16, count byte) error {
 
 	d.config.AutoRetransmitDelay = delay
 	d.config.AutoRetransmitCount = count
+	d.configChanged()
 	return nil
 }
 
@@ -717,6 +935,7 @@ func (d *Device) updateRFSetup() error {
 		rfSetup |= 3 << 1
 	}
 	d.writeRegister(_RF_SETUP, rfSetup)
+	d.configChanged()
 	return nil
 }
 
@@ -725,11 +944,14 @@ func (d *Device) updateRFSetup() error {
 // PowerDown puts the NRF24L01 into Power Down mode.
 // In this mode, the radio is disabled with minimal current consumption (approx. 900nA).
 // This is useful for battery-powered applications when the radio is not in use.
+// A running beacon is stopped: the radio wakes up in standby.
 // This method is concurrent safe.
 func (d *Device) PowerDown() {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	d.writeRegister(_CONFIG, d.readRegister(_CONFIG)&^byte(_PWR_UP))
+	d.stopBeacon()
+	d.writeRegister(_CONFIG, d.register(_CONFIG)&^byte(_PWR_UP))
+	d.setMode(ModePowerDown)
 }
 
 // PowerUp wakes the NRF24L01 from Power Down mode.
@@ -739,28 +961,40 @@ func (d *Device) PowerDown() {
 func (d *Device) PowerUp() {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	d.writeRegister(_CONFIG, d.readRegister(_CONFIG)|_PWR_UP)
-	time.Sleep(2 * time.Millisecond) // Wait for oscillator stabilization
+	config := d.register(_CONFIG)
+	d.writeRegister(_CONFIG, config|_PWR_UP)
+	d.config.Clock.Sleep(2 * time.Millisecond) // Wait for oscillator stabilization
+	if config&_PRIM_RX != 0 && d.ceHigh {
+		d.setMode(ModeRX)
+	} else {
+		d.setMode(ModeStandby)
+	}
 }
 
 func (d *Device) startListening() {
 	d.setCE(false)
-	d.writeRegister(_CONFIG, d.readRegister(_CONFIG)|_PRIM_RX)
-	d.setCE(true)
-	time.Sleep(130 * time.Microsecond)
+	// Discard stale packets before enabling RX, so that packets arriving
+	// right after the switch are not flushed with them
 	d.clearStatus()
 	d.flushRX()
+	d.writeRegister(_CONFIG, d.register(_CONFIG)|_PRIM_RX)
+	d.setCE(true)
+	d.config.Clock.Sleep(130 * time.Microsecond)
+	d.setMode(ModeRX)
 }
 
 func (d *Device) stopListening() {
 	d.setCE(false)
-	d.writeRegister(_CONFIG, d.readRegister(_CONFIG) & ^byte(_PRIM_RX))
+	d.writeRegister(_CONFIG, d.register(_CONFIG) & ^byte(_PRIM_RX))
+	d.setMode(ModeStandby)
 }
 
 // --- NRF24L01 Read/Write ---
 
-func (d *Device) available() bool {
-	return ((d.readRegister(_STATUS) >> 1) & 0x07) != 7
+// available reports whether the RX FIFO holds a packet and, if so, the pipe it arrived on.
+func (d *Device) available() (int, bool) {
+	pipe := int((d.readRegister(_STATUS) >> 1) & 0x07)
+	return pipe, pipe != 7
 }
 
 func (d *Device) getDynamicPayloadSize() byte {
@@ -778,11 +1012,9 @@ func (d *Device) getDynamicPayloadSize() byte {
 	return 0
 }
 
+// readDynamic reads a packet of dynamic length.
+// The returned slice aliases the scratch buffer: copy it before the next SPI transaction.
 func (d *Device) readDynamic() ([]byte, bool) {
-	if !d.available() {
-		return nil, false
-	}
-
 	// 1. Ask the radio how big the current packet is
 	size := d.getDynamicPayloadSize()
 	if size == 0 {
@@ -790,7 +1022,7 @@ func (d *Device) readDynamic() ([]byte, bool) {
 		// or a glitch. In either case, we must remove it from the FIFO or we will loop forever.
 		// Since we can't "read" 0 bytes to advance the FIFO, we flush.
 		d.flushRX()
-		d.clearStatus()
+		d.writeRegister(_STATUS, _RX_DR)
 		return nil, false
 	}
 
@@ -801,21 +1033,12 @@ func (d *Device) readDynamic() ([]byte, bool) {
 	}
 
 	_, data := d.spiTransfer(int(size) + 1)
-
-	// Copy result to safe buffer BEFORE calling clearStatus which reuses scratch
-	result := make([]byte, len(data))
-	copy(result, data)
-
-	d.clearStatus()
-	
-	return result, true
+	return data, true
 }
 
-func (d *Device) readFixedPayload() ([]byte, bool) {
-	if !d.available() {
-		return nil, false
-	}
-
+// readFixedPayload reads a packet of PayloadSize bytes.
+// The returned slice aliases the scratch buffer: copy it before the next SPI transaction.
+func (d *Device) readFixedPayload() []byte {
 	size := int(d.config.PayloadSize)
 	// Read exactly size bytes
 	d.scratch[0] = _R_RX_PAYLOAD
@@ -824,26 +1047,58 @@ func (d *Device) readFixedPayload() ([]byte, bool) {
 	}
 
 	_, data := d.spiTransfer(size + 1)
+	return data
+}
 
-	// Copy result to safe buffer BEFORE calling clearStatus which reuses scratch
-	result := make([]byte, len(data))
-	copy(result, data)
+func (d *Device) write(data []byte, noAck bool) (err error) {
+	defer func() { d.afterTX(data, noAck, err) }()
 
-	d.clearStatus()
+	d.stopListening()
+	if d.observer != nil {
+		d.observer.TxStart(d.txAddr, data, noAck)
+	}
 
-	return result, true
-}
+	d.loadPayload(data, noAck)
 
-func (d *Device) write(data []byte, noAck bool) error {
-	d.stopListening()
+	d.setCE(true)
+	d.setMode(ModeTX)
+	d.config.Clock.Sleep(15 * time.Microsecond)
+	d.setCE(false)
 
+	// Calculate a safe timeout based on retransmit settings.
+	// (Delay * Count) is the maximum time the hardware will spend retrying.
+	// We add a 50ms safety buffer for SPI communication and OS scheduling.
+	timeoutDuration := time.Duration(d.config.AutoRetransmitDelay)*time.Duration(d.config.AutoRetransmitCount)*time.Microsecond + 50*time.Millisecond
+	deadline := d.config.Clock.Now().Add(timeoutDuration)
+
+	for {
+		if !d.config.Clock.Now().Before(deadline) {
+			d.clearStatus()
+			d.flushTX()
+			return fmt.Errorf("%w: %w", ErrPkg, ErrTimeout)
+		}
+		status := d.readRegister(_STATUS)
+		if status&(_TX_DS|_MAX_RT) != 0 {
+			d.clearStatus()
+			if status&_MAX_RT != 0 {
+				d.flushTX()
+				return fmt.Errorf("%w: %w", ErrPkg, ErrMaxRetries)
+			}
+			return nil
+		}
+		d.config.Clock.Sleep(1 * time.Millisecond)
+	}
+}
+
+// loadPayload writes a payload to the TX FIFO.
+func (d *Device) loadPayload(data []byte, noAck bool) {
 	cmdPrefix := byte(_W_TX_PAYLOAD)
 	if noAck {
 		cmdPrefix = _W_TX_PAYLOAD_NOACK
 	}
 
 	d.scratch[0] = cmdPrefix
-	
+
 	if d.config.EnableDynamicPayload {
 		copy(d.scratch[1:], data)
 		d.spiTransfer(1 + len(data))
@@ -857,36 +1112,14 @@ func (d *Device) write(data []byte, noAck bool) error {
 		copy(d.scratch[1:], data) // Copy up to len(data), rest will be zeros
 		d.spiTransfer(1 + size)
 	}
+}
 
-	d.setCE(true)
-	time.Sleep(15 * time.Microsecond)
-	d.setCE(false)
-
-	// Calculate a safe timeout based on retransmit settings.
-	// (Delay * Count) is the maximum time the hardware will spend retrying.
-	// We add a 50ms safety buffer for SPI communication and OS scheduling.
-	timeoutDuration := time.Duration(d.config.AutoRetransmitDelay)*time.Duration(d.config.AutoRetransmitCount)*time.Microsecond + 50*time.Millisecond
-	timeout := time.After(timeoutDuration)
-
-	for {
-		select {
-		case <-timeout:
-			d.clearStatus()
-			d.flushTX()
-			return fmt.Errorf("%w: %w", ErrPkg, ErrTimeout)
-		default:
-			status := d.readRegister(_STATUS)
-			if status&(_TX_DS|_MAX_RT) != 0 {
-				d.clearStatus()
-				if status&_MAX_RT != 0 {
-					d.flushTX()
-					return fmt.Errorf("%w: %w", ErrPkg, ErrMaxRetries)
-				}
-				return nil
-			}
-			time.Sleep(1 * time.Millisecond)
-		}
+// payloadLimit returns the largest payload that can be transmitted.
+func (d *Device) payloadLimit() int {
+	if !d.config.EnableDynamicPayload {
+		return int(d.config.PayloadSize)
 	}
+	return _MAX_PAYLOAD_BYTES
 }
 
 // Transmit sends a message.
@@ -896,25 +1129,25 @@ func (dev *Device) Transmit(destAddr Address, p []byte) error {
 	dev.mu.Lock()
 	defer dev.mu.Unlock()
 
-	dev.stopListening()
-
-	limit := int(_MAX_PAYLOAD_BYTES)
-	if !dev.config.EnableDynamicPayload {
-		limit = int(dev.config.PayloadSize)
+	if dev.sniffing {
+		return fmt.Errorf("%w: %w", ErrPkg, ErrSnifferActive)
 	}
 
-	if len(p) > limit {
+	if limit := dev.payloadLimit(); len(p) > limit {
 		return fmt.Errorf("%w: payload too large (%d bytes), limit is %d", ErrPkg, len(p), limit)
 	}
 
+	dev.pauseBeacon()
+	dev.verifyPeriodically()
+	dev.stopListening()
 	dev.setTargetAddress(destAddr)
 
 	if err := dev.write(p, false); err != nil {
-		dev.startListening()
+		dev.resume()
 		return fmt.Errorf("failed to send data: %w", err)
 	}
 
-	dev.startListening()
+	dev.resume()
 	return nil
 }
 
@@ -959,25 +1192,25 @@ func (dev *Device) TransmitNoAck(destAddr Address, p []byte) error {
 	dev.mu.Lock()
 	defer dev.mu.Unlock()
 
-	dev.stopListening()
-
-	limit := int(_MAX_PAYLOAD_BYTES)
-	if !dev.config.EnableDynamicPayload {
-		limit = int(dev.config.PayloadSize)
+	if dev.sniffing {
+		return fmt.Errorf("%w: %w", ErrPkg, ErrSnifferActive)
 	}
 
-	if len(p) > limit {
+	if limit := dev.payloadLimit(); len(p) > limit {
 		return fmt.Errorf("%w: payload too large (%d bytes), limit is %d", ErrPkg, len(p), limit)
 	}
 
+	dev.pauseBeacon()
+	dev.verifyPeriodically()
+	dev.stopListening()
 	dev.setTargetAddress(destAddr)
 
 	if err := dev.write(p, true); err != nil {
-		dev.startListening()
+		dev.resume()
 		return fmt.Errorf("failed to send data: %w", err)
 	}
 
-	dev.startListening()
+	dev.resume()
 	return nil
 }
 
@@ -992,6 +1225,7 @@ func (d *Device) SetAddressWidth(width byte) error {
 
 	d.writeRegister(_SETUP_AW, width-2)
 	d.config.AddressWidth = width
+	d.configChanged()
 	return nil
 }
 
@@ -1003,18 +1237,106 @@ func (dev *Device) Receive() ([]byte, bool) {
 	dev.mu.Lock()
 	defer dev.mu.Unlock()
 
-	if dev.config.EnableDynamicPayload {
-		payload, ok := dev.readDynamic()
-		if ok {
-			return payload, true
-		}
+	payload, _, ok := dev.receive()
+	return payload, ok
+}
+
+// ReceiveWithPipe is like Receive but also returns the data pipe (0-5) the packet arrived on.
+// This method is concurrent safe.
+func (dev *Device) ReceiveWithPipe() ([]byte, int, bool) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+
+	return dev.receive()
+}
+
+// ReceiveInto is like ReceiveWithPipe but copies the packet into buf instead of allocating
+// it, and returns its length. A Packet holds any payload: pass pkt[:]. A payload longer than
+// buf is truncated.
+// Without a FrameRecorder, receiving a packet with ReceiveInto does not allocate.
+// This method is concurrent safe.
+func (dev *Device) ReceiveInto(buf []byte) (n int, pipe int, ok bool) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+
+	return dev.receiveInto(buf)
+}
+
+// receive reads the next packet and the pipe it arrived on into a new slice.
+// Call with lock held.
+func (d *Device) receive() ([]byte, int, bool) {
+	var buf Packet
+	n, pipe, ok := d.readPacket(buf[:])
+	if !ok {
+		return nil, 0, false
+	}
+	payload := make([]byte, n)
+	copy(payload, buf[:n])
+	d.received(pipe, payload)
+	return payload, pipe, true
+}
+
+// receiveInto reads the next packet and the pipe it arrived on into buf.
+// Call with lock held.
+func (d *Device) receiveInto(buf []byte) (int, int, bool) {
+	n, pipe, ok := d.readPacket(buf)
+	if !ok {
+		return 0, 0, false
+	}
+	d.received(pipe, buf[:n])
+	return n, pipe, true
+}
+
+// readPacket reads the next packet from the RX FIFO into buf, and returns the number of bytes
+// copied and the pipe it arrived on.
+// Call with lock held.
+func (d *Device) readPacket(buf []byte) (int, int, bool) {
+	if !d.receiving() {
+		return 0, 0, false
+	}
+	d.verifyPeriodically()
+
+	pipe, ok := d.available()
+	if !ok {
+		return 0, 0, false
+	}
+	// While the RX FIFO is full, the radio drops the packets it receives
+	if d.readRegister(_FIFO_STATUS)&_RX_FULL != 0 {
+		d.stats.RxFIFOFull++
+	}
+
+	n, ok := d.readPayload(buf)
+	return n, pipe, ok
+}
+
+// readPayload reads the packet at the head of the RX FIFO into buf, then clears RX_DR, in the
+// order recommended by the datasheet. TX_DS and MAX_RT are left alone.
+// Call with lock held.
+func (d *Device) readPayload(buf []byte) (int, bool) {
+	var payload []byte
+	ok := true
+	if d.config.EnableDynamicPayload {
+		payload, ok = d.readDynamic()
 	} else {
-		payload, ok := dev.readFixedPayload()
-		if ok {
-			return payload, true
-		}
+		payload = d.readFixedPayload()
+	}
+	if !ok {
+		return 0, false
+	}
+	// Copy the payload BEFORE clearing RX_DR, which reuses scratch
+	n := copy(buf, payload)
+	d.writeRegister(_STATUS, _RX_DR)
+	return n, true
+}
+
+// received counts, records and reports a packet read from the RX FIFO.
+// Call with lock held.
+func (d *Device) received(pipe int, payload []byte) {
+	d.stats.RxPackets[pipe]++
+	d.recordRX(pipe, payload)
+	if d.observer != nil {
+		d.observer.RxPacket(pipe, payload)
 	}
-	return nil, false
 }
 
 // WaitForInterrupt blocks until the IRQ pin goes low (active) or the context is cancelled.
@@ -1028,47 +1350,86 @@ func (d *Device) WaitForInterrupt(ctx context.Context) (byte, error) {
 
 	// Check if interrupt is already active (low = false)
 	if d.config.IRQ.Read() == Low {
-		d.mu.Lock()
-		status := d.readRegister(_STATUS)
-		d.mu.Unlock()
-		return status, nil
+		return d.interruptStatus(), nil
 	}
 
 	// Wait for signal from the Watch callback or context
 	select {
 	case <-d.irqChan:
-		d.mu.Lock()
-		status := d.readRegister(_STATUS)
-		d.mu.Unlock()
-		return status, nil
+		return d.interruptStatus(), nil
 	case <-ctx.Done():
 		return 0, ctx.Err()
 	}
 }
 
+// interruptStatus reads the STATUS register after an interrupt and notifies the observer.
+func (d *Device) interruptStatus() byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status := d.readRegister(_STATUS)
+	if d.observer != nil {
+		d.observer.IRQ(status)
+	}
+	return status
+}
+
 // ReceiveBlocking waits for a packet to arrive or for the context to be cancelled.
 // It blocks efficiently using the IRQ pin if configured, or falls back to polling.
 // This method is concurrent safe.
 func (d *Device) ReceiveBlocking(ctx context.Context) ([]byte, error) {
+	data, _, err := d.ReceiveBlockingWithPipe(ctx)
+	return data, err
+}
+
+// ReceiveBlockingWithPipe is like ReceiveBlocking but also returns the data pipe (0-5)
+// the packet arrived on.
+// This method is concurrent safe.
+func (d *Device) ReceiveBlockingWithPipe(ctx context.Context) ([]byte, int, error) {
+	var data []byte
+	var pipe int
+	err := d.waitReceive(ctx, func() bool {
+		var ok bool
+		data, pipe, ok = d.ReceiveWithPipe() // ReceiveWithPipe is already thread-safe
+		return ok
+	})
+	return data, pipe, err
+}
+
+// ReceiveBlockingInto is like ReceiveBlockingWithPipe but copies the packet into buf, like
+// ReceiveInto.
+// With an IRQ pin and without a FrameRecorder, waiting for and receiving a packet with
+// ReceiveBlockingInto does not allocate.
+// This method is concurrent safe.
+func (d *Device) ReceiveBlockingInto(ctx context.Context, buf []byte) (n int, pipe int, err error) {
+	err = d.waitReceive(ctx, func() bool {
+		var ok bool
+		n, pipe, ok = d.ReceiveInto(buf)
+		return ok
+	})
+	return n, pipe, err
+}
+
+// waitReceive calls receive until it gets a packet, waiting for the IRQ pin or polling
+// between the calls, or until the context is cancelled.
+func (d *Device) waitReceive(ctx context.Context, receive func() bool) error {
 	for {
 		// Check for cancellation
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		default:
 		}
 
 		// 1. Check if data is already available
-		data, ok := d.Receive() // Receive is already thread-safe
-		if ok {
-			return data, nil
+		if receive() {
+			return nil
 		}
 
 		// 2. Wait for data
 		if d.config.IRQ != nil {
 			status, err := d.WaitForInterrupt(ctx)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			
 			// Check if it was RX_DR (Data Ready)
@@ -1083,13 +1444,13 @@ func (d *Device) ReceiveBlocking(ctx context.Context) ([]byte, error) {
 			// Check context cancellation before sleeping
 			select {
 			case <-ctx.Done():
-				return nil, ctx.Err()
+				return ctx.Err()
 			default:
 				// Fall through
 			}
 			
 			// Use Sleep instead of time.NewTimer/time.After to avoid heap allocation overhead
-			time.Sleep(5 * time.Millisecond)
+			d.config.Clock.Sleep(5 * time.Millisecond)
 		}
 	}
 }
@@ -1109,6 +1470,14 @@ func (d *Device) Ping(_ context.Context, addr Address) (bool, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	// Transmitting would overwrite the listening address of the sniffer
+	if d.sniffing {
+		return false, fmt.Errorf("%w: %w", ErrPkg, ErrSnifferActive)
+	}
+
+	d.pauseBeacon()
+	defer d.resumeBeacon()
+
 	// 1. Set the target address
 	d.setTargetAddress(Address(addr))
 
@@ -1118,10 +1487,10 @@ func (d *Device) Ping(_ context.Context, addr Address) (bool, error) {
 	err := d.write([]byte{0x00}, false)
 
 	if err == nil {
-		globalLogger.Info("Ping Success")
+		d.log(LogInfo, "Ping Success", Field{"address", addr})
 		return true, nil
 	}
 	
-	globalLogger.Info("Ping Failed")
+	d.log(LogInfo, "Ping Failed", Field{"address", addr}, Field{"error", err})
 	return false, nil
 }
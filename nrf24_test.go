@@ -2,8 +2,12 @@ package nrf24
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"os"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -149,23 +153,21 @@ func TestTransmit(t *testing.T) {
 	mockSPI.tx = nil
 
 	// Simulation sequence for Transmit:
-	// 1. stopListening() -> read(_CONFIG), write(_CONFIG)
+	// 1. stopListening() -> write(_CONFIG) (CONFIG comes from the shadow, no read)
 	// 2. setTargetAddress() -> write(_TX_ADDR), write(_RX_ADDR_P0)
-	// 3. write() -> stopListening() again -> read(_CONFIG), write(_CONFIG)
+	// 3. write() -> stopListening() again -> write(_CONFIG)
 	// 4. write() -> W_TX_PAYLOAD
 	// 5. write() loop -> read(_STATUS) -> MUST RETURN TX_DS (0x20)
 	
 	// Queue dummy responses for the setup steps
-	mockSPI.queueRx([]byte{0, 0}) // 1. Read Config
-	mockSPI.queueRx([]byte{0})    // 2. Write Config
-	mockSPI.queueRx([]byte{0})    // 3. Write TX Addr
-	mockSPI.queueRx([]byte{0})    // 4. Write RX Addr
-	mockSPI.queueRx([]byte{0, 0}) // 5. Read Config (write calls stopListening again)
-	mockSPI.queueRx([]byte{0})    // 6. Write Config
-	mockSPI.queueRx([]byte{0})    // 7. Write Payload
+	mockSPI.queueRx([]byte{0}) // 1. Write Config
+	mockSPI.queueRx([]byte{0}) // 2. Write TX Addr
+	mockSPI.queueRx([]byte{0}) // 3. Write RX Addr
+	mockSPI.queueRx([]byte{0}) // 4. Write Config (write calls stopListening again)
+	mockSPI.queueRx([]byte{0}) // 5. Write Payload
 	
 	// Queue the SUCCESS status
-	mockSPI.queueRx([]byte{0, 0x20}) // 8. Read Status (returns 0x20)
+	mockSPI.queueRx([]byte{0, 0x20}) // 6. Read Status (returns 0x20)
 
 	// Queue responses for the cleanup (clearStatus, startListening)
 	// If we don't queue these, they get zeros, which is fine, but good to be explicit or loose.
@@ -202,7 +204,7 @@ func TestTransmitFailure(t *testing.T) {
 	mockSPI.tx = nil
 	mockSPI.rxQueue = nil
 	// Dummies for Transmit setup
-	for i := 0; i < 7; i++ {
+	for i := 0; i < 5; i++ {
 		mockSPI.queueRx([]byte{0})
 	}
 	// Return MAX_RT status
@@ -219,8 +221,8 @@ func TestTransmitFailure(t *testing.T) {
 	// Test Case 2: Timeout (Device unresponsive - returns 0)
 	mockSPI.tx = nil
 	mockSPI.rxQueue = nil
-	// Dummies for Transmit setup
-	for i := 0; i < 7; i++ {
+	// Dummies for Transmit setup (the target address is unchanged, so it is not written again)
+	for i := 0; i < 3; i++ {
 		mockSPI.queueRx([]byte{0})
 	}
 	// Note: If no responses queued, mock returns 0.
@@ -256,17 +258,21 @@ func TestReceive(t *testing.T) {
 	//    Cmd: [STATUS, NOP] -> Resp: [0, 0x40] (Wait, readRegister returns byte 1)
 	mockSPI.queueRx([]byte{0x00, 0x40})
 
-	// 2. readDynamic() -> getDynamicPayloadSize()
+	// 2. receive() -> reads FIFO_STATUS to count RX FIFO overflows.
+	//    Resp: [Status, 0x02] (RX_FULL)
+	mockSPI.queueRx([]byte{0x40, 0x02})
+
+	// 3. readDynamic() -> getDynamicPayloadSize()
 	//    Cmd: [R_RX_PL_WID, NOP] -> Resp: [Status, Size]
 	//    Let's say payload is "world" (5 bytes).
 	mockSPI.queueRx([]byte{0x40, 0x05})
 
-	// 3. readDynamic() -> read payload
+	// 4. readDynamic() -> read payload
 	//    Cmd: [R_RX_PAYLOAD, NOP, NOP, NOP, NOP, NOP]
 	//    Resp: [Status, 'w', 'o', 'r', 'l', 'd']
 	mockSPI.queueRx([]byte{0x40, 'w', 'o', 'r', 'l', 'd'})
 	
-	// 4. clearStatus() -> writeRegister(STATUS, ...)
+	// 5. clearStatus() -> writeRegister(STATUS, ...)
 	//    Returns status (ignored).
 	mockSPI.queueRx([]byte{0x00, 0x00})
 
@@ -277,6 +283,9 @@ func TestReceive(t *testing.T) {
 	if string(data) != "world" {
 		t.Errorf("Expected payload 'world', got '%s'", string(data))
 	}
+	if stats := dev.Stats(); stats.RxPackets[0] != 1 || stats.RxFIFOFull != 1 {
+		t.Errorf("Expected 1 packet on pipe 0 read from a full FIFO, got %+v", stats)
+	}
 }
 
 func TestConfiguration(t *testing.T) {
@@ -316,12 +325,9 @@ func TestOpenRxPipe(t *testing.T) {
 	dev, _ := NewWithHardware(cfg, mockSPI)
 
 	// Test Pipe 1 (Full Address)
+	// OpenRxPipe updates DYNPD, EN_RXADDR and EN_AA from the shadow, without reading them
 	mockSPI.tx = nil
 	mockSPI.rxQueue = nil
-	// OpenRxPipe reads: DYNPD, EN_RXADDR, EN_AA
-	mockSPI.queueRx([]byte{0, 0}) // Read DYNPD
-	mockSPI.queueRx([]byte{0, 0}) // Read EN_RXADDR
-	mockSPI.queueRx([]byte{0, 0}) // Read EN_AA
 	
 	addr := []byte{0xA1, 0xA2, 0xA3, 0xA4, 0xA5}
 	dev.OpenRxPipe(1, addr)
@@ -334,10 +340,6 @@ func TestOpenRxPipe(t *testing.T) {
 	// Test Pipe 2 (LSB only)
 	mockSPI.tx = nil
 	mockSPI.rxQueue = nil
-	// OpenRxPipe reads: DYNPD, EN_RXADDR, EN_AA
-	mockSPI.queueRx([]byte{0, 0}) // Read DYNPD
-	mockSPI.queueRx([]byte{0, 0}) // Read EN_RXADDR
-	mockSPI.queueRx([]byte{0, 0}) // Read EN_AA
 	
 	dev.OpenRxPipe(2, []byte{0xCC})
 	
@@ -345,9 +347,10 @@ func TestOpenRxPipe(t *testing.T) {
 	if !bytes.Contains(mockSPI.tx, []byte{0x2C, 0xCC}) {
 		t.Errorf("OpenRxPipe(2) didn't write LSB correctly: %X", mockSPI.tx)
 	}
-	// Should enable bit 2 in EN_RXADDR (0x02) and EN_AA (0x01)
-	// Write Command for EN_RXADDR: 0x22. Value: 0x04 (bit 2).
-	if !bytes.Contains(mockSPI.tx, []byte{0x22, 0x04}) {
+	// Should enable bit 2 in EN_RXADDR (0x02) and EN_AA (0x01), next to pipes 0 and 1
+	// enabled at initialization.
+	// Write Command for EN_RXADDR: 0x22. Value: 0x07 (bits 0-2).
+	if !bytes.Contains(mockSPI.tx, []byte{0x22, 0x07}) {
 		t.Errorf("OpenRxPipe(2) didn't enable pipe in EN_RXADDR: %X", mockSPI.tx)
 	}
 }
@@ -368,12 +371,15 @@ func TestReceiveFixed(t *testing.T) {
 	// 1. available() -> reads STATUS. Expects _RX_DR (0x40).
 	mockSPI.queueRx([]byte{0x00, 0x40})
 
-	// 2. readFixedPayload() -> R_RX_PAYLOAD.
+	// 2. receive() -> reads FIFO_STATUS.
+	mockSPI.queueRx([]byte{0x40, 0x00})
+
+	// 3. readFixedPayload() -> R_RX_PAYLOAD.
 	//    Cmd: [R_RX_PAYLOAD, NOP, NOP, NOP, NOP, NOP] (Length 5)
 	//    Resp: [Status, 'h', 'e', 'l', 'l', 'o']
 	mockSPI.queueRx([]byte{0x40, 'h', 'e', 'l', 'l', 'o'})
 	
-	// 3. clearStatus() -> writeRegister(STATUS, ...)
+	// 4. clearStatus() -> writeRegister(STATUS, ...)
 	mockSPI.queueRx([]byte{0x00, 0x00})
 
 	data, found := dev.Receive()
@@ -394,26 +400,59 @@ func TestCloseRxPipe(t *testing.T) {
 	mockSPI.tx = nil
 	mockSPI.rxQueue = nil
 
-	// Pre-condition: Assume bits are set. readRegister returns values with bits set.
-	// CloseRxPipe reads EN_RXADDR and EN_AA.
-	// We simulate they are currently 0xFF (all pipes enabled).
-	// But we also need to account for the writes consuming from the queue!
-	
-	mockSPI.queueRx([]byte{0, 0xFF}) // 1. Read EN_RXADDR -> returns 0xFF
-	mockSPI.queueRx([]byte{0})       // 2. Write EN_RXADDR -> dummy
-	mockSPI.queueRx([]byte{0, 0xFF}) // 3. Read EN_AA -> returns 0xFF
-	mockSPI.queueRx([]byte{0})       // 4. Write EN_AA -> dummy
-	
+	// CloseRxPipe updates EN_RXADDR and EN_AA from the shadow: the values written by the
+	// initialization (pipes 0 and 1) and OpenRxPipe (pipe 2).
+	dev.OpenRxPipe(2, []byte{0xCC})
+	mockSPI.tx = nil
+
 	dev.CloseRxPipe(2)
-	
-	// Should clear bit 2 (0x04). Result 0xFB.
-	// Write EN_RXADDR (0x22) -> 0xFB
-	if !bytes.Contains(mockSPI.tx, []byte{0x22, 0xFB}) {
-		t.Errorf("CloseRxPipe(2) didn't clear EN_RXADDR correctly: %X", mockSPI.tx)
+
+	// Should clear bit 2 (0x04), without any read. Result 0x03.
+	// Write EN_RXADDR (0x22) -> 0x03, then EN_AA (0x21) -> 0x03
+	if !bytes.Equal(mockSPI.tx, []byte{0x22, 0x03, 0x21, 0x03}) {
+		t.Errorf("CloseRxPipe(2) didn't clear EN_RXADDR and EN_AA correctly: %X", mockSPI.tx)
 	}
-	// Write EN_AA (0x21) -> 0xFB
-	if !bytes.Contains(mockSPI.tx, []byte{0x21, 0xFB}) {
-		t.Errorf("CloseRxPipe(2) didn't clear EN_AA correctly: %X", mockSPI.tx)
+}
+
+// ceSPI records the command and the level of CE of each SPI transaction.
+type ceSPI struct {
+	mockSPIConn
+	ce     *mockPin
+	cmds   []byte
+	levels []Level
+}
+
+func (m *ceSPI) Tx(w, r []byte) error {
+	m.cmds = append(m.cmds, w[0])
+	m.levels = append(m.levels, m.ce.level)
+	return m.mockSPIConn.Tx(w, r)
+}
+
+func TestStartListeningFlushesFirst(t *testing.T) {
+	mockCE := &mockPin{}
+	mockSPI := &ceSPI{ce: mockCE}
+	dev, err := NewWithHardware(HardwareConfig{CE: mockCE}, mockSPI)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+
+	// Packets received once CE is high in RX mode must survive the switch:
+	// stale packets are flushed in standby, before PRIM_RX is set and CE raised
+	dev.stopListening()
+	mockSPI.cmds, mockSPI.levels = nil, nil
+	dev.startListening()
+	flush := slices.Index(mockSPI.cmds, _FLUSH_RX)
+	config := slices.Index(mockSPI.cmds, 0x20|_CONFIG)
+	if flush < 0 || config < 0 || flush > config {
+		t.Errorf("Expected FLUSH_RX before the CONFIG write, got commands %X", mockSPI.cmds)
+	}
+	for i, l := range mockSPI.levels {
+		if l != Low {
+			t.Errorf("Expected CE low during transaction %d, before entering RX mode", i)
+		}
+	}
+	if mockCE.level != High {
+		t.Error("Expected CE high in RX mode")
 	}
 }
 
@@ -473,8 +512,8 @@ func TestDiagnostics(t *testing.T) {
 	// Verify it sends command 0xB0 instead of 0xA0
 	mockSPI.tx = nil
 	mockSPI.rxQueue = nil
-	// Queue 7 dummies for setup (stopListening, setTargetAddress, write setup) + 1 for status
-	for i := 0; i < 7; i++ {
+	// Queue 5 dummies for setup (stopListening, setTargetAddress, write setup) + 1 for status
+	for i := 0; i < 5; i++ {
 		mockSPI.queueRx([]byte{0})
 	}
 	mockSPI.queueRx([]byte{0, 0x20}) // Status Success
@@ -486,3 +525,117 @@ func TestDiagnostics(t *testing.T) {
 		t.Errorf("TransmitNoAck didn't send 0xB0 command. TX: %X", mockSPI.tx)
 	}
 }
+
+// rxSPI is an SPI connection that always has a packet on pipe 1, without allocating.
+type rxSPI struct {
+	payload []byte
+}
+
+func (s *rxSPI) Tx(w, r []byte) error {
+	cmd := w[0]
+	r[0] = _RX_DR | 1<<1
+	switch {
+	case cmd == _STATUS:
+		r[1] = _RX_DR | 1<<1
+	case cmd == _R_RX_PL_WID:
+		r[1] = byte(len(s.payload))
+	case cmd == _R_RX_PAYLOAD:
+		copy(r[1:], s.payload)
+	case cmd < _W_REGISTER:
+		r[1] = 0
+	}
+	return nil
+}
+
+func TestReceiveInto(t *testing.T) {
+	conn := &rxSPI{payload: []byte("ping")}
+	dev, err := NewWithHardware(HardwareConfig{
+		RadioConfig: RadioConfig{EnableDynamicPayload: true},
+		CE:          &mockPin{},
+		IRQ:         &mockPin{},
+	}, conn)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+
+	var pkt Packet
+	n, pipe, ok := dev.ReceiveInto(pkt[:])
+	if !ok || pipe != 1 || string(pkt[:n]) != "ping" {
+		t.Errorf("ReceiveInto() = %q, %d, %v, want \"ping\", 1", pkt[:n], pipe, ok)
+	}
+	short := make([]byte, 2)
+	if n, _, _ := dev.ReceiveInto(short); n != 2 || string(short) != "pi" {
+		t.Errorf("Expected the payload to be truncated to the buffer, got %q", short[:n])
+	}
+
+	allocs := testing.AllocsPerRun(100, func() { dev.ReceiveInto(pkt[:]) })
+	if allocs != 0 {
+		t.Errorf("ReceiveInto allocated %v times per packet", allocs)
+	}
+	ctx := context.Background()
+	allocs = testing.AllocsPerRun(100, func() { dev.ReceiveBlockingInto(ctx, pkt[:]) })
+	if allocs != 0 {
+		t.Errorf("ReceiveBlockingInto allocated %v times per packet", allocs)
+	}
+	if got := dev.Stats().RxPackets[1]; got != 204 {
+		t.Errorf("Expected 204 packets on pipe 1, got %d", got)
+	}
+}
+
+func TestScanChannel(t *testing.T) {
+	mockSPI := &mockSPIConn{}
+	mockCE := &mockPin{}
+	dev, err := NewWithHardware(HardwareConfig{CE: mockCE}, mockSPI)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+
+	// In standby with PRIM_RX cleared, as after a Ping, raising CE would transmit
+	dev.stopListening()
+	mockSPI.tx = nil
+	if _, err := dev.ScanChannel(10, 0); err != nil {
+		t.Fatalf("ScanChannel failed: %v", err)
+	}
+	want := []byte{0x20 | _CONFIG, 0x0F, 0x20 | _RF_CH, 10, _RPD, _NOP, 0x20 | _RF_CH, 0, 0x20 | _CONFIG, 0x0E}
+	if !bytes.Equal(mockSPI.tx, want) {
+		t.Errorf("Expected ScanChannel to listen in RX mode, then restore TX mode: got %X, want %X", mockSPI.tx, want)
+	}
+	if mockCE.level != Low || dev.mode != ModeStandby {
+		t.Errorf("Expected CE low in standby after the scan, got CE %v, mode %v", mockCE.level, dev.mode)
+	}
+
+	dev.sniffing = true
+	if _, err := dev.ScanChannel(10, 0); !errors.Is(err, ErrSnifferActive) {
+		t.Errorf("Expected ErrSnifferActive while sniffing, got %v", err)
+	}
+	dev.sniffing = false
+	dev.beacon = &beacon{}
+	if _, err := dev.ScanChannel(10, 0); !errors.Is(err, ErrBeaconActive) {
+		t.Errorf("Expected ErrBeaconActive while sending a beacon, got %v", err)
+	}
+}
+
+func TestBeaconStepAfterReload(t *testing.T) {
+	dev, _ := NewWithHardware(HardwareConfig{CE: &mockPin{}}, &mockSPIConn{})
+	if err := dev.StartBeacon(Address{1, 2, 3, 4, 5}, []byte("beacon"), time.Hour); err != nil {
+		t.Fatalf("StartBeacon failed: %v", err)
+	}
+	b, load := dev.beacon, dev.beaconLoads
+
+	// A reload while runBeacon does not hold the lock makes its pending step stale
+	if err := dev.SetBeaconPayload([]byte("v2")); err != nil {
+		t.Fatalf("SetBeaconPayload failed: %v", err)
+	}
+	ran := false
+	if running, current := dev.beaconStep(b, load, func() { ran = true }); !running || current || ran {
+		t.Errorf("Expected a stale step to be skipped, got running %v, current %v, ran %v", running, current, ran)
+	}
+	if running, current := dev.beaconStep(b, dev.beaconLoads, func() { ran = true }); !running || !current || !ran {
+		t.Errorf("Expected the step to run, got running %v, current %v, ran %v", running, current, ran)
+	}
+
+	dev.StopBeacon()
+	if running, _ := dev.beaconStep(b, dev.beaconLoads, func() {}); running {
+		t.Error("Expected the beacon to be stopped")
+	}
+}
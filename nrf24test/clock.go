@@ -0,0 +1,50 @@
+package nrf24test
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Clock is a virtual nrf24.Clock: Sleep returns at once after advancing Now by the delay,
+// so that a Device runs its power-up, CE pulses and TX polling in virtual time and tests
+// run as fast as the SPI traffic allows. Clock is concurrent safe.
+type Clock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept time.Duration
+}
+
+// NewClock creates a clock starting at the given time.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) Sleep(d time.Duration) {
+	c.Advance(d)
+	// Let the other goroutines run, as a real Sleep would, e.g. while polling
+	runtime.Gosched()
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+		c.slept += d
+	}
+}
+
+// Slept returns the total time the clock was advanced by.
+func (c *Clock) Slept() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slept
+}
@@ -0,0 +1,214 @@
+package nrf24test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+)
+
+var peer = nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}
+
+func newDevice(t *testing.T) (*nrf24.Device, *Radio) {
+	t.Helper()
+	r := NewRadio()
+	dev, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	return dev, r
+}
+
+func TestConfigurationWrites(t *testing.T) {
+	_, r := newDevice(t)
+	r.SPI.ExpectWrite(0x05, 76)   // RF_CH
+	r.SPI.ExpectWrite(0x1C, 0x03) // DYNPD on pipes 0 and 1
+	if err := r.SPI.Verify(); err != nil {
+		t.Error(err)
+	}
+	if got := r.SPI.Register(0x05); got != 76 {
+		t.Errorf("RF_CH = %d, want 76", got)
+	}
+
+	r.SPI.ExpectWrite(0x05, 99)
+	if err := r.SPI.Verify(); err == nil {
+		t.Error("Expected an unmet expectation")
+	}
+}
+
+func TestTransmit(t *testing.T) {
+	dev, r := newDevice(t)
+	r.CE.ResetTransitions()
+
+	if err := dev.Transmit(peer, []byte("hi")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	payloads := r.SPI.TxPayloads()
+	if len(payloads) != 1 || string(payloads[0].Data) != "hi" || payloads[0].NoAck {
+		t.Errorf("TxPayloads() = %v, want one acknowledged \"hi\"", payloads)
+	}
+	if addr := r.SPI.RegisterBytes(0x10); !bytes.Equal(addr, peer[:]) {
+		t.Errorf("TX_ADDR = %X, want %X", addr, peer[:])
+	}
+	// One CE pulse of at least 10µs starts the transmission
+	pulses := r.CE.Pulses()
+	if len(pulses) == 0 || pulses[0] < 10*time.Microsecond {
+		t.Errorf("Expected a CE pulse of at least 10µs, got %v", pulses)
+	}
+	if levels := r.CE.Levels(); levels[len(levels)-1] != nrf24.High {
+		t.Error("Expected CE high (listening) after the transmission")
+	}
+
+	r.SPI.QueueTxResult(TxMaxRetries)
+	if err := dev.Transmit(peer, []byte("hi")); !errors.Is(err, nrf24.ErrMaxRetries) {
+		t.Errorf("Expected ErrMaxRetries, got %v", err)
+	}
+	r.SPI.QueueTxResult(TxNoResponse)
+	if err := dev.Transmit(peer, []byte("hi")); !errors.Is(err, nrf24.ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+	if err := dev.Transmit(peer, []byte("hi")); err != nil {
+		t.Errorf("Expected the default result to be acknowledged, got %v", err)
+	}
+}
+
+func TestTransmitOnCERisingEdge(t *testing.T) {
+	s := NewSPI()
+	s.Tx([]byte{0x20, 0x0C}, make([]byte, 2)) // CONFIG: EN_CRC, CRCO, powered down
+	s.Tx([]byte{0xA0, 'h', 'i'}, make([]byte, 3))
+	s.SetCE(true)
+	if s.Status()&TxDS != 0 {
+		t.Fatal("Expected no transmission while powered down")
+	}
+	s.Tx([]byte{0x20, 0x0E}, make([]byte, 2)) // PWR_UP while CE is high
+	if s.Status()&TxDS == 0 {
+		t.Fatal("Expected a transmission on power up with CE high")
+	}
+	s.SetCE(false)
+
+	// A reused payload is sent again on every CE pulse, and REUSE_TX_PL alone sends nothing
+	s.Tx([]byte{0x27, 0x70}, make([]byte, 2)) // clear STATUS
+	s.QueueTxResult(TxAcked, TxMaxRetries)
+	s.Tx([]byte{0xE1}, make([]byte, 1)) // FLUSH_TX
+	s.Tx([]byte{0xB0, 'b'}, make([]byte, 2))
+	s.Tx([]byte{0xE3}, make([]byte, 1)) // REUSE_TX_PL
+	if s.Status()&TxDS != 0 {
+		t.Fatal("Expected no transmission on REUSE_TX_PL")
+	}
+	s.SetCE(true)
+	s.SetCE(false)
+	if s.Status()&TxDS == 0 || s.Register(0x17)&0x50 != 0x40 {
+		t.Fatalf("Expected TX_DS with the payload kept for reuse, got STATUS=%02X FIFO_STATUS=%02X", s.Status(), s.Register(0x17))
+	}
+	// The NOACK payload completes with TX_DS whatever the queued result
+	s.Tx([]byte{0x27, 0x70}, make([]byte, 2))
+	s.SetCE(true)
+	s.SetCE(false)
+	if s.Status()&(TxDS|MaxRT) != TxDS {
+		t.Errorf("STATUS = %02X, want TX_DS after the second pulse", s.Status())
+	}
+}
+
+func TestVirtualTime(t *testing.T) {
+	start := time.Now()
+	dev, r := newDevice(t)
+	r.SPI.QueueTxResult(TxNoResponse)
+	if err := dev.Transmit(peer, []byte("hi")); !errors.Is(err, nrf24.ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+	// The power-up delay and the 50ms TX timeout only advance the virtual clock
+	if slept := r.Clock.Slept(); slept < 55*time.Millisecond {
+		t.Errorf("Expected at least 55ms of virtual time, got %v", slept)
+	}
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("Expected the TX timeout not to take real time, took %v", elapsed)
+	}
+}
+
+func TestReceiveWithIRQ(t *testing.T) {
+	dev, r := newDevice(t)
+	if r.IRQ.Watched() != nrf24.FallingEdge {
+		t.Fatalf("Expected the driver to watch the falling edge of IRQ, got %v", r.IRQ.Watched())
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.SPI.QueueRx(2, []byte("hello"))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, pipe, err := dev.ReceiveBlockingWithPipe(ctx)
+	if err != nil || string(data) != "hello" || pipe != 2 {
+		t.Errorf("ReceiveBlockingWithPipe() = %q, %d, %v, want \"hello\", 2", data, pipe, err)
+	}
+	if r.IRQ.Read() != nrf24.High {
+		t.Error("Expected IRQ to be released once RX_DR is cleared")
+	}
+
+	// The RX FIFO holds 3 packets
+	for i := 0; i < 3; i++ {
+		if !r.SPI.QueueRx(0, []byte{byte(i)}) {
+			t.Fatalf("QueueRx %d failed", i)
+		}
+	}
+	if r.SPI.QueueRx(0, []byte{3}) {
+		t.Error("Expected the 4th packet to be dropped")
+	}
+}
+
+func TestErrorInjection(t *testing.T) {
+	dev, r := newDevice(t)
+	errSPI := errors.New("bus error")
+	r.SPI.FailTx(0, errSPI)
+	if _, ok := dev.Receive(); ok {
+		t.Error("Expected no packet while the SPI fails")
+	}
+	r.SPI.FailTx(0, nil)
+	r.SPI.QueueRx(0, []byte("ok"))
+	if data, ok := dev.Receive(); !ok || string(data) != "ok" {
+		t.Errorf("Receive() = %q, %v, want \"ok\"", data, ok)
+	}
+
+	// The verification of the connection reads RF_CH back
+	r = NewRadio()
+	r.SPI.FailTx(3, errSPI)
+	if _, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76}); err == nil {
+		t.Error("Expected NewDevice to fail")
+	}
+
+	r = NewRadio()
+	r.IRQ.FailWatch(errors.New("no edge detection"))
+	if _, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76}); err == nil {
+		t.Error("Expected NewDevice to fail without IRQ edge detection")
+	}
+}
+
+func TestPinEdges(t *testing.T) {
+	p := NewPin(nrf24.Low)
+	calls := 0
+	p.Watch(nrf24.RisingEdge, func() { calls++ })
+	p.SetLevel(nrf24.High)
+	p.SetLevel(nrf24.Low)
+	p.SetLevel(nrf24.Low)
+	if calls != 1 {
+		t.Errorf("Expected 1 rising edge, got %d", calls)
+	}
+	if !p.Trigger() || calls != 2 {
+		t.Error("Expected Trigger to call the handler")
+	}
+	p.Unwatch()
+	if p.Trigger() {
+		t.Error("Expected Trigger to fail after Unwatch")
+	}
+
+	p.FailOut(errors.New("stuck"))
+	if err := p.Out(nrf24.High); err == nil {
+		t.Error("Expected Out to fail")
+	}
+	if len(p.Levels()) != 0 {
+		t.Error("Expected failed Out calls not to be recorded")
+	}
+}
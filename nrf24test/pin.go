@@ -0,0 +1,226 @@
+package nrf24test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/michcald/nrf24"
+)
+
+// Transition is a level set on a Pin with Out, timestamped with the Clock of the Radio of
+// the pin, or the system time for a pin created with NewPin.
+type Transition struct {
+	Level nrf24.Level
+	Time  time.Time
+}
+
+// Pin is a scriptable GPIO pin implementing nrf24.Pin. It records the levels set with Out,
+// for assertions on CE pulses, and calls the Watch handler on the edges of the levels set
+// with SetLevel, or on Trigger.
+//
+// The zero value is a low input. Pin is concurrent safe.
+type Pin struct {
+	mu          sync.Mutex
+	level       nrf24.Level
+	output      bool
+	pull        nrf24.Pull
+	transitions []Transition
+	edge        nrf24.Edge
+	handler     func()
+	clock       nrf24.Clock
+	onOut       func(nrf24.Level) // called without the lock after every Out
+
+	outErr, inErr, watchErr error
+}
+
+// NewPin creates a pin at the given level.
+func NewPin(level nrf24.Level) *Pin {
+	return &Pin{level: level}
+}
+
+func (p *Pin) Out(l nrf24.Level) error {
+	p.mu.Lock()
+	if p.outErr != nil {
+		err := p.outErr
+		p.mu.Unlock()
+		return err
+	}
+	p.output = true
+	p.level = l
+	p.transitions = append(p.transitions, Transition{Level: l, Time: p.now()})
+	onOut := p.onOut
+	p.mu.Unlock()
+
+	if onOut != nil {
+		onOut(l)
+	}
+	return nil
+}
+
+func (p *Pin) In(pull nrf24.Pull) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inErr != nil {
+		return p.inErr
+	}
+	p.output = false
+	if pull != nrf24.PullNoChange {
+		p.pull = pull
+	}
+	return nil
+}
+
+func (p *Pin) Read() nrf24.Level {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.level
+}
+
+func (p *Pin) Watch(edge nrf24.Edge, handler func()) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.watchErr != nil {
+		return p.watchErr
+	}
+	p.edge, p.handler = edge, handler
+	return nil
+}
+
+func (p *Pin) Unwatch() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.edge, p.handler = nrf24.NoEdge, nil
+	return nil
+}
+
+// now returns the time of the clock of the Radio of the pin, if any.
+// Call with lock held.
+func (p *Pin) now() time.Time {
+	if p.clock != nil {
+		return p.clock.Now()
+	}
+	return time.Now()
+}
+
+// SetLevel changes the level of the pin, as driven by the other side, and calls the Watch
+// handler if the change is a watched edge.
+func (p *Pin) SetLevel(l nrf24.Level) {
+	p.mu.Lock()
+	old := p.level
+	p.level = l
+	handler := p.handler
+	watched := false
+	switch p.edge {
+	case nrf24.RisingEdge:
+		watched = old == nrf24.Low && l == nrf24.High
+	case nrf24.FallingEdge:
+		watched = old == nrf24.High && l == nrf24.Low
+	case nrf24.BothEdges:
+		watched = old != l
+	}
+	p.mu.Unlock()
+
+	if watched && handler != nil {
+		handler()
+	}
+}
+
+// Trigger calls the Watch handler, as if the watched edge happened.
+// It returns false if the pin is not watched.
+func (p *Pin) Trigger() bool {
+	p.mu.Lock()
+	handler := p.handler
+	p.mu.Unlock()
+	if handler == nil {
+		return false
+	}
+	handler()
+	return true
+}
+
+// Watched returns the edge watched with Watch, or NoEdge.
+func (p *Pin) Watched() nrf24.Edge {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.edge
+}
+
+// IsOutput reports whether the pin was last configured as an output.
+func (p *Pin) IsOutput() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.output
+}
+
+// Pull returns the last pull mode set with In.
+func (p *Pin) Pull() nrf24.Pull {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pull
+}
+
+// Transitions returns the levels set with Out, in order.
+func (p *Pin) Transitions() []Transition {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Transition(nil), p.transitions...)
+}
+
+// Levels returns the levels set with Out, in order, e.g. to compare CE sequences.
+func (p *Pin) Levels() []nrf24.Level {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	levels := make([]nrf24.Level, len(p.transitions))
+	for i, t := range p.transitions {
+		levels[i] = t.Level
+	}
+	return levels
+}
+
+// Pulses returns the duration of every completed high pulse set with Out (low to high
+// to low), e.g. to check that the CE pulses of transmissions last at least 10µs.
+func (p *Pin) Pulses() []time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var pulses []time.Duration
+	var rise time.Time
+	high := false
+	for _, t := range p.transitions {
+		switch {
+		case t.Level == nrf24.High && !high:
+			rise, high = t.Time, true
+		case t.Level == nrf24.Low && high:
+			pulses = append(pulses, t.Time.Sub(rise))
+			high = false
+		}
+	}
+	return pulses
+}
+
+// ResetTransitions forgets the recorded levels.
+func (p *Pin) ResetTransitions() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transitions = nil
+}
+
+// FailOut makes Out return err, until FailOut(nil).
+func (p *Pin) FailOut(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.outErr = err
+}
+
+// FailIn makes In return err, until FailIn(nil).
+func (p *Pin) FailIn(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inErr = err
+}
+
+// FailWatch makes Watch return err, until FailWatch(nil).
+func (p *Pin) FailWatch(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watchErr = err
+}
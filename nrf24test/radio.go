@@ -0,0 +1,59 @@
+// Package nrf24test provides scriptable mocks of the nRF24L01+ hardware interfaces, to unit
+// test code using an nrf24.Device without a radio.
+//
+// A Radio bundles an SPI register model with CE and IRQ pins: the model transmits on the
+// rising edges of the CE pin, and the IRQ pin follows the interrupt flags of the model:
+//
+//	r := nrf24test.NewRadio()
+//	dev, _ := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76})
+//
+//	r.SPI.QueueRx(1, []byte("hello"))           // a packet arrives on pipe 1
+//	r.SPI.QueueTxResult(nrf24test.TxMaxRetries) // the next transmission is not acknowledged
+//	r.SPI.FailTx(0, io.ErrUnexpectedEOF)        // SPI errors from now on
+//
+// Devices created with NewDevice run in the virtual time of the Clock of the radio, so CE
+// pulses and TX timeouts take no real time.
+//
+// The SPI and Pin mocks can also be used on their own with nrf24.NewWithHardware.
+package nrf24test
+
+import (
+	"time"
+
+	"github.com/michcald/nrf24"
+)
+
+// Radio is an SPI register model with its CE and IRQ pins.
+type Radio struct {
+	SPI *SPI
+	CE  *Pin
+	// IRQ is low while an interrupt flag not masked in CONFIG is raised.
+	IRQ *Pin
+	// Clock is the virtual clock of the devices using the radio, and of the CE transitions.
+	Clock *Clock
+}
+
+// NewRadio creates a radio in its power-on reset state.
+func NewRadio() *Radio {
+	r := &Radio{SPI: NewSPI(), CE: NewPin(nrf24.Low), IRQ: NewPin(nrf24.High), Clock: NewClock(time.Now())}
+	r.CE.clock, r.IRQ.clock = r.Clock, r.Clock
+	r.CE.onOut = func(l nrf24.Level) { r.SPI.SetCE(l == nrf24.High) }
+	r.SPI.onChange = func() {
+		if r.SPI.interrupt() {
+			r.IRQ.SetLevel(nrf24.Low)
+		} else {
+			r.IRQ.SetLevel(nrf24.High)
+		}
+	}
+	return r
+}
+
+// HardwareConfig returns the configuration of a Device using the radio.
+func (r *Radio) HardwareConfig(c nrf24.RadioConfig) nrf24.HardwareConfig {
+	return nrf24.HardwareConfig{RadioConfig: c, CE: r.CE, IRQ: r.IRQ, Clock: r.Clock}
+}
+
+// NewDevice creates a Device using the radio.
+func (r *Radio) NewDevice(c nrf24.RadioConfig) (*nrf24.Device, error) {
+	return nrf24.NewWithHardware(r.HardwareConfig(c), r.SPI)
+}
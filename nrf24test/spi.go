@@ -0,0 +1,285 @@
+package nrf24test
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/internal/chip"
+)
+
+// STATUS register flags, for SetStatus.
+const (
+	RxDR  byte = 1 << 6 // Data ready in the RX FIFO
+	TxDS  byte = 1 << 5 // Data sent (acknowledged)
+	MaxRT byte = 1 << 4 // Maximum number of retransmissions
+)
+
+// Register addresses and SPI commands recorded by the model.
+const (
+	regRPD = 0x09
+
+	cmdWRegister       = 0x20
+	cmdWTxPayload      = 0xA0
+	cmdWAckPayload     = 0xA8
+	cmdWTxPayloadNoAck = 0xB0
+)
+
+// TxResult is the outcome of a transmission, see QueueTxResult.
+type TxResult int
+
+const (
+	// TxAcked completes the transmission with TX_DS.
+	TxAcked TxResult = iota
+	// TxMaxRetries fails the transmission with MAX_RT after AutoRetransmitCount retries.
+	// Packets sent without acknowledgement complete with TX_DS anyway.
+	TxMaxRetries
+	// TxNoResponse never completes the transmission, so that the driver times out.
+	TxNoResponse
+)
+
+// RegisterWrite is a W_REGISTER command.
+type RegisterWrite struct {
+	Reg   byte
+	Value []byte
+}
+
+func (w RegisterWrite) String() string {
+	return fmt.Sprintf("%s=%X", nrf24.RegisterName(w.Reg), w.Value)
+}
+
+// TxPayload is a payload written to the TX FIFO.
+type TxPayload struct {
+	Data  []byte
+	NoAck bool
+	// Pipe is the pipe of a W_ACK_PAYLOAD, or -1 for W_TX_PAYLOAD.
+	Pipe int
+}
+
+// SPI is a mock nRF24L01+ implementing nrf24.SPI, for unit tests of code using a Device.
+// It shares the register model of the radios emulated by package sim: registers read back
+// what was written and the FIFOs behave like the radio's. As on the radio, a packet is only
+// transmitted on a rising edge of CE (or while CE is high) in PTX mode with PWR_UP set,
+// and the outcome is the one queued by QueueTxResult (acknowledged by default).
+//
+// The CE input of the model follows the CE pin of a Radio; set it with SetCE when using
+// an SPI on its own.
+//
+// The zero value is not usable: create it with NewSPI. SPI is concurrent safe.
+type SPI struct {
+	mu      sync.Mutex
+	chip    *chip.Chip
+	carrier bool
+	results []TxResult
+
+	writes       []RegisterWrite
+	expected     []RegisterWrite
+	payloads     []TxPayload
+	transactions [][]byte
+
+	failAfter int
+	failErr   error
+
+	onChange func() // called without the lock after every transaction, SetCE and SetStatus
+}
+
+// NewSPI creates a radio model with the power-on reset register values.
+func NewSPI() *SPI {
+	s := &SPI{}
+	s.chip = chip.New(medium{s})
+	return s
+}
+
+// Tx executes an SPI command.
+func (s *SPI) Tx(w, r []byte) error {
+	s.mu.Lock()
+	if s.failErr != nil {
+		if s.failAfter == 0 {
+			err := s.failErr
+			s.mu.Unlock()
+			return err
+		}
+		s.failAfter--
+	}
+	s.transactions = append(s.transactions, bytes.Clone(w))
+	s.record(w)
+	out := make([]byte, len(w))
+	s.chip.Execute(bytes.Clone(w), out)
+	onChange := s.onChange
+	s.mu.Unlock()
+
+	copy(r, out)
+	if onChange != nil {
+		onChange()
+	}
+	return nil
+}
+
+// record records the register writes and payloads of a command. Called with s.mu held.
+func (s *SPI) record(w []byte) {
+	if len(w) == 0 {
+		return
+	}
+	cmd, args := w[0], w[1:]
+	switch {
+	case cmd >= cmdWRegister && cmd < 0x40:
+		s.writes = append(s.writes, RegisterWrite{Reg: cmd & 0x1F, Value: bytes.Clone(args)})
+	case cmd == cmdWTxPayload, cmd == cmdWTxPayloadNoAck:
+		s.payloads = append(s.payloads, TxPayload{Data: bytes.Clone(args), NoAck: cmd == cmdWTxPayloadNoAck, Pipe: -1})
+	case cmd&0xF8 == cmdWAckPayload:
+		s.payloads = append(s.payloads, TxPayload{Data: bytes.Clone(args), Pipe: int(cmd & 0x07)})
+	}
+}
+
+// medium completes the transmissions of the model with the queued results.
+type medium struct {
+	s *SPI
+}
+
+// Transmit returns the next queued result. Called with s.mu held.
+func (m medium) Transmit(*chip.Chip, chip.Packet) (acked bool, ackPayload []byte, done bool) {
+	result := TxAcked
+	if len(m.s.results) > 0 {
+		result, m.s.results = m.s.results[0], m.s.results[1:]
+	}
+	return result == TxAcked, nil, result != TxNoResponse
+}
+
+func (m medium) Carrier(byte) bool { return m.s.carrier }
+
+// SetCE sets the level of the CE input of the model, transmitting on a rising edge.
+func (s *SPI) SetCE(high bool) {
+	s.mu.Lock()
+	s.chip.SetCE(high)
+	onChange := s.onChange
+	s.mu.Unlock()
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// interrupt reports whether an interrupt flag not masked in CONFIG is raised.
+func (s *SPI) interrupt() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chip.IRQ()
+}
+
+// Register returns the value of a register: the first byte for address registers.
+func (s *SPI) Register(reg byte) byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chip.Register(reg)
+}
+
+// RegisterBytes returns every byte of a register, e.g. the 5 bytes of TX_ADDR.
+func (s *SPI) RegisterBytes(reg byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chip.RegisterBytes(reg)
+}
+
+// SetRegister sets the value of a register, as if the radio had changed it.
+// Setting bit 0 of RPD reports a carrier while in RX mode.
+func (s *SPI) SetRegister(reg byte, val ...byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if reg&0x1F == regRPD {
+		s.carrier = len(val) > 0 && val[0]&0x01 != 0
+		return
+	}
+	s.chip.SetRegister(reg&0x1F, val)
+}
+
+// SetStatus raises interrupt flags (RxDR, TxDS, MaxRT) in STATUS.
+func (s *SPI) SetStatus(flags byte) {
+	s.mu.Lock()
+	s.chip.RaiseFlags(flags)
+	onChange := s.onChange
+	s.mu.Unlock()
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// Status returns the STATUS register.
+func (s *SPI) Status() byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chip.Status()
+}
+
+// QueueRx puts a received packet in the RX FIFO and raises RX_DR.
+// It returns false if the RX FIFO is full, like the radio dropping the packet.
+func (s *SPI) QueueRx(pipe int, payload []byte) bool {
+	s.mu.Lock()
+	ok := s.chip.QueueRx(pipe, payload)
+	onChange := s.onChange
+	s.mu.Unlock()
+	if ok && onChange != nil {
+		onChange()
+	}
+	return ok
+}
+
+// QueueTxResult sets the outcome of the next transmissions, in order.
+// Transmissions without a queued result are acknowledged.
+func (s *SPI) QueueTxResult(results ...TxResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, results...)
+}
+
+// TxPayloads returns every payload written to the TX FIFO, including ACK payloads.
+func (s *SPI) TxPayloads() []TxPayload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TxPayload(nil), s.payloads...)
+}
+
+// Writes returns every W_REGISTER command, in order.
+func (s *SPI) Writes() []RegisterWrite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RegisterWrite(nil), s.writes...)
+}
+
+// Transactions returns the bytes written by every transaction, in order.
+func (s *SPI) Transactions() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.transactions...)
+}
+
+// ExpectWrite adds an expected W_REGISTER command, checked by Verify.
+func (s *SPI) ExpectWrite(reg byte, value ...byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expected = append(s.expected, RegisterWrite{Reg: reg, Value: value})
+}
+
+// Verify checks that the expected register writes happened, in the order of the
+// ExpectWrite calls. Other writes may happen in between.
+func (s *SPI) Verify() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := 0
+	for _, w := range s.writes {
+		if i < len(s.expected) && w.Reg == s.expected[i].Reg && bytes.Equal(w.Value, s.expected[i].Value) {
+			i++
+		}
+	}
+	if i < len(s.expected) {
+		return fmt.Errorf("nrf24test: expected register write %s (%d of %d) did not happen", s.expected[i], i+1, len(s.expected))
+	}
+	return nil
+}
+
+// FailTx makes the transactions fail with err after the next n ones succeed,
+// until FailTx is called again. FailTx(0, nil) stops the failures.
+func (s *SPI) FailTx(n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failAfter, s.failErr = n, err
+}
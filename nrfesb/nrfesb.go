@@ -0,0 +1,229 @@
+// Package nrfesb configures nrf24.Device to talk to nRF51/nRF52 chips running Nordic's
+// Enhanced ShockBurst library (nrf_esb in the nRF5 SDK).
+//
+// Both radios share the ESB on-air format, but the two SDKs describe it differently:
+//
+//   - nrf_esb selects the protocol: NRF_ESB_PROTOCOL_ESB_DPL matches dynamic payloads,
+//     NRF_ESB_PROTOCOL_ESB (legacy, nRF24L compatible) matches a fixed payload size;
+//   - nrf_esb splits a pipe address into a 1-byte prefix and a 4-byte base address, shared by
+//     pipe 0 (base_addr_p0) and pipes 1 to 7 (base_addr_p1).
+//
+// The nRF24L01+ registers, and nrf24.Address, hold the address LSByte first. The prefix is the
+// least significant byte, followed by the base address in array order:
+//
+//	base_addr_p0 = {0x01, 0x02, 0x03, 0x04}, pipe_prefixes[0] = 0xA0
+//	nrf24.Address{0xA0, 0x01, 0x02, 0x03, 0x04} (RF24: 0x04030201A0)
+//	on air, MSByte first: 04 03 02 01 A0
+//
+// With 3 or 4-byte addresses the nRF5 radio only sends the last bytes of the base address:
+// a 3-byte address is {prefix, base[2], base[3]}.
+//
+// Pipes 2 to 5 of the nRF24L01+ only differ from pipe 1 by their first byte, exactly like the
+// nrf_esb prefixes, so nrf_esb pipes 1 to 5 can be received at once. Pipes 6 and 7 can only
+// be transmitted to.
+package nrfesb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/michcald/nrf24"
+)
+
+// Protocol is the nrf_esb protocol.
+type Protocol byte
+
+const (
+	// ProtocolESBDPL is NRF_ESB_PROTOCOL_ESB_DPL: dynamic payload length, the nrf_esb default.
+	ProtocolESBDPL Protocol = iota
+	// ProtocolESB is NRF_ESB_PROTOCOL_ESB: fixed payload length, the legacy nRF24L mode.
+	ProtocolESB
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolESBDPL:
+		return "ESB_DPL"
+	case ProtocolESB:
+		return "ESB"
+	default:
+		return "Unknown"
+	}
+}
+
+// Pipes is the number of pipes of nrf_esb.
+const Pipes = 8
+
+// ErrMismatch is returned when a radio configuration cannot talk to an nrf_esb configuration.
+var ErrMismatch = errors.New("radio configuration does not match nrf_esb")
+
+// Config mirrors nrf_esb_config_t and the addresses set with nrf_esb_set_base_address_0,
+// nrf_esb_set_base_address_1 and nrf_esb_set_prefixes.
+type Config struct {
+	Protocol Protocol
+	// Bitrate is the nrf_esb bitrate. The BLE bitrates have no nRF24L01+ equivalent.
+	Bitrate nrf24.DataRate
+	// CRC is CRCLength8 or CRCLength16; the nRF24L01+ cannot acknowledge packets without CRC.
+	CRC nrf24.CRCLength
+	// PayloadLength is the fixed payload length of ProtocolESB, from 1 to 32.
+	PayloadLength byte
+	// RetransmitDelay is the delay between retransmissions, in microseconds.
+	RetransmitDelay uint16
+	// RetransmitCount is the number of retransmissions.
+	RetransmitCount byte
+	// RFChannel is the channel: the frequency is 2400 + RFChannel MHz on both radios.
+	RFChannel byte
+	// AddressLength is the address length in bytes, including the prefix (3 to 5).
+	AddressLength byte
+	BaseAddrP0    [4]byte
+	BaseAddrP1    [4]byte
+	Prefixes      [Pipes]byte
+}
+
+// DefaultConfig returns the defaults of nrf_esb (NRF_ESB_DEFAULT_CONFIG and the initial addresses),
+// which match the reset values of the nRF24L01+ addresses.
+func DefaultConfig() Config {
+	return Config{
+		Protocol:        ProtocolESBDPL,
+		Bitrate:         nrf24.DataRate2mbps,
+		CRC:             nrf24.CRCLength16,
+		PayloadLength:   32,
+		RetransmitDelay: 250,
+		RetransmitCount: 3,
+		RFChannel:       2,
+		AddressLength:   5,
+		BaseAddrP0:      [4]byte{0xE7, 0xE7, 0xE7, 0xE7},
+		BaseAddrP1:      [4]byte{0xC2, 0xC2, 0xC2, 0xC2},
+		Prefixes:        [Pipes]byte{0xE7, 0xC2, 0xC3, 0xC4, 0xC5, 0xC6, 0xC7, 0xC8},
+	}
+}
+
+// Validate reports the settings that have no nRF24L01+ equivalent.
+func (c Config) Validate() error {
+	var errs []error
+	if c.Protocol != ProtocolESBDPL && c.Protocol != ProtocolESB {
+		errs = append(errs, fmt.Errorf("unknown protocol %d", c.Protocol))
+	}
+	if c.Bitrate > nrf24.DataRate2mbps {
+		errs = append(errs, fmt.Errorf("bitrate %d is not supported by the nRF24L01+", c.Bitrate))
+	}
+	if c.CRC != nrf24.CRCLength8 && c.CRC != nrf24.CRCLength16 {
+		errs = append(errs, fmt.Errorf("CRC must be 8 or 16 bits"))
+	}
+	if c.Protocol == ProtocolESB && (c.PayloadLength == 0 || c.PayloadLength > 32) {
+		errs = append(errs, fmt.Errorf("payload length must be between 1 and 32"))
+	}
+	if c.RetransmitDelay > 4000 {
+		errs = append(errs, fmt.Errorf("retransmit delay must be at most 4000 us"))
+	}
+	if c.RetransmitCount > 15 {
+		errs = append(errs, fmt.Errorf("retransmit count must be at most 15"))
+	}
+	if c.RFChannel > 124 {
+		errs = append(errs, fmt.Errorf("channel number must be between 0 and 124"))
+	}
+	if c.AddressLength < 3 || c.AddressLength > 5 {
+		errs = append(errs, fmt.Errorf("address length must be 3, 4, or 5"))
+	}
+	return errors.Join(errs...)
+}
+
+// PipeAddress returns the address of an nrf_esb pipe in nRF24L01+ register order.
+// Only the first AddressLength bytes are meaningful.
+func (c Config) PipeAddress(pipe int) (nrf24.Address, error) {
+	if pipe < 0 || pipe >= Pipes {
+		return nrf24.Address{}, fmt.Errorf("pipe must be between 0 and %d", Pipes-1)
+	}
+	if c.AddressLength < 3 || c.AddressLength > 5 {
+		return nrf24.Address{}, fmt.Errorf("address length must be 3, 4, or 5")
+	}
+	base := c.BaseAddrP1
+	if pipe == 0 {
+		base = c.BaseAddrP0
+	}
+	var a nrf24.Address
+	a[0] = c.Prefixes[pipe]
+	copy(a[1:c.AddressLength], base[5-c.AddressLength:])
+	return a, nil
+}
+
+// SplitAddress returns the nrf_esb prefix and base address of an address in register order.
+// It is the inverse of Config.PipeAddress; the base bytes not sent on air are left zero.
+func SplitAddress(a nrf24.Address, width byte) (prefix byte, base [4]byte) {
+	width = min(max(width, 3), 5)
+	copy(base[5-width:], a[1:width])
+	return a[0], base
+}
+
+// RadioConfig returns the settings of a Device that receives what an nrf_esb transmitter
+// sends to a pipe, and can transmit to every nrf_esb pipe with Config.PipeAddress.
+// The retransmit delay is rounded up to a multiple of 250us, the granularity of the nRF24L01+.
+func (c Config) RadioConfig(pipe int) (nrf24.RadioConfig, error) {
+	if err := c.Validate(); err != nil {
+		return nrf24.RadioConfig{}, err
+	}
+	addr, err := c.PipeAddress(pipe)
+	if err != nil {
+		return nrf24.RadioConfig{}, err
+	}
+	return nrf24.RadioConfig{
+		ChannelNumber:        c.RFChannel,
+		RxAddr:               addr,
+		EnableDynamicPayload: c.Protocol == ProtocolESBDPL,
+		PayloadSize:          c.PayloadLength,
+		EnableAutoAck:        true,
+		DataRate:             c.Bitrate,
+		PALevel:              nrf24.PALevelMax,
+		AutoRetransmitDelay:  max((c.RetransmitDelay+249)/250*250, 250),
+		AutoRetransmitCount:  c.RetransmitCount,
+		AddressWidth:         c.AddressLength,
+		CRCLength:            c.CRC,
+	}, nil
+}
+
+// Verify reports every setting of a radio configuration that prevents it from receiving what
+// an nrf_esb transmitter sends to a pipe. Unset fields take the defaults of nrf24.NewWithHardware.
+// Each mismatch wraps ErrMismatch; invalid nrf_esb settings are reported as by Config.Validate.
+func Verify(rc nrf24.RadioConfig, c Config, pipe int) error {
+	want, err := c.RadioConfig(pipe)
+	if err != nil {
+		return err
+	}
+
+	// Defaults of nrf24.NewWithHardware
+	if rc.AddressWidth == 0 {
+		rc.AddressWidth = 5
+	}
+	if rc.CRCLength == nrf24.CRCLengthDisabled {
+		rc.CRCLength = nrf24.CRCLength16
+	}
+	if !rc.EnableDynamicPayload && (rc.PayloadSize == 0 || rc.PayloadSize > 32) {
+		rc.PayloadSize = 32
+	}
+
+	var errs []error
+	mismatch := func(name string, got, want any) {
+		errs = append(errs, fmt.Errorf("%w: %s is %v, nrf_esb uses %v", ErrMismatch, name, got, want))
+	}
+	if rc.ChannelNumber != want.ChannelNumber {
+		mismatch("channel", rc.ChannelNumber, want.ChannelNumber)
+	}
+	if rc.DataRate != want.DataRate {
+		mismatch("data rate", rc.DataRate, want.DataRate)
+	}
+	if rc.CRCLength != want.CRCLength {
+		mismatch("CRC length", rc.CRCLength, want.CRCLength)
+	}
+	if rc.AddressWidth != want.AddressWidth {
+		mismatch("address width", rc.AddressWidth, want.AddressWidth)
+	} else if w := rc.AddressWidth; !bytes.Equal(rc.RxAddr[:w], want.RxAddr[:w]) {
+		mismatch("RX address", rc.RxAddr, want.RxAddr)
+	}
+	if rc.EnableDynamicPayload != want.EnableDynamicPayload {
+		mismatch("dynamic payload", rc.EnableDynamicPayload, c.Protocol)
+	} else if !rc.EnableDynamicPayload && rc.PayloadSize != want.PayloadSize {
+		mismatch("payload size", rc.PayloadSize, want.PayloadSize)
+	}
+	return errors.Join(errs...)
+}
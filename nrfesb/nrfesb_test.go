@@ -0,0 +1,173 @@
+package nrfesb
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/sim"
+)
+
+func TestDefaultAddresses(t *testing.T) {
+	// The nrf_esb defaults are the reset values of the nRF24L01+ pipe addresses
+	c := DefaultConfig()
+	for pipe, want := range []nrf24.Address{
+		{0xE7, 0xE7, 0xE7, 0xE7, 0xE7},
+		{0xC2, 0xC2, 0xC2, 0xC2, 0xC2},
+		{0xC3, 0xC2, 0xC2, 0xC2, 0xC2},
+		{0xC4, 0xC2, 0xC2, 0xC2, 0xC2},
+	} {
+		if got, err := c.PipeAddress(pipe); err != nil || got != want {
+			t.Errorf("PipeAddress(%d) = %s, %v, want %s", pipe, got, err, want)
+		}
+	}
+	if _, err := c.PipeAddress(Pipes); err == nil {
+		t.Error("Expected an error for pipe 8")
+	}
+}
+
+func TestAddressByteOrder(t *testing.T) {
+	c := DefaultConfig()
+	c.BaseAddrP0 = [4]byte{0x01, 0x02, 0x03, 0x04}
+	c.Prefixes[0] = 0xA0
+
+	tests := []struct {
+		width byte
+		want  nrf24.Address
+	}{
+		{5, nrf24.Address{0xA0, 0x01, 0x02, 0x03, 0x04}},
+		{4, nrf24.Address{0xA0, 0x02, 0x03, 0x04}},
+		{3, nrf24.Address{0xA0, 0x03, 0x04}},
+	}
+	for _, tt := range tests {
+		c.AddressLength = tt.width
+		got, err := c.PipeAddress(0)
+		if err != nil || got != tt.want {
+			t.Errorf("Width %d: PipeAddress(0) = %s, %v, want %s", tt.width, got, err, tt.want)
+		}
+		prefix, base := SplitAddress(got, tt.width)
+		var wantBase [4]byte
+		copy(wantBase[5-tt.width:], c.BaseAddrP0[5-tt.width:])
+		if prefix != 0xA0 || base != wantBase {
+			t.Errorf("Width %d: SplitAddress() = %02X % X, want A0 % X", tt.width, prefix, base, wantBase)
+		}
+	}
+}
+
+// TestOnAirVectors checks the on-air bits of packets to nrf_esb pipes, from the first address
+// bit to the CRC (zero padded). The vectors were computed independently of the driver.
+func TestOnAirVectors(t *testing.T) {
+	c := DefaultConfig()
+	c.BaseAddrP0 = [4]byte{0x01, 0x02, 0x03, 0x04}
+	c.Prefixes[0] = 0xA0
+
+	tests := []struct {
+		name   string
+		width  byte
+		crc    nrf24.CRCLength
+		packet nrf24.ESBPacket
+		want   []byte
+	}{
+		{
+			// ESB_DPL, 5-byte address, PID 1, "hi": address 04 03 02 01 A0, PCF 000010 01 0
+			name:   "dpl",
+			width:  5,
+			crc:    nrf24.CRCLength16,
+			packet: nrf24.ESBPacket{PID: 1, Payload: []byte("hi")},
+			want:   []byte{0x04, 0x03, 0x02, 0x01, 0xA0, 0x09, 0x34, 0x34, 0x84, 0xB1, 0x80},
+		},
+		{
+			// Legacy ESB, 3-byte address, PID 2, no ACK: address 04 03 A0, PCF 000100 10 1
+			name:   "legacy",
+			width:  3,
+			crc:    nrf24.CRCLength8,
+			packet: nrf24.ESBPacket{PID: 2, NoAck: true, Payload: []byte{0x01, 0x02, 0x03, 0x04}},
+			want:   []byte{0x04, 0x03, 0xA0, 0x12, 0x80, 0x81, 0x01, 0x82, 0x13, 0x00},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.AddressLength = tt.width
+			addr, err := c.PipeAddress(0)
+			if err != nil {
+				t.Fatalf("PipeAddress failed: %v", err)
+			}
+			p := tt.packet
+			p.Address, p.AddressWidth = addr, tt.width
+			frame, err := nrf24.EncodeESBFrame(p, tt.crc)
+			if err != nil {
+				t.Fatalf("EncodeESBFrame failed: %v", err)
+			}
+			if !bytes.Equal(frame, tt.want) {
+				t.Errorf("On-air bits = % X, want % X", frame, tt.want)
+			}
+			if got, ok := nrf24.DecodeESBFrame(tt.want, tt.crc); !ok || got.Address != addr || !bytes.Equal(got.Payload, p.Payload) {
+				t.Errorf("DecodeESBFrame() = %+v, %v", got, ok)
+			}
+		})
+	}
+}
+
+func TestRadioConfig(t *testing.T) {
+	c := DefaultConfig()
+	c.Protocol = ProtocolESB
+	c.PayloadLength = 8
+	c.RetransmitDelay = 600
+
+	rc, err := c.RadioConfig(2)
+	if err != nil {
+		t.Fatalf("RadioConfig failed: %v", err)
+	}
+	if rc.EnableDynamicPayload || rc.PayloadSize != 8 || rc.AutoRetransmitDelay != 750 || rc.RxAddr[0] != 0xC3 {
+		t.Errorf("Unexpected radio config %+v", rc)
+	}
+	if err := Verify(rc, c, 2); err != nil {
+		t.Errorf("Verify(RadioConfig()) = %v", err)
+	}
+
+	rc.DataRate = nrf24.DataRate1mbps
+	rc.EnableDynamicPayload = true
+	rc.RxAddr[0] = 0xC4
+	err = Verify(rc, c, 2)
+	if !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Expected ErrMismatch, got %v", err)
+	}
+	for _, want := range []string{"data rate", "dynamic payload", "RX address"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected a %s mismatch in %q", want, err)
+		}
+	}
+
+	c.CRC = nrf24.CRCLengthDisabled
+	if _, err := c.RadioConfig(0); err == nil {
+		t.Error("Expected an error without CRC")
+	}
+}
+
+func TestInterop(t *testing.T) {
+	// A device configured for pipe 3 receives what is sent to the nrf_esb pipe 3
+	c := DefaultConfig()
+	rc, err := c.RadioConfig(3)
+	if err != nil {
+		t.Fatalf("RadioConfig failed: %v", err)
+	}
+	air := sim.NewAir()
+	rx, _, err := air.NewDevice(rc)
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	tx, _, err := air.NewDevice(rc)
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+
+	addr, _ := c.PipeAddress(3)
+	if err := tx.Transmit(addr, []byte("nrf52")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	if data, ok := rx.Receive(); !ok || string(data) != "nrf52" {
+		t.Errorf("Expected 'nrf52', got %q (%v)", data, ok)
+	}
+}
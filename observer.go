@@ -0,0 +1,144 @@
+package nrf24
+
+// Mode is the operating mode of the radio.
+type Mode uint8
+
+const (
+	// ModePowerDown is the power down mode: the radio is off.
+	ModePowerDown Mode = iota
+	// ModeStandby is the standby mode: powered up, neither receiving nor transmitting.
+	ModeStandby
+	// ModeRX is the receive mode.
+	ModeRX
+	// ModeTX is the transmit mode.
+	ModeTX
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModePowerDown:
+		return "power-down"
+	case ModeStandby:
+		return "standby"
+	case ModeRX:
+		return "RX"
+	case ModeTX:
+		return "TX"
+	default:
+		return "unknown"
+	}
+}
+
+// Observer is notified of the activity of a Device.
+// Like FrameRecorder, its methods are called synchronously while the device lock is held,
+// so implementations must return quickly and must not call back into the Device.
+// Embed NopObserver to implement only some of the methods.
+type Observer interface {
+	// TxStart is called before a payload is sent to an address.
+	TxStart(addr Address, payload []byte, noAck bool)
+	// TxDone is called when a payload was delivered, after retransmits retransmissions.
+	TxDone(addr Address, retransmits byte)
+	// TxFailed is called when a payload was not delivered (ErrMaxRetries or ErrTimeout).
+	TxFailed(addr Address, retransmits byte, err error)
+	// RxPacket is called for every packet read from the RX FIFO.
+	// With ReceiveInto and ReceiveAll, payload is the buffer of the caller: copy it to retain it.
+	RxPacket(pipe int, payload []byte)
+	// IRQ is called when WaitForInterrupt (and so ReceiveBlocking) handles an interrupt,
+	// with the content of the STATUS register.
+	IRQ(status byte)
+	// ModeChange is called when the radio switches between power down, standby, RX and TX.
+	ModeChange(from, to Mode)
+	// ConfigChange is called with the new settings after SetChannel, SetDataRate, SetPALevel,
+	// SetAutoRetransmit and SetAddressWidth.
+	ConfigChange(c RadioConfig)
+}
+
+// NopObserver is an Observer whose methods do nothing.
+type NopObserver struct{}
+
+func (NopObserver) TxStart(addr Address, payload []byte, noAck bool)   {}
+func (NopObserver) TxDone(addr Address, retransmits byte)              {}
+func (NopObserver) TxFailed(addr Address, retransmits byte, err error) {}
+func (NopObserver) RxPacket(pipe int, payload []byte)                  {}
+func (NopObserver) IRQ(status byte)                                    {}
+func (NopObserver) ModeChange(from, to Mode)                           {}
+func (NopObserver) ConfigChange(c RadioConfig)                         {}
+
+// MultiObserver returns an observer that notifies every given observer in order.
+func MultiObserver(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+type multiObserver []Observer
+
+func (m multiObserver) TxStart(addr Address, payload []byte, noAck bool) {
+	for _, o := range m {
+		o.TxStart(addr, payload, noAck)
+	}
+}
+
+func (m multiObserver) TxDone(addr Address, retransmits byte) {
+	for _, o := range m {
+		o.TxDone(addr, retransmits)
+	}
+}
+
+func (m multiObserver) TxFailed(addr Address, retransmits byte, err error) {
+	for _, o := range m {
+		o.TxFailed(addr, retransmits, err)
+	}
+}
+
+func (m multiObserver) RxPacket(pipe int, payload []byte) {
+	for _, o := range m {
+		o.RxPacket(pipe, payload)
+	}
+}
+
+func (m multiObserver) IRQ(status byte) {
+	for _, o := range m {
+		o.IRQ(status)
+	}
+}
+
+func (m multiObserver) ModeChange(from, to Mode) {
+	for _, o := range m {
+		o.ModeChange(from, to)
+	}
+}
+
+func (m multiObserver) ConfigChange(c RadioConfig) {
+	for _, o := range m {
+		o.ConfigChange(c)
+	}
+}
+
+// SetObserver attaches an observer of the device activity.
+// Use MultiObserver to attach several. Pass nil to detach the current observer.
+// This method is concurrent safe.
+func (d *Device) SetObserver(o Observer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.observer = o
+}
+
+// setMode records the mode of the radio and notifies the observer of changes.
+// Call with lock held.
+func (d *Device) setMode(m Mode) {
+	if m == d.mode {
+		return
+	}
+	from := d.mode
+	d.mode = m
+	if d.observer != nil {
+		d.observer.ModeChange(from, m)
+	}
+}
+
+// configChanged notifies the observer of new settings.
+// Call with lock held.
+func (d *Device) configChanged() {
+	if d.observer != nil {
+		d.observer.ConfigChange(d.config.RadioConfig)
+	}
+}
@@ -0,0 +1,93 @@
+package nrf24
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// eventLog records the notifications of an Observer as strings.
+type eventLog struct {
+	NopObserver
+	events []string
+}
+
+func (l *eventLog) TxStart(addr Address, payload []byte, noAck bool) {
+	l.events = append(l.events, fmt.Sprintf("tx-start %s %q", addr, payload))
+}
+
+func (l *eventLog) TxDone(addr Address, retransmits byte) {
+	l.events = append(l.events, fmt.Sprintf("tx-done %d", retransmits))
+}
+
+func (l *eventLog) TxFailed(addr Address, retransmits byte, err error) {
+	l.events = append(l.events, fmt.Sprintf("tx-failed %v", errors.Is(err, ErrMaxRetries)))
+}
+
+func (l *eventLog) RxPacket(pipe int, payload []byte) {
+	l.events = append(l.events, fmt.Sprintf("rx %d %q", pipe, payload))
+}
+
+func (l *eventLog) ModeChange(from, to Mode) {
+	l.events = append(l.events, fmt.Sprintf("mode %s->%s", from, to))
+}
+
+func (l *eventLog) ConfigChange(c RadioConfig) {
+	l.events = append(l.events, fmt.Sprintf("config channel=%d", c.ChannelNumber))
+}
+
+func TestObserver(t *testing.T) {
+	mockSPI := &mockSPIConn{}
+	dev, _ := NewWithHardware(HardwareConfig{RadioConfig: RadioConfig{EnableDynamicPayload: true}, CE: &mockPin{}}, mockSPI)
+	log := &eventLog{}
+	other := &eventLog{}
+	dev.SetObserver(MultiObserver(log, other))
+
+	// Transmit acknowledged after 2 retransmissions
+	mockSPI.rxQueue = nil
+	for i := 0; i < 5; i++ {
+		mockSPI.queueRx([]byte{0})
+	}
+	mockSPI.queueRx([]byte{0, 0x20})
+	mockSPI.queueRx([]byte{0})
+	mockSPI.queueRx([]byte{0, 0x02})
+	if err := dev.Transmit(Address{1, 2, 3, 4, 5}, []byte("hi")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+
+	// Receive on pipe 1
+	mockSPI.rxQueue = nil
+	mockSPI.queueRx([]byte{0x00, 0x42})
+	mockSPI.queueRx([]byte{0x42, 0x00})
+	mockSPI.queueRx([]byte{0x42, 0x02})
+	mockSPI.queueRx([]byte{0x42, 'o', 'k'})
+	dev.Receive()
+
+	dev.SetChannel(90)
+	dev.PowerDown()
+
+	want := []string{
+		"mode RX->standby",
+		`tx-start 01:02:03:04:05 "hi"`,
+		"mode standby->TX",
+		"tx-done 2",
+		"mode TX->standby",
+		"mode standby->RX",
+		`rx 1 "ok"`,
+		"config channel=90",
+		"mode RX->power-down",
+	}
+	if !slices.Equal(log.events, want) {
+		t.Errorf("Unexpected events:\n got %q\nwant %q", log.events, want)
+	}
+	if !slices.Equal(other.events, want) {
+		t.Errorf("Expected every observer to get the events, got %q", other.events)
+	}
+
+	dev.SetObserver(nil)
+	dev.SetChannel(10)
+	if len(log.events) != len(want) {
+		t.Errorf("Expected no events after detaching, got %q", log.events[len(want):])
+	}
+}
@@ -0,0 +1,67 @@
+package nrf24
+
+import "context"
+
+// Transmitter sends packets.
+type Transmitter interface {
+	// Transmit sends a message and waits for its acknowledgement.
+	Transmit(destAddr Address, p []byte) error
+	// TransmitNoAck sends a message without requesting an acknowledgement.
+	TransmitNoAck(destAddr Address, p []byte) error
+}
+
+// Receiver reads the packets received on the open data pipes.
+type Receiver interface {
+	// Receive returns the next received packet, if any, without blocking.
+	Receive() ([]byte, bool)
+	// ReceiveWithPipe is like Receive but also returns the data pipe the packet arrived on.
+	ReceiveWithPipe() ([]byte, int, bool)
+	// ReceiveBlocking waits for a packet or for the context to be cancelled.
+	ReceiveBlocking(ctx context.Context) ([]byte, error)
+	// ReceiveBlockingWithPipe is like ReceiveBlocking but also returns the data pipe the packet arrived on.
+	ReceiveBlockingWithPipe(ctx context.Context) ([]byte, int, error)
+}
+
+// PipeManager opens and closes the data pipes, and queues their ACK payloads.
+type PipeManager interface {
+	// OpenRxPipe opens a data pipe (0-5) with its address. See Device.OpenRxPipe.
+	OpenRxPipe(pipeID int, address []byte) error
+	// CloseRxPipe closes a data pipe.
+	CloseRxPipe(pipeID int) error
+	// PipeAddress returns the address of a data pipe.
+	PipeAddress(pipeID int) (Address, error)
+	// WriteAckPayload queues a payload to be sent with the next ACK on a pipe.
+	WriteAckPayload(pipeID int, data []byte) error
+}
+
+// Configurator reads and changes the RF settings.
+type Configurator interface {
+	// RadioConfig returns the current settings.
+	RadioConfig() RadioConfig
+	SetChannel(channel byte) error
+	SetDataRate(rate DataRate) error
+	SetPALevel(level PALevel) error
+	SetAutoRetransmit(delay uint16, count byte) error
+	SetAddressWidth(width byte) error
+}
+
+// Radio is an nRF24L01+ radio: a *Device, or anything standing in for one, such as a
+// simulated radio, a client of a remote daemon or a recorder.
+//
+// Code written against Radio rather than *Device can be tested with a fake and run
+// unchanged on a remote radio. Code needing less should accept the smallest interface
+// it uses, e.g. a Transmitter.
+type Radio interface {
+	Transmitter
+	Receiver
+	PipeManager
+	Configurator
+	// PowerUp wakes the radio from Power Down mode.
+	PowerUp()
+	// PowerDown puts the radio in Power Down mode.
+	PowerDown()
+	// Close releases the radio.
+	Close() error
+}
+
+var _ Radio = (*Device)(nil)
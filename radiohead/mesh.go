@@ -0,0 +1,190 @@
+package radiohead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Message types of RHMesh, carried in the first data byte of routed messages.
+const (
+	MeshApplication            = 0
+	MeshRouteDiscoveryRequest  = 1
+	MeshRouteDiscoveryResponse = 2
+	MeshRouteFailure           = 3
+)
+
+// MaxMeshMessageSize is the largest application data a mesh message can carry.
+const MaxMeshMessageSize = MaxRoutedMessageSize - 1
+
+// DefaultDiscoveryTimeout is the time RHMesh waits for a route discovery response.
+const DefaultDiscoveryTimeout = 4 * time.Second
+
+// Mesh is a Router that discovers routes on demand, compatible with RHMesh.
+// Like RadioHead, it is meant to be used from a single goroutine.
+type Mesh struct {
+	*Router
+
+	// DiscoveryTimeout is the time to wait for a route discovery response.
+	DiscoveryTimeout time.Duration
+
+	// pending holds the application messages received during a route discovery.
+	pending []RoutedMessage
+}
+
+// NewMesh creates a mesh layer on a reliable datagram layer.
+func NewMesh(r *Reliable) *Mesh {
+	m := &Mesh{Router: NewRouter(r), DiscoveryTimeout: DefaultDiscoveryTimeout}
+	m.peek = m.peekAt
+	m.forwardFailed = m.routeFailed
+	return m
+}
+
+// SendToWait sends application data to a destination, discovering the route first if needed.
+// If the next hop does not acknowledge the message, the route is deleted.
+func (m *Mesh) SendToWait(ctx context.Context, data []byte, dest byte) error {
+	if len(data) > MaxMeshMessageSize {
+		return fmt.Errorf("%w: %d bytes, max is %d", ErrMessageTooLong, len(data), MaxMeshMessageSize)
+	}
+	if dest != BroadcastAddress {
+		if _, ok := m.Route(dest); !ok {
+			if err := m.discover(ctx, dest); err != nil {
+				return err
+			}
+		}
+	}
+	err := m.Router.SendToWait(ctx, append([]byte{MeshApplication}, data...), dest)
+	if errors.Is(err, ErrUnableToDeliver) {
+		m.DeleteRoute(dest)
+	}
+	return err
+}
+
+// discover broadcasts a route discovery request and waits for the route to be learnt.
+func (m *Mesh) discover(ctx context.Context, dest byte) error {
+	req := []byte{MeshRouteDiscoveryRequest, 1, dest}
+	if err := m.Router.SendToWait(ctx, req, BroadcastAddress); err != nil {
+		return err
+	}
+
+	dctx, cancel := context.WithTimeout(ctx, m.DiscoveryTimeout)
+	defer cancel()
+	for {
+		if _, ok := m.Route(dest); ok {
+			return nil
+		}
+		msg, err := m.receive(dctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return ErrNoRoute
+		}
+		if msg != nil {
+			m.pending = append(m.pending, *msg)
+		}
+	}
+}
+
+// ReceiveFromAck waits for application data addressed to this node (or broadcast),
+// handling route discovery and forwarding meanwhile.
+// The returned message holds the application data, without the mesh message type.
+func (m *Mesh) ReceiveFromAck(ctx context.Context) (RoutedMessage, error) {
+	if len(m.pending) > 0 {
+		msg := m.pending[0]
+		m.pending = m.pending[1:]
+		return msg, nil
+	}
+	for {
+		msg, err := m.receive(ctx)
+		if err != nil {
+			return RoutedMessage{}, err
+		}
+		if msg != nil {
+			return *msg, nil
+		}
+	}
+}
+
+// receive handles one routed message. It returns the application messages and nil for
+// the mesh control messages.
+func (m *Mesh) receive(ctx context.Context) (*RoutedMessage, error) {
+	msg, err := m.Router.ReceiveFromAck(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Data) == 0 {
+		return nil, nil
+	}
+	typ, body := msg.Data[0], msg.Data[1:]
+
+	switch typ {
+	case MeshApplication:
+		msg.Data = body
+		return &msg, nil
+
+	case MeshRouteDiscoveryRequest:
+		if msg.Dest != BroadcastAddress || msg.Source == m.Address() || len(body) < 2 {
+			return nil, nil
+		}
+		dest, route := body[1], body[2:]
+		// Everything this request went through is reachable through its last hop
+		m.AddRoute(msg.Source, msg.From)
+		for _, node := range route {
+			m.AddRoute(node, msg.From)
+		}
+		switch {
+		case dest == m.Address():
+			resp := append([]byte{MeshRouteDiscoveryResponse}, body...)
+			m.Router.sendFromSource(ctx, resp, msg.Source, m.Address(), 0)
+		case int(msg.Hops) < int(m.MaxHops) && !slices.Contains(route, m.Address()) && len(msg.Data) < MaxRoutedMessageSize:
+			// Not for us: append ourselves to the route and broadcast it further
+			fwd := append(slices.Clone(msg.Data), m.Address())
+			m.Router.sendFromSource(ctx, fwd, BroadcastAddress, msg.Source, 0)
+		}
+
+	case MeshRouteDiscoveryResponse:
+		if len(body) >= 2 {
+			m.learnResponse(msg.From, body[1], body[2:])
+		}
+
+	case MeshRouteFailure:
+		if len(body) >= 1 {
+			m.DeleteRoute(body[0])
+		}
+	}
+	return nil, nil
+}
+
+// learnResponse adds routes through the hop a discovery response came from.
+func (m *Mesh) learnResponse(from, dest byte, route []byte) {
+	m.AddRoute(dest, from)
+	for _, node := range route {
+		m.AddRoute(node, from)
+	}
+}
+
+// peekAt learns routes from the discovery responses this node forwards: the responding
+// node and the nodes listed after this one are reachable through the hop the response came from.
+func (m *Mesh) peekAt(msg RoutedMessage) {
+	if len(msg.Data) < 3 || msg.Data[0] != MeshRouteDiscoveryResponse {
+		return
+	}
+	route := msg.Data[3:]
+	if i := slices.Index(route, m.Address()); i >= 0 {
+		route = route[i+1:]
+	}
+	m.learnResponse(msg.From, msg.Data[2], route)
+}
+
+// routeFailed deletes a broken route and tells the source of the message about it.
+func (m *Mesh) routeFailed(msg RoutedMessage) {
+	m.DeleteRoute(msg.Dest)
+	if msg.Source != m.Address() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*m.reliable.Timeout*time.Duration(m.reliable.Retries+1))
+		defer cancel()
+		m.Router.sendFromSource(ctx, []byte{MeshRouteFailure, msg.Dest}, msg.Source, m.Address(), 0)
+	}
+}
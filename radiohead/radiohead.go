@@ -0,0 +1,165 @@
+// Package radiohead talks to nodes running the RadioHead library (RH_NRF24 driver).
+//
+// The layers follow those of RadioHead:
+//
+//   - Node adds and strips the 4-byte RadioHead header (RH_NRF24 with RHDatagram addressing);
+//   - Reliable adds software acknowledgements, retries and duplicate suppression (RHReliableDatagram);
+//   - Router forwards messages along a static routing table (RHRouter);
+//   - Mesh discovers routes on demand (RHMesh).
+//
+// RadioHead disables the hardware acknowledgements of the nRF24L01+, so every layer is
+// built on Device.TransmitNoAck and Device.Receive. Configure the device with RadioConfig.
+package radiohead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/michcald/nrf24"
+)
+
+// HeaderSize is the size of the RadioHead header: to, from, id and flags.
+const HeaderSize = 4
+
+// MaxMessageSize is the largest message that fits after the header.
+const MaxMessageSize = 32 - HeaderSize
+
+// BroadcastAddress is the destination of messages for every node.
+const BroadcastAddress = 0xFF
+
+// Header flags.
+const (
+	// FlagAck marks an acknowledgement of RHReliableDatagram.
+	FlagAck = 0x80
+	// FlagRetry marks a retransmission of RHReliableDatagram.
+	FlagRetry = 0x40
+	// FlagsApplication are the flags available to applications.
+	FlagsApplication = 0x0F
+)
+
+// DefaultNetworkAddress is the nRF24L01+ address shared by every RH_NRF24 node by default.
+var DefaultNetworkAddress = nrf24.Address{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}
+
+var (
+	// ErrInvalidMessage is returned for packets too short to hold a RadioHead header.
+	ErrInvalidMessage = errors.New("invalid radiohead message")
+	// ErrMessageTooLong is returned when the data does not fit in a message.
+	ErrMessageTooLong = errors.New("radiohead message too long")
+)
+
+// RadioConfig returns the radio settings of RH_NRF24: channel 2, 2Mbps, 0dBm,
+// dynamic payloads, 16-bit CRC and the default network address.
+// Every node of a RadioHead network shares the same address; nodes are told apart by the header.
+func RadioConfig() nrf24.RadioConfig {
+	return nrf24.RadioConfig{
+		ChannelNumber:        2,
+		RxAddr:               DefaultNetworkAddress,
+		EnableDynamicPayload: true,
+		DataRate:             nrf24.DataRate2mbps,
+		PALevel:              nrf24.PALevelMax,
+		AddressWidth:         5,
+		CRCLength:            nrf24.CRCLength16,
+	}
+}
+
+// Header is the RadioHead message header.
+type Header struct {
+	To    byte
+	From  byte
+	ID    byte
+	Flags byte
+}
+
+// Message is a received message.
+type Message struct {
+	Header
+	Data []byte
+}
+
+// Encode builds the packet of a message.
+func Encode(h Header, data []byte) ([]byte, error) {
+	if len(data) > MaxMessageSize {
+		return nil, fmt.Errorf("%w: %d bytes, max is %d", ErrMessageTooLong, len(data), MaxMessageSize)
+	}
+	return append([]byte{h.To, h.From, h.ID, h.Flags}, data...), nil
+}
+
+// Decode parses a packet into its header and data.
+func Decode(raw []byte) (Message, error) {
+	if len(raw) < HeaderSize {
+		return Message{}, fmt.Errorf("%w: %d bytes", ErrInvalidMessage, len(raw))
+	}
+	return Message{
+		Header: Header{To: raw[0], From: raw[1], ID: raw[2], Flags: raw[3]},
+		Data:   raw[HeaderSize:],
+	}, nil
+}
+
+// Node is a RadioHead node on top of a radio (RH_NRF24 with RHDatagram addressing).
+type Node struct {
+	dev     nrf24.Radio
+	address byte
+	network nrf24.Address
+
+	// Promiscuous delivers the messages addressed to any node, not only this one.
+	Promiscuous bool
+}
+
+// NewNode creates a node with an address on a device configured with RadioConfig
+// (or the same settings with another network address).
+func NewNode(dev nrf24.Radio, address byte) *Node {
+	network, _ := dev.PipeAddress(1)
+	return &Node{dev: dev, address: address, network: network}
+}
+
+// Address returns the address of this node.
+func (n *Node) Address() byte {
+	return n.address
+}
+
+// Send transmits a message with an explicit header.
+func (n *Node) Send(h Header, data []byte) error {
+	raw, err := Encode(h, data)
+	if err != nil {
+		return err
+	}
+	return n.dev.TransmitNoAck(n.network, raw)
+}
+
+// SendTo transmits data to a node (or BroadcastAddress), without acknowledgement.
+func (n *Node) SendTo(data []byte, to byte) error {
+	return n.Send(Header{To: to, From: n.address}, data)
+}
+
+// accept reports whether a received message is for this node.
+func (n *Node) accept(m Message) bool {
+	return n.Promiscuous || m.To == n.address || m.To == BroadcastAddress
+}
+
+// Receive returns the next message for this node, if any, without blocking.
+// Packets for other nodes and malformed packets are discarded.
+func (n *Node) Receive() (Message, bool) {
+	for {
+		raw, ok := n.dev.Receive()
+		if !ok {
+			return Message{}, false
+		}
+		if m, err := Decode(raw); err == nil && n.accept(m) {
+			return m, true
+		}
+	}
+}
+
+// ReceiveBlocking waits for a message for this node or for the context to be cancelled.
+func (n *Node) ReceiveBlocking(ctx context.Context) (Message, error) {
+	for {
+		raw, err := n.dev.ReceiveBlocking(ctx)
+		if err != nil {
+			return Message{}, err
+		}
+		if m, err := Decode(raw); err == nil && n.accept(m) {
+			return m, nil
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package radiohead
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/sim"
+)
+
+// newNodes creates RadioHead nodes with the given addresses on a shared air.
+func newNodes(t *testing.T, addrs ...byte) []*Node {
+	t.Helper()
+	air := sim.NewAir()
+	var nodes []*Node
+	for _, a := range addrs {
+		dev, _, err := air.NewDevice(RadioConfig())
+		if err != nil {
+			t.Fatalf("NewDevice failed: %v", err)
+		}
+		nodes = append(nodes, NewNode(dev, a))
+	}
+	return nodes
+}
+
+func newReliable(n *Node) *Reliable {
+	r := NewReliable(n)
+	r.Timeout = 20 * time.Millisecond
+	return r
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestEncodeDecode(t *testing.T) {
+	raw, err := Encode(Header{To: 2, From: 1, ID: 7, Flags: FlagRetry}, []byte("hi"))
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !bytes.Equal(raw, []byte{2, 1, 7, FlagRetry, 'h', 'i'}) {
+		t.Errorf("Encode() = % X", raw)
+	}
+	m, err := Decode(raw)
+	if err != nil || m.To != 2 || m.From != 1 || m.ID != 7 || string(m.Data) != "hi" {
+		t.Errorf("Decode() = %+v, %v", m, err)
+	}
+
+	if _, err := Encode(Header{}, make([]byte, MaxMessageSize+1)); !errors.Is(err, ErrMessageTooLong) {
+		t.Errorf("Expected ErrMessageTooLong, got %v", err)
+	}
+	if _, err := Decode([]byte{1, 2}); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("Expected ErrInvalidMessage, got %v", err)
+	}
+}
+
+func TestNodeAddressing(t *testing.T) {
+	nodes := newNodes(t, 1, 2, 3)
+
+	if err := nodes[0].SendTo([]byte("for 2"), 2); err != nil {
+		t.Fatalf("SendTo failed: %v", err)
+	}
+	if m, ok := nodes[1].Receive(); !ok || string(m.Data) != "for 2" || m.From != 1 {
+		t.Errorf("Expected 'for 2' from 1, got %+v (%v)", m, ok)
+	}
+	if m, ok := nodes[2].Receive(); ok {
+		t.Errorf("Expected node 3 to ignore a message for 2, got %+v", m)
+	}
+
+	if err := nodes[0].SendTo([]byte("all"), BroadcastAddress); err != nil {
+		t.Fatalf("SendTo failed: %v", err)
+	}
+	for _, n := range nodes[1:] {
+		if m, ok := n.Receive(); !ok || string(m.Data) != "all" {
+			t.Errorf("Node %d: expected broadcast, got %+v (%v)", n.Address(), m, ok)
+		}
+	}
+}
+
+func TestReliable(t *testing.T) {
+	nodes := newNodes(t, 1, 2)
+	client, server := newReliable(nodes[0]), newReliable(nodes[1])
+	ctx := testContext(t)
+
+	got := make(chan Message, 4)
+	go func() {
+		for {
+			m, err := server.ReceiveFromAck(ctx)
+			if err != nil {
+				close(got)
+				return
+			}
+			got <- m
+		}
+	}()
+
+	for _, data := range []string{"one", "two"} {
+		if err := client.SendToWait(ctx, []byte(data), 2); err != nil {
+			t.Fatalf("SendToWait(%q) failed: %v", data, err)
+		}
+		if m := <-got; string(m.Data) != data || m.From != 1 {
+			t.Errorf("Expected %q from 1, got %+v", data, m)
+		}
+	}
+}
+
+func TestReliableDuplicate(t *testing.T) {
+	nodes := newNodes(t, 1, 2)
+	server := newReliable(nodes[1])
+
+	// A retransmission of a message already received is acknowledged but not delivered again
+	h := Header{To: 2, From: 1, ID: 9}
+	nodes[0].Send(h, []byte("once"))
+	if m, ok := server.Receive(); !ok || string(m.Data) != "once" {
+		t.Fatalf("Expected 'once', got %+v (%v)", m, ok)
+	}
+	h.Flags = FlagRetry
+	nodes[0].Send(h, []byte("once"))
+	if m, ok := server.Receive(); ok {
+		t.Errorf("Expected the duplicate to be suppressed, got %+v", m)
+	}
+}
+
+func TestReliableNoAck(t *testing.T) {
+	nodes := newNodes(t, 1)
+	client := newReliable(nodes[0])
+	client.Retries = 2
+
+	if err := client.SendToWait(testContext(t), []byte("anyone?"), 9); !errors.Is(err, ErrNoAck) {
+		t.Fatalf("Expected ErrNoAck, got %v", err)
+	}
+	if client.Retransmissions() != 2 {
+		t.Errorf("Expected 2 retransmissions, got %d", client.Retransmissions())
+	}
+}
+
+// serve runs the receive loop of a router until ctx is cancelled, forwarding messages
+// and sending the delivered ones on a channel.
+func serve(ctx context.Context, receive func(context.Context) (RoutedMessage, error)) <-chan RoutedMessage {
+	got := make(chan RoutedMessage, 4)
+	go func() {
+		defer close(got)
+		for {
+			m, err := receive(ctx)
+			if err != nil {
+				return
+			}
+			got <- m
+		}
+	}()
+	return got
+}
+
+func TestRouter(t *testing.T) {
+	nodes := newNodes(t, 1, 2, 3)
+	a, b, c := NewRouter(newReliable(nodes[0])), NewRouter(newReliable(nodes[1])), NewRouter(newReliable(nodes[2]))
+	ctx := testContext(t)
+
+	// 1 reaches 3 through 2
+	a.AddRoute(3, 2)
+	b.AddRoute(3, 3)
+	serve(ctx, b.ReceiveFromAck)
+	got := serve(ctx, c.ReceiveFromAck)
+
+	if err := a.SendToWait(ctx, []byte("hop"), 3); err != nil {
+		t.Fatalf("SendToWait failed: %v", err)
+	}
+	m := <-got
+	if string(m.Data) != "hop" || m.Source != 1 || m.Dest != 3 || m.Hops != 1 || m.From != 2 {
+		t.Errorf("Unexpected routed message %+v", m)
+	}
+
+	if err := a.SendToWait(ctx, []byte("x"), 4); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Expected ErrNoRoute, got %v", err)
+	}
+}
+
+func TestMeshDiscovery(t *testing.T) {
+	nodes := newNodes(t, 1, 2, 3)
+	a, b, c := NewMesh(newReliable(nodes[0])), NewMesh(newReliable(nodes[1])), NewMesh(newReliable(nodes[2]))
+	a.DiscoveryTimeout = time.Second
+	ctx := testContext(t)
+
+	serve(ctx, b.ReceiveFromAck)
+	got := serve(ctx, c.ReceiveFromAck)
+
+	if err := a.SendToWait(ctx, []byte("found you"), 3); err != nil {
+		t.Fatalf("SendToWait failed: %v", err)
+	}
+	if _, ok := a.Route(3); !ok {
+		t.Error("Expected a route to 3 after discovery")
+	}
+	m := <-got
+	if string(m.Data) != "found you" || m.Source != 1 {
+		t.Errorf("Unexpected mesh message %+v", m)
+	}
+}
+
+func TestMeshNoRoute(t *testing.T) {
+	nodes := newNodes(t, 1)
+	a := NewMesh(newReliable(nodes[0]))
+	a.DiscoveryTimeout = 50 * time.Millisecond
+
+	if err := a.SendToWait(testContext(t), []byte("x"), 7); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Expected ErrNoRoute, got %v", err)
+	}
+}
+
+func TestRadioConfig(t *testing.T) {
+	c := RadioConfig()
+	if c.ChannelNumber != 2 || c.DataRate != nrf24.DataRate2mbps || !c.EnableDynamicPayload {
+		t.Errorf("Unexpected preset %+v", c)
+	}
+}
@@ -0,0 +1,177 @@
+package radiohead
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrNoAck is returned when a message was not acknowledged after every retry.
+var ErrNoAck = errors.New("radiohead message not acknowledged")
+
+// Defaults of RHReliableDatagram.
+const (
+	DefaultRetries = 3
+	DefaultTimeout = 200 * time.Millisecond
+)
+
+// ackData is the payload of acknowledgements.
+var ackData = []byte{'!'}
+
+// ackDelay gives the sender time to switch back to RX after its transmission
+// before it is sent the acknowledgement.
+const ackDelay = 5 * time.Millisecond
+
+// Reliable adds acknowledgements, retries and duplicate suppression to a Node,
+// compatible with RHReliableDatagram.
+// Like RadioHead, it is meant to be used from a single goroutine.
+type Reliable struct {
+	node *Node
+
+	// Retries is the number of retransmissions after the first attempt.
+	Retries int
+	// Timeout is the minimum time to wait for an acknowledgement; the actual wait
+	// is random between Timeout and twice Timeout, as in RadioHead.
+	Timeout time.Duration
+
+	lastID byte
+	// seen holds the ID of the last message delivered from each node.
+	seen [256]struct {
+		id byte
+		ok bool
+	}
+	// pending holds the messages received while waiting for an acknowledgement.
+	pending []Message
+	// retransmissions counts the retransmissions since the Reliable was created.
+	retransmissions uint64
+}
+
+// NewReliable creates a reliable datagram layer on a node.
+func NewReliable(node *Node) *Reliable {
+	return &Reliable{node: node, Retries: DefaultRetries, Timeout: DefaultTimeout}
+}
+
+// Node returns the underlying node.
+func (r *Reliable) Node() *Node {
+	return r.node
+}
+
+// Retransmissions returns the number of retransmissions so far.
+func (r *Reliable) Retransmissions() uint64 {
+	return r.retransmissions
+}
+
+// SendToWait sends data to a node and waits for its acknowledgement, retrying up to Retries times.
+// Broadcasts are sent once and never acknowledged.
+func (r *Reliable) SendToWait(ctx context.Context, data []byte, to byte) error {
+	return r.send(ctx, data, to, 0)
+}
+
+func (r *Reliable) send(ctx context.Context, data []byte, to byte, flags byte) error {
+	r.lastID++
+	h := Header{To: to, From: r.node.address, ID: r.lastID, Flags: flags &^ FlagAck}
+
+	for attempt := 0; attempt <= r.Retries; attempt++ {
+		if attempt > 0 {
+			h.Flags |= FlagRetry
+			r.retransmissions++
+		}
+		if err := r.node.Send(h, data); err != nil {
+			return err
+		}
+		if to == BroadcastAddress {
+			return nil
+		}
+
+		timeout := r.Timeout + rand.N(r.Timeout+1)
+		acked, err := r.waitAck(ctx, h, timeout)
+		if err != nil {
+			return err
+		}
+		if acked {
+			return nil
+		}
+	}
+	return ErrNoAck
+}
+
+// waitAck waits for the acknowledgement of a message.
+// It acknowledges and queues the other messages received meanwhile.
+func (r *Reliable) waitAck(ctx context.Context, sent Header, timeout time.Duration) (bool, error) {
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		m, err := r.node.ReceiveBlocking(wctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			return false, nil
+		}
+		if m.Flags&FlagAck != 0 {
+			if m.From == sent.To && m.To == r.node.address && m.ID == sent.ID {
+				return true, nil
+			}
+			continue
+		}
+		if r.accept(m) {
+			r.pending = append(r.pending, m)
+		}
+	}
+}
+
+// accept acknowledges a received message and reports whether it is new.
+func (r *Reliable) accept(m Message) bool {
+	if m.To != r.node.address {
+		// Broadcasts and messages seen in promiscuous mode are not acknowledged
+		return true
+	}
+	time.Sleep(ackDelay)
+	r.node.Send(Header{To: m.From, From: r.node.address, ID: m.ID, Flags: FlagAck}, ackData)
+
+	s := &r.seen[m.From]
+	if s.ok && s.id == m.ID {
+		return false
+	}
+	s.id, s.ok = m.ID, true
+	return true
+}
+
+// Receive returns the next new message, if any, without blocking.
+// Messages addressed to this node are acknowledged; duplicates are acknowledged again but not returned.
+func (r *Reliable) Receive() (Message, bool) {
+	if len(r.pending) > 0 {
+		m := r.pending[0]
+		r.pending = r.pending[1:]
+		return m, true
+	}
+	for {
+		m, ok := r.node.Receive()
+		if !ok {
+			return Message{}, false
+		}
+		if m.Flags&FlagAck == 0 && r.accept(m) {
+			return m, true
+		}
+	}
+}
+
+// ReceiveFromAck waits for a new message or for the context to be cancelled.
+// Messages addressed to this node are acknowledged; duplicates are acknowledged again but not returned.
+func (r *Reliable) ReceiveFromAck(ctx context.Context) (Message, error) {
+	if len(r.pending) > 0 {
+		m := r.pending[0]
+		r.pending = r.pending[1:]
+		return m, nil
+	}
+	for {
+		m, err := r.node.ReceiveBlocking(ctx)
+		if err != nil {
+			return Message{}, err
+		}
+		if m.Flags&FlagAck == 0 && r.accept(m) {
+			return m, nil
+		}
+	}
+}
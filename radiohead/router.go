@@ -0,0 +1,157 @@
+package radiohead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RouterHeaderSize is the size of the header RHRouter adds after the RadioHead header:
+// destination, source, hops, id and flags.
+const RouterHeaderSize = 5
+
+// MaxRoutedMessageSize is the largest data a routed message can carry.
+const MaxRoutedMessageSize = MaxMessageSize - RouterHeaderSize
+
+// DefaultMaxHops is the default maximum number of hops of RHRouter.
+const DefaultMaxHops = 30
+
+var (
+	// ErrNoRoute is returned when there is no route to the destination.
+	ErrNoRoute = errors.New("radiohead: no route to destination")
+	// ErrUnableToDeliver is returned when the next hop did not acknowledge a message.
+	ErrUnableToDeliver = errors.New("radiohead: unable to deliver to next hop")
+)
+
+// RoutedMessage is a message received through a Router.
+type RoutedMessage struct {
+	// Dest is the final destination of the message.
+	Dest byte
+	// Source is the node that created the message.
+	Source byte
+	// Hops is the number of times the message was forwarded.
+	Hops byte
+	// ID is the end-to-end sequence number set by the source.
+	ID    byte
+	Flags byte
+	// From is the last hop the message was received from.
+	From byte
+	Data []byte
+}
+
+// Router forwards messages along a routing table, compatible with RHRouter.
+// Like RadioHead, it is meant to be used from a single goroutine.
+type Router struct {
+	reliable *Reliable
+
+	// MaxHops is the number of hops after which messages are dropped.
+	MaxHops byte
+
+	routes map[byte]byte
+	lastID byte
+
+	// peek sees every message addressed to another node before it is forwarded.
+	peek func(m RoutedMessage)
+	// forwardFailed is called when a message could not be forwarded to its next hop.
+	forwardFailed func(m RoutedMessage)
+}
+
+// NewRouter creates a router on a reliable datagram layer, with an empty routing table.
+func NewRouter(r *Reliable) *Router {
+	return &Router{reliable: r, MaxHops: DefaultMaxHops, routes: make(map[byte]byte)}
+}
+
+// Address returns the address of this node.
+func (r *Router) Address() byte {
+	return r.reliable.node.address
+}
+
+// AddRoute sets the next hop towards a destination.
+func (r *Router) AddRoute(dest, nextHop byte) {
+	r.routes[dest] = nextHop
+}
+
+// DeleteRoute removes the route to a destination.
+func (r *Router) DeleteRoute(dest byte) {
+	delete(r.routes, dest)
+}
+
+// Route returns the next hop towards a destination.
+func (r *Router) Route(dest byte) (byte, bool) {
+	hop, ok := r.routes[dest]
+	return hop, ok
+}
+
+// SendToWait sends data to a destination through the next hop of its route, and waits
+// for the next hop to acknowledge it. Broadcasts reach the direct neighbours only.
+func (r *Router) SendToWait(ctx context.Context, data []byte, dest byte) error {
+	return r.sendFromSource(ctx, data, dest, r.Address(), 0)
+}
+
+func (r *Router) sendFromSource(ctx context.Context, data []byte, dest, source, flags byte) error {
+	if len(data) > MaxRoutedMessageSize {
+		return fmt.Errorf("%w: %d bytes, max is %d", ErrMessageTooLong, len(data), MaxRoutedMessageSize)
+	}
+	r.lastID++
+	return r.route(ctx, RoutedMessage{Dest: dest, Source: source, ID: r.lastID, Flags: flags, Data: data})
+}
+
+// route sends a message to the next hop towards its destination.
+func (r *Router) route(ctx context.Context, m RoutedMessage) error {
+	hop := byte(BroadcastAddress)
+	if m.Dest != BroadcastAddress {
+		var ok bool
+		if hop, ok = r.routes[m.Dest]; !ok {
+			return ErrNoRoute
+		}
+	}
+	raw := append([]byte{m.Dest, m.Source, m.Hops, m.ID, m.Flags}, m.Data...)
+	err := r.reliable.SendToWait(ctx, raw, hop)
+	if errors.Is(err, ErrNoAck) {
+		return ErrUnableToDeliver
+	}
+	return err
+}
+
+// ReceiveFromAck waits for a message addressed to this node (or broadcast), forwarding
+// meanwhile the messages addressed to other nodes.
+func (r *Router) ReceiveFromAck(ctx context.Context) (RoutedMessage, error) {
+	for {
+		dm, err := r.reliable.ReceiveFromAck(ctx)
+		if err != nil {
+			return RoutedMessage{}, err
+		}
+		if len(dm.Data) < RouterHeaderSize {
+			continue
+		}
+		m := RoutedMessage{
+			Dest:   dm.Data[0],
+			Source: dm.Data[1],
+			Hops:   dm.Data[2],
+			ID:     dm.Data[3],
+			Flags:  dm.Data[4],
+			From:   dm.From,
+			Data:   dm.Data[RouterHeaderSize:],
+		}
+		if m.Dest == r.Address() || m.Dest == BroadcastAddress {
+			return m, nil
+		}
+
+		// Not for us: forward it to the next hop
+		if r.peek != nil {
+			r.peek(m)
+		}
+		if m.Hops >= r.MaxHops {
+			continue
+		}
+		m.Hops++
+		if err := r.route(ctx, m); err != nil {
+			if ctx.Err() != nil {
+				return RoutedMessage{}, ctx.Err()
+			}
+			if r.forwardFailed != nil {
+				r.forwardFailed(m)
+			}
+		}
+	}
+}
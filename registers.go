@@ -0,0 +1,206 @@
+package nrf24
+
+import (
+	"fmt"
+	"strings"
+)
+
+// registerNames maps register addresses to their datasheet names.
+var registerNames = [...]string{
+	0x00: "CONFIG",
+	0x01: "EN_AA",
+	0x02: "EN_RXADDR",
+	0x03: "SETUP_AW",
+	0x04: "SETUP_RETR",
+	0x05: "RF_CH",
+	0x06: "RF_SETUP",
+	0x07: "STATUS",
+	0x08: "OBSERVE_TX",
+	0x09: "RPD",
+	0x0A: "RX_ADDR_P0",
+	0x0B: "RX_ADDR_P1",
+	0x0C: "RX_ADDR_P2",
+	0x0D: "RX_ADDR_P3",
+	0x0E: "RX_ADDR_P4",
+	0x0F: "RX_ADDR_P5",
+	0x10: "TX_ADDR",
+	0x11: "RX_PW_P0",
+	0x12: "RX_PW_P1",
+	0x13: "RX_PW_P2",
+	0x14: "RX_PW_P3",
+	0x15: "RX_PW_P4",
+	0x16: "RX_PW_P5",
+	0x17: "FIFO_STATUS",
+	0x1C: "DYNPD",
+	0x1D: "FEATURE",
+}
+
+// RegisterName returns the datasheet name of a register address,
+// or a hexadecimal placeholder for reserved addresses.
+func RegisterName(reg byte) string {
+	if int(reg) < len(registerNames) && registerNames[reg] != "" {
+		return registerNames[reg]
+	}
+	return fmt.Sprintf("REG_%02X", reg)
+}
+
+// IsAddressRegister reports whether reg holds a full multi-byte address
+// (RX_ADDR_P0, RX_ADDR_P1 or TX_ADDR).
+func IsAddressRegister(reg byte) bool {
+	return reg == _RX_ADDR_P0 || reg == _RX_ADDR_P1 || reg == _TX_ADDR_REG
+}
+
+// Registers is a snapshot of the radio registers.
+type Registers struct {
+	// Values holds the single-byte registers indexed by address.
+	// The entries for RX_ADDR_P0, RX_ADDR_P1 and TX_ADDR hold their LSByte only.
+	Values [0x1E]byte
+	// RxAddrP0, RxAddrP1 and TxAddr hold the full 5-byte address registers.
+	RxAddrP0 Address
+	RxAddrP1 Address
+	TxAddr   Address
+}
+
+// ReadRegisters reads every documented register from the radio.
+// This is intended for diagnostics: it does not change the radio state.
+// This method is concurrent safe.
+func (d *Device) ReadRegisters() Registers {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var r Registers
+	for reg := range r.Values {
+		if registerNames[reg] == "" {
+			continue
+		}
+		r.Values[reg] = d.readRegister(byte(reg))
+	}
+	d.readRegisterN(_RX_ADDR_P0, r.RxAddrP0[:])
+	d.readRegisterN(_RX_ADDR_P1, r.RxAddrP1[:])
+	d.readRegisterN(_TX_ADDR_REG, r.TxAddr[:])
+	return r
+}
+
+// readRegisterN reads a multi-byte register into out.
+func (d *Device) readRegisterN(reg byte, out []byte) {
+	d.scratch[0] = reg
+	for i := 1; i <= len(out); i++ {
+		d.scratch[i] = _NOP
+	}
+	_, data := d.spiTransfer(1 + len(out))
+	copy(out, data)
+}
+
+// DecodeRegister returns a human readable description of the bit fields of a single-byte register.
+func DecodeRegister(reg, val byte) string {
+	bit := func(name string, mask byte) string {
+		if val&mask != 0 {
+			return name + "=1"
+		}
+		return name + "=0"
+	}
+	pipes := func(prefix string) string {
+		var b strings.Builder
+		for p := 5; p >= 0; p-- {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(bit(fmt.Sprintf("%s%d", prefix, p), 1<<p))
+		}
+		return b.String()
+	}
+
+	switch reg {
+	case _CONFIG:
+		return strings.Join([]string{
+			bit("MASK_RX_DR", 1<<6), bit("MASK_TX_DS", 1<<5), bit("MASK_MAX_RT", 1<<4),
+			bit("EN_CRC", _EN_CRC), bit("CRCO", _CRCO), bit("PWR_UP", _PWR_UP), bit("PRIM_RX", _PRIM_RX),
+		}, " ")
+	case _EN_AA:
+		return pipes("ENAA_P")
+	case _EN_RXADDR:
+		return pipes("ERX_P")
+	case _SETUP_AW:
+		if val&0x03 == 0 {
+			return "AW=illegal(2 bytes)"
+		}
+		return fmt.Sprintf("AW=%d bytes", val&0x03+2)
+	case _SETUP_RETR:
+		return fmt.Sprintf("ARD=%dus ARC=%d", (int(val>>4)+1)*250, val&0x0F)
+	case _RF_CH:
+		return fmt.Sprintf("RF_CH=%d (%d MHz)", val&0x7F, 2400+int(val&0x7F))
+	case _RF_SETUP:
+		rate := DataRate1mbps
+		switch {
+		case val&(1<<5) != 0:
+			rate = DataRate250kbps
+		case val&(1<<3) != 0:
+			rate = DataRate2mbps
+		}
+		return fmt.Sprintf("%s RF_DR=%s RF_PWR=%s", bit("CONT_WAVE", 1<<7), rate, PALevel((val>>1)&0x03))
+	case _STATUS:
+		return DecodeStatus(val)
+	case _OBSERVE_TX:
+		return fmt.Sprintf("PLOS_CNT=%d ARC_CNT=%d", val>>4, val&0x0F)
+	case _RPD:
+		return bit("RPD", 0x01)
+	case 0x11, 0x12, 0x13, 0x14, 0x15, 0x16:
+		return fmt.Sprintf("RX_PW=%d", val&0x3F)
+	case 0x17:
+		return strings.Join([]string{
+			bit("TX_REUSE", 1<<6), bit("TX_FULL", 1<<5), bit("TX_EMPTY", 1<<4),
+			bit("RX_FULL", 1<<1), bit("RX_EMPTY", 1<<0),
+		}, " ")
+	case _DYNPD:
+		return pipes("DPL_P")
+	case _FEATURE:
+		return strings.Join([]string{
+			bit("EN_DPL", _EN_DPL), bit("EN_ACK_PAY", _EN_ACK_PAY), bit("EN_DYN_ACK", _EN_DYN_ACK),
+		}, " ")
+	}
+	return ""
+}
+
+// DecodeStatus returns a human readable description of the STATUS register.
+func DecodeStatus(status byte) string {
+	pipe := (status >> 1) & 0x07
+	rxPipe := fmt.Sprintf("RX_P_NO=%d", pipe)
+	switch pipe {
+	case 7:
+		rxPipe = "RX_P_NO=empty"
+	case 6:
+		rxPipe = "RX_P_NO=unused"
+	}
+	flag := func(name string, mask byte) string {
+		if status&mask != 0 {
+			return name + "=1"
+		}
+		return name + "=0"
+	}
+	return strings.Join([]string{
+		flag("RX_DR", _RX_DR), flag("TX_DS", _TX_DS), flag("MAX_RT", _MAX_RT), rxPipe, flag("TX_FULL", 0x01),
+	}, " ")
+}
+
+func (r Registers) String() string {
+	var b strings.Builder
+	for reg, val := range r.Values {
+		if registerNames[reg] == "" {
+			continue
+		}
+		name := registerNames[reg]
+		switch byte(reg) {
+		case _RX_ADDR_P0:
+			fmt.Fprintf(&b, "%-12s %s\n", name, r.RxAddrP0)
+		case _RX_ADDR_P1:
+			fmt.Fprintf(&b, "%-12s %s\n", name, r.RxAddrP1)
+		case _TX_ADDR_REG:
+			fmt.Fprintf(&b, "%-12s %s\n", name, r.TxAddr)
+		case 0x0C, 0x0D, 0x0E, 0x0F:
+			fmt.Fprintf(&b, "%-12s 0x%02X\n", name, val)
+		default:
+			fmt.Fprintf(&b, "%-12s 0x%02X  %s\n", name, val, DecodeRegister(byte(reg), val))
+		}
+	}
+	return b.String()
+}
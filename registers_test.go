@@ -0,0 +1,47 @@
+package nrf24
+
+import (
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	for _, s := range []string{"E7:E6:E5:E4:E3", "E7E6E5E4E3"} {
+		a, err := ParseAddress(s)
+		if err != nil {
+			t.Fatalf("ParseAddress(%q) failed: %v", s, err)
+		}
+		if a != (Address{0xE7, 0xE6, 0xE5, 0xE4, 0xE3}) {
+			t.Errorf("ParseAddress(%q) = %s", s, a)
+		}
+	}
+	if a, err := ParseAddress("01:02:03"); err != nil || a != (Address{1, 2, 3}) {
+		t.Errorf("ParseAddress of a 3-byte address = %s, %v", a, err)
+	}
+	for _, s := range []string{"", "E7", "E7:E7:E7:E7:E7:E7", "ZZ:00:00"} {
+		if _, err := ParseAddress(s); err == nil {
+			t.Errorf("Expected ParseAddress(%q) to fail", s)
+		}
+	}
+}
+
+func TestReadRegisters(t *testing.T) {
+	mockSPI := &mockSPIConn{}
+	dev, _ := NewWithHardware(HardwareConfig{CE: &mockPin{}}, mockSPI)
+
+	mockSPI.rxQueue = nil
+	mockSPI.queueRx([]byte{0x0E, 0x0F}) // CONFIG
+	regs := dev.ReadRegisters()
+	if regs.Values[_CONFIG] != 0x0F {
+		t.Errorf("Expected CONFIG 0x0F, got 0x%02X", regs.Values[_CONFIG])
+	}
+
+	if got := DecodeRegister(_CONFIG, 0x0F); got != "MASK_RX_DR=0 MASK_TX_DS=0 MASK_MAX_RT=0 EN_CRC=1 CRCO=1 PWR_UP=1 PRIM_RX=1" {
+		t.Errorf("Unexpected CONFIG decoding: %s", got)
+	}
+	if got := DecodeStatus(0x42); got != "RX_DR=1 TX_DS=0 MAX_RT=0 RX_P_NO=1 TX_FULL=0" {
+		t.Errorf("Unexpected STATUS decoding: %s", got)
+	}
+	if got := DecodeRegister(_RF_SETUP, 0x26); got != "CONT_WAVE=0 RF_DR=250kbps RF_PWR=0dBm" {
+		t.Errorf("Unexpected RF_SETUP decoding: %s", got)
+	}
+}
@@ -0,0 +1,117 @@
+package nrf24
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrRegisterMismatch is returned by VerifyRegisters when a register of the radio no longer
+// holds the value last written by the driver, e.g. after a brown-out reset of the radio.
+var ErrRegisterMismatch = errors.New("register does not hold the written value")
+
+// shadowRegisters is the set of single-byte configuration registers cached by the driver:
+// only the driver changes them, unlike STATUS, OBSERVE_TX, RPD and FIFO_STATUS.
+const shadowRegisters uint32 = 1<<_CONFIG | 1<<_EN_AA | 1<<_EN_RXADDR | 1<<_SETUP_AW |
+	1<<_SETUP_RETR | 1<<_RF_CH | 1<<_RF_SETUP |
+	0x0F<<(_RX_ADDR_P0+2) | // RX_ADDR_P2-5
+	0x3F<<_RX_PW_P0 | 1<<_DYNPD | 1<<_FEATURE
+
+// shadow is a copy of the configuration registers as last written to, or read from, the radio.
+// It lets read-modify-write updates skip the SPI read.
+type shadow struct {
+	values [0x1E]byte
+	// valid has a bit set for every register whose value is known
+	valid uint32
+}
+
+// isShadowed reports whether a register is cached by the shadow.
+func isShadowed(reg byte) bool {
+	return reg < 0x1E && shadowRegisters&(1<<reg) != 0
+}
+
+// register returns the value of a configuration register from the shadow, reading it from
+// the radio only when unknown. Registers that are not cached are always read.
+// Call with lock held.
+func (d *Device) register(reg byte) byte {
+	if !isShadowed(reg) {
+		return d.readRegister(reg)
+	}
+	if d.shadow.valid&(1<<reg) != 0 {
+		return d.shadow.values[reg]
+	}
+	val, ok := d.readRegisterOK(reg)
+	if ok {
+		d.shadow.values[reg] = val
+		d.shadow.valid |= 1 << reg
+	}
+	return val
+}
+
+// updateShadow records the outcome of a register write.
+// A failed write leaves the value unknown, so that the next access reads it from the radio.
+// Call with lock held.
+func (d *Device) updateShadow(reg, val byte, ok bool) {
+	if !isShadowed(reg) {
+		return
+	}
+	if ok {
+		d.shadow.values[reg] = val
+		d.shadow.valid |= 1 << reg
+	} else {
+		d.shadow.valid &^= 1 << reg
+	}
+}
+
+// VerifyRegisters reads back every configuration register cached by the driver and compares
+// it with the cached value. Mismatching registers are rewritten with the cached value, along
+// with the addresses of pipes 0 and 1, and reported as an ErrRegisterMismatch.
+//
+// Read-modify-write updates of the configuration use the cache instead of reading the radio.
+// Call VerifyRegisters, or set HardwareConfig.VerifyInterval, to detect a radio that lost its
+// configuration.
+// This method is concurrent safe.
+func (d *Device) VerifyRegisters() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.verifyRegisters()
+}
+
+// verifyRegisters implements VerifyRegisters. Call with lock held.
+func (d *Device) verifyRegisters() error {
+	d.lastVerify = d.config.Clock.Now()
+	var mismatches []string
+	for reg := byte(0); reg < 0x1E; reg++ {
+		if d.shadow.valid&(1<<reg) == 0 {
+			continue
+		}
+		val, ok := d.readRegisterOK(reg)
+		if !ok {
+			return fmt.Errorf("%w: failed to read %s", ErrPkg, RegisterName(reg))
+		}
+		if want := d.shadow.values[reg]; val != want {
+			mismatches = append(mismatches, fmt.Sprintf("%s=%02X (want %02X)", RegisterName(reg), val, want))
+			d.writeRegister(reg, want)
+		}
+	}
+	if len(mismatches) == 0 {
+		return nil
+	}
+	// The radio was probably reset: restore the addresses of pipes 0 and 1 too, and TX_ADDR
+	// with the next transmission
+	width := int(d.config.AddressWidth)
+	d.writeRegisterN(_RX_ADDR_P0, d.pipeAddrs[0][:width])
+	d.writeRegisterN(_RX_ADDR_P1, d.pipeAddrs[1][:width])
+	d.txAddrSet = false
+	err := fmt.Errorf("%w: %w: %s", ErrPkg, ErrRegisterMismatch, strings.Join(mismatches, ", "))
+	d.log(LogWarn, "Registers restored", Field{"error", err})
+	return err
+}
+
+// verifyPeriodically runs verifyRegisters if VerifyInterval elapsed since the last verification.
+// Call with lock held.
+func (d *Device) verifyPeriodically() {
+	if d.config.VerifyInterval > 0 && d.config.Clock.Now().Sub(d.lastVerify) >= d.config.VerifyInterval {
+		d.verifyRegisters()
+	}
+}
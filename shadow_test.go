@@ -0,0 +1,107 @@
+package nrf24_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/nrf24test"
+)
+
+// reads returns the registers read by the transactions, in order.
+func reads(transactions [][]byte) []byte {
+	var regs []byte
+	for _, tx := range transactions {
+		if len(tx) == 2 && tx[0] < 0x20 {
+			regs = append(regs, tx[0])
+		}
+	}
+	return regs
+}
+
+func TestShadowTransmit(t *testing.T) {
+	r := nrf24test.NewRadio()
+	dev, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	if err := dev.OpenRxPipe(2, []byte{0xC3}); err != nil {
+		t.Fatalf("OpenRxPipe failed: %v", err)
+	}
+
+	start := len(r.SPI.Transactions())
+	for i := 0; i < 2; i++ {
+		if err := dev.Transmit(peerAddr, []byte("hi")); err != nil {
+			t.Fatalf("Transmit %d failed: %v", i, err)
+		}
+	}
+	// Only STATUS and OBSERVE_TX are read: CONFIG comes from the shadow
+	for _, reg := range reads(r.SPI.Transactions()[start:]) {
+		if reg != 0x07 && reg != 0x08 {
+			t.Errorf("Unexpected read of %s", nrf24.RegisterName(reg))
+		}
+	}
+	// The second transmission to the same peer does not write the address again
+	addrWrites := 0
+	for _, w := range r.SPI.Writes() {
+		if w.Reg == 0x10 {
+			addrWrites++
+		}
+	}
+	if addrWrites != 1 {
+		t.Errorf("Expected TX_ADDR to be written once, got %d writes", addrWrites)
+	}
+	if got := r.SPI.Register(0x02); got != 0x07 {
+		t.Errorf("EN_RXADDR = %02X, want 07", got)
+	}
+}
+
+func TestVerifyRegisters(t *testing.T) {
+	r := nrf24test.NewRadio()
+	dev, err := r.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	if err := dev.VerifyRegisters(); err != nil {
+		t.Fatalf("VerifyRegisters failed: %v", err)
+	}
+
+	// The radio loses its configuration
+	r.SPI.SetRegister(0x05, 2)
+	r.SPI.SetRegister(0x1D, 0)
+	r.SPI.SetRegister(0x0B, 0xE7, 0xE7, 0xE7, 0xE7, 0xE7)
+	if err := dev.VerifyRegisters(); !errors.Is(err, nrf24.ErrRegisterMismatch) {
+		t.Fatalf("Expected ErrRegisterMismatch, got %v", err)
+	}
+	if ch, feature := r.SPI.Register(0x05), r.SPI.Register(0x1D); ch != 76 || feature == 0 {
+		t.Errorf("Expected RF_CH and FEATURE to be restored, got %d and %02X", ch, feature)
+	}
+	if addr := r.SPI.RegisterBytes(0x0B); nrf24.Address(addr) != dev.RadioConfig().RxAddr {
+		t.Errorf("Expected RX_ADDR_P1 to be restored, got %X", addr)
+	}
+	if err := dev.VerifyRegisters(); err != nil {
+		t.Errorf("Expected the restored registers to verify, got %v", err)
+	}
+}
+
+func TestVerifyInterval(t *testing.T) {
+	r := nrf24test.NewRadio()
+	hc := r.HardwareConfig(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	hc.VerifyInterval = time.Second
+	dev, err := nrf24.NewWithHardware(hc, r.SPI)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+
+	r.SPI.SetRegister(0x05, 2)
+	dev.Receive()
+	if got := r.SPI.Register(0x05); got != 2 {
+		t.Errorf("Expected no verification before the interval, RF_CH = %d", got)
+	}
+	r.Clock.Advance(time.Second)
+	dev.Receive()
+	if got := r.SPI.Register(0x05); got != 76 {
+		t.Errorf("Expected RF_CH to be restored after the interval, got %d", got)
+	}
+}
@@ -0,0 +1,230 @@
+// Package sim emulates nRF24L01+ radios sharing a virtual air medium.
+//
+// Each emulated Radio implements the register map, the SPI command set and the
+// RX/TX FIFOs of the real chip, and provides the CE and IRQ pins, so it can be passed
+// straight to nrf24.NewWithHardware. Packets transmitted by one radio are delivered to
+// every other radio on the same Air that listens on the same channel, data rate and
+// address, including Enhanced ShockBurst auto-acknowledgements and ACK payloads. When
+// several radios acknowledge the same packet, the transmitter gets the first ACK.
+//
+// Timing is not emulated: transmissions complete as soon as CE is pulsed. With SetClock,
+// the devices of an Air run in virtual time (e.g. with an nrf24test.Clock) and take no
+// real time to power up or transmit.
+package sim
+
+import (
+	"sync"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/internal/chip"
+)
+
+// carrierHold is how long a transmission keeps RPD set on its channel.
+const carrierHold = 50 * time.Millisecond
+
+// Air is a shared radio medium connecting emulated radios.
+type Air struct {
+	mu     sync.Mutex
+	radios []*Radio
+	lastTX [128]time.Time
+	noise  [128]bool
+	clock  nrf24.Clock
+}
+
+// NewAir creates an empty air medium.
+func NewAir() *Air {
+	return &Air{clock: nrf24.SystemClock}
+}
+
+// SetClock sets the clock of the air, which times the carrier detected after a
+// transmission, and of the devices created afterwards with NewDevice.
+func (a *Air) SetClock(c nrf24.Clock) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clock = c
+}
+
+// SetNoise marks a channel as busy (or clear again), so that RPD reports a carrier on it.
+func (a *Air) SetNoise(channel byte, busy bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.noise[channel&0x7F] = busy
+}
+
+// NewRadio adds a radio in its power-on reset state to the air.
+func (a *Air) NewRadio() *Radio {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r := &Radio{air: a, chip: chip.New(medium{a})}
+	r.cePin = &cePin{r: r}
+	r.irqPin = &irqPin{r: r}
+	a.radios = append(a.radios, r)
+	return r
+}
+
+// NewDevice adds a radio to the air and creates a driver for it.
+func (a *Air) NewDevice(c nrf24.RadioConfig) (*nrf24.Device, *Radio, error) {
+	r := a.NewRadio()
+	a.mu.Lock()
+	clock := a.clock
+	a.mu.Unlock()
+	dev, err := nrf24.NewWithHardware(nrf24.HardwareConfig{
+		RadioConfig: c,
+		CE:          r.CE(),
+		IRQ:         r.IRQ(),
+		Clock:       clock,
+	}, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dev, r, nil
+}
+
+// Radio is an emulated nRF24L01+ chip. It implements nrf24.SPI.
+type Radio struct {
+	air    *Air
+	chip   *chip.Chip
+	irqLow bool
+
+	cePin  *cePin
+	irqPin *irqPin
+}
+
+// CE returns the Chip Enable pin of the radio.
+func (r *Radio) CE() nrf24.Pin { return r.cePin }
+
+// IRQ returns the (active low) interrupt pin of the radio.
+func (r *Radio) IRQ() nrf24.Pin { return r.irqPin }
+
+// Register returns the current value of a single-byte register.
+func (r *Radio) Register(reg byte) byte {
+	r.air.mu.Lock()
+	defer r.air.mu.Unlock()
+	return r.chip.Register(reg)
+}
+
+// Tx executes one SPI transaction (w is the MOSI data, rd receives the MISO data).
+func (r *Radio) Tx(w, rd []byte) error {
+	if len(w) == 0 {
+		return nil
+	}
+	// w and rd may share the same backing array
+	cmd := make([]byte, len(w))
+	copy(cmd, w)
+	out := make([]byte, len(rd))
+
+	r.air.mu.Lock()
+	r.chip.Execute(cmd, out)
+	fire := r.air.updateIRQs()
+	r.air.mu.Unlock()
+
+	copy(rd, out)
+	for _, h := range fire {
+		h()
+	}
+	return nil
+}
+
+func (r *Radio) setCE(high bool) {
+	r.air.mu.Lock()
+	r.chip.SetCE(high)
+	fire := r.air.updateIRQs()
+	r.air.mu.Unlock()
+
+	for _, h := range fire {
+		h()
+	}
+}
+
+// medium carries the packets transmitted by the radios of an air.
+type medium struct {
+	air *Air
+}
+
+// Transmit delivers a packet to every other radio of the air. Call with the air lock held.
+func (m medium) Transmit(tx *chip.Chip, p chip.Packet) (acked bool, ackPayload []byte, done bool) {
+	m.air.lastTX[tx.Channel()] = m.air.clock.Now()
+	for _, rx := range m.air.radios {
+		// Every receiver sends its ACK, and its ACK payload: the transmitter
+		// gets the first one, the others collide on the air
+		if ok, payload := rx.chip.Receive(tx, p); ok && !acked {
+			acked, ackPayload = true, payload
+		}
+	}
+	return acked, ackPayload, true
+}
+
+func (m medium) Carrier(channel byte) bool {
+	return m.air.carrier(channel)
+}
+
+// carrier reports whether a carrier is present on a channel. Call with the air lock held.
+func (a *Air) carrier(ch byte) bool {
+	return a.noise[ch] || a.clock.Now().Sub(a.lastTX[ch]) < carrierHold
+}
+
+// updateIRQs updates the IRQ line of every radio and returns the handlers to call
+// for new falling edges. Call with the air lock held.
+func (a *Air) updateIRQs() []func() {
+	var fire []func()
+	for _, r := range a.radios {
+		low := r.chip.IRQ()
+		if low && !r.irqLow && r.irqPin.handler != nil {
+			fire = append(fire, r.irqPin.handler)
+		}
+		r.irqLow = low
+	}
+	return fire
+}
+
+// cePin drives the CE input of an emulated radio.
+type cePin struct {
+	r *Radio
+}
+
+func (p *cePin) Out(l nrf24.Level) error {
+	p.r.setCE(l == nrf24.High)
+	return nil
+}
+
+func (p *cePin) In(pull nrf24.Pull) error { return nil }
+
+func (p *cePin) Read() nrf24.Level {
+	p.r.air.mu.Lock()
+	defer p.r.air.mu.Unlock()
+	return nrf24.Level(p.r.chip.CE())
+}
+
+func (p *cePin) Watch(edge nrf24.Edge, handler func()) error { return nil }
+func (p *cePin) Unwatch() error                              { return nil }
+
+// irqPin is the active low IRQ output of an emulated radio.
+type irqPin struct {
+	r       *Radio
+	handler func()
+}
+
+func (p *irqPin) Out(l nrf24.Level) error  { return nil }
+func (p *irqPin) In(pull nrf24.Pull) error { return nil }
+
+func (p *irqPin) Read() nrf24.Level {
+	p.r.air.mu.Lock()
+	defer p.r.air.mu.Unlock()
+	return nrf24.Level(!p.r.irqLow)
+}
+
+func (p *irqPin) Watch(edge nrf24.Edge, handler func()) error {
+	p.r.air.mu.Lock()
+	defer p.r.air.mu.Unlock()
+	p.handler = handler
+	return nil
+}
+
+func (p *irqPin) Unwatch() error {
+	p.r.air.mu.Lock()
+	defer p.r.air.mu.Unlock()
+	p.handler = nil
+	return nil
+}
@@ -0,0 +1,215 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/nrf24test"
+)
+
+func newPair(t *testing.T, dynamic bool) (*nrf24.Device, *nrf24.Device) {
+	t.Helper()
+	air := NewAir()
+	a, _, err := air.NewDevice(nrf24.RadioConfig{
+		ChannelNumber:        76,
+		RxAddr:               nrf24.Address{0xA1, 0xA1, 0xA1, 0xA1, 0xA1},
+		EnableDynamicPayload: dynamic,
+	})
+	if err != nil {
+		t.Fatalf("NewDevice(a) failed: %v", err)
+	}
+	b, _, err := air.NewDevice(nrf24.RadioConfig{
+		ChannelNumber:        76,
+		RxAddr:               nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2},
+		EnableDynamicPayload: dynamic,
+	})
+	if err != nil {
+		t.Fatalf("NewDevice(b) failed: %v", err)
+	}
+	return a, b
+}
+
+func TestTransmitReceive(t *testing.T) {
+	a, b := newPair(t, true)
+
+	if err := a.Transmit(nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}, []byte("hello")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := b.ReceiveBlocking(ctx)
+	if err != nil {
+		t.Fatalf("ReceiveBlocking failed: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("Expected 'hello', got %q", data)
+	}
+}
+
+func TestFixedPayload(t *testing.T) {
+	a, b := newPair(t, false)
+
+	if err := a.Transmit(nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}, []byte("hi")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	data, ok := b.Receive()
+	if !ok {
+		t.Fatal("Expected a packet")
+	}
+	if len(data) != 32 || string(data[:2]) != "hi" {
+		t.Errorf("Expected 32-byte padded payload starting with 'hi', got %q", data)
+	}
+}
+
+func TestMaxRetries(t *testing.T) {
+	a, _ := newPair(t, true)
+
+	err := a.Transmit(nrf24.Address{0x01, 0x02, 0x03, 0x04, 0x05}, []byte("nobody"))
+	if !errors.Is(err, nrf24.ErrMaxRetries) {
+		t.Fatalf("Expected ErrMaxRetries, got %v", err)
+	}
+	lost, retries := a.GetRetransmissionCounters()
+	if lost != 1 || retries != 3 {
+		t.Errorf("Expected counters (1, 3), got (%d, %d)", lost, retries)
+	}
+}
+
+func TestAckPayload(t *testing.T) {
+	a, b := newPair(t, true)
+
+	if err := b.WriteAckPayload(1, []byte("pong")); err != nil {
+		t.Fatalf("WriteAckPayload failed: %v", err)
+	}
+	if err := a.Transmit(nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}, []byte("ping")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	// The ACK payload is flushed when the transmitter goes back to listening,
+	// so only the receiver side is checked here.
+	data, ok := b.Receive()
+	if !ok || string(data) != "ping" {
+		t.Errorf("Expected 'ping' on receiver, got %q (%v)", data, ok)
+	}
+}
+
+func TestAckPayloadFIFO(t *testing.T) {
+	air := NewAir()
+	dev, radio, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, EnableDynamicPayload: true})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+
+	// Queued ACK payloads take TX FIFO slots, as on the chip
+	const fifoStatus, txFull, txEmpty = 0x17, 0x20, 0x10
+	if radio.Register(fifoStatus)&txEmpty == 0 {
+		t.Fatal("Expected an empty TX FIFO")
+	}
+	for pipe := 0; pipe < 3; pipe++ {
+		if err := dev.WriteAckPayload(pipe+1, []byte("ack")); err != nil {
+			t.Fatalf("WriteAckPayload failed: %v", err)
+		}
+	}
+	if v := radio.Register(fifoStatus); v&txEmpty != 0 || v&txFull == 0 {
+		t.Errorf("FIFO_STATUS = %02X, want TX_FULL with three ACK payloads", v)
+	}
+	if radio.Register(0x07)&0x01 == 0 {
+		t.Error("Expected TX_FULL in STATUS")
+	}
+}
+
+func TestCarrier(t *testing.T) {
+	air := NewAir()
+	dev, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 10})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	if dev.IsCarrierDetected() {
+		t.Error("Expected no carrier on a quiet channel")
+	}
+	air.SetNoise(10, true)
+	if !dev.IsCarrierDetected() {
+		t.Error("Expected carrier on a noisy channel")
+	}
+}
+
+func TestVirtualClock(t *testing.T) {
+	air := NewAir()
+	clock := nrf24test.NewClock(time.Unix(0, 0))
+	air.SetClock(clock)
+	a, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 10})
+	if err != nil {
+		t.Fatalf("NewDevice(a) failed: %v", err)
+	}
+	b, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 10, RxAddr: nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}})
+	if err != nil {
+		t.Fatalf("NewDevice(b) failed: %v", err)
+	}
+	if err := a.TransmitNoAck(nrf24.Address{1, 2, 3, 4, 5}, []byte("hi")); err != nil {
+		t.Fatalf("TransmitNoAck failed: %v", err)
+	}
+	if !b.IsCarrierDetected() {
+		t.Error("Expected carrier right after a transmission")
+	}
+	clock.Advance(carrierHold)
+	if b.IsCarrierDetected() {
+		t.Errorf("Expected no carrier %v after the transmission", carrierHold)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	air := NewAir()
+	bcast := nrf24.Address{0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	var listeners []*nrf24.Device
+	for i := 0; i < 3; i++ {
+		dev, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, RxAddr: bcast, EnableDynamicPayload: true})
+		if err != nil {
+			t.Fatalf("NewDevice failed: %v", err)
+		}
+		listeners = append(listeners, dev)
+	}
+
+	if err := listeners[0].TransmitNoAck(bcast, []byte("all")); err != nil {
+		t.Fatalf("TransmitNoAck failed: %v", err)
+	}
+	for i, dev := range listeners[1:] {
+		if data, ok := dev.Receive(); !ok || string(data) != "all" {
+			t.Errorf("Listener %d: expected 'all', got %q (%v)", i+1, data, ok)
+		}
+	}
+}
+
+func TestSharedAddress(t *testing.T) {
+	air := NewAir()
+	addr := nrf24.Address{0xC1, 0xC1, 0xC1, 0xC1, 0xC1}
+	tx, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, RxAddr: nrf24.Address{0xE1, 0xE1, 0xE1, 0xE1, 0xE1}})
+	if err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	var listeners []*nrf24.Device
+	for i := 0; i < 2; i++ {
+		dev, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, RxAddr: addr})
+		if err != nil {
+			t.Fatalf("NewDevice failed: %v", err)
+		}
+		listeners = append(listeners, dev)
+	}
+
+	// Acknowledged or not, a packet reaches every radio listening on its address
+	if err := tx.Transmit(addr, []byte("acked")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	if err := tx.TransmitNoAck(addr, []byte("no ack")); err != nil {
+		t.Fatalf("TransmitNoAck failed: %v", err)
+	}
+	for i, dev := range listeners {
+		for _, want := range []string{"acked", "no ack"} {
+			if data, ok := dev.Receive(); !ok || !strings.HasPrefix(string(data), want) {
+				t.Errorf("Listener %d: expected %q, got %q (%v)", i, want, data, ok)
+			}
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package nrf24
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --- Promiscuous Sniffer ---
+//
+// The nRF24L01+ has no promiscuous mode, but it can be tricked into one:
+// with the (officially illegal) 2-byte address width, CRC checking disabled and the
+// address set to 0x00AA, the radio locks onto background noise (0x00) followed by the
+// preamble of any packet on the channel (0xAA). Everything after the preamble,
+// starting with the real destination address, is then delivered as a raw 32-byte payload
+// that can be searched in software for valid ESB packets (see DecodeESBFrame).
+
+// snifferAddress is the 2-byte listening address in register order (LSByte first).
+// On air it reads 0x00 (noise) followed by 0xAA (preamble).
+var snifferAddress = [2]byte{0xAA, 0x00}
+
+// ErrSnifferActive is returned when transmitting while the sniffer mode is enabled.
+var ErrSnifferActive = errors.New("sniffer mode active")
+
+// pipeState is the configuration of the RX pipes, saved while sniffing.
+type pipeState struct {
+	enAA, enRxAddr, dynpd, feature byte
+	payloadWidths                  [6]byte
+	addrs                          [6]Address
+}
+
+// savePipes returns the current configuration of the RX pipes.
+// Call with lock held.
+func (d *Device) savePipes() pipeState {
+	p := pipeState{
+		enAA:     d.register(_EN_AA),
+		enRxAddr: d.register(_EN_RXADDR),
+		dynpd:    d.register(_DYNPD),
+		feature:  d.register(_FEATURE),
+		addrs:    d.pipeAddrs,
+	}
+	for pipe := range p.payloadWidths {
+		p.payloadWidths[pipe] = d.register(_RX_PW_P0 + byte(pipe))
+	}
+	return p
+}
+
+// restorePipes writes back a configuration of the RX pipes returned by savePipes.
+// Call with lock held.
+func (d *Device) restorePipes(p pipeState) {
+	d.beginBatch()
+	defer d.endBatch()
+
+	width := d.config.AddressWidth
+	d.writeRegisterN(_RX_ADDR_P0, p.addrs[0][:width])
+	d.writeRegisterN(_RX_ADDR_P1, p.addrs[1][:width])
+	for pipe := 2; pipe <= 5; pipe++ {
+		d.writeRegister(_RX_ADDR_P0+byte(pipe), p.addrs[pipe][0])
+	}
+	for pipe, w := range p.payloadWidths {
+		d.writeRegister(_RX_PW_P0+byte(pipe), w)
+	}
+	d.writeRegister(_FEATURE, p.feature)
+	d.writeRegister(_DYNPD, p.dynpd)
+	d.writeRegister(_EN_AA, p.enAA)
+	d.writeRegister(_EN_RXADDR, p.enRxAddr)
+	d.pipeAddrs = p.addrs
+}
+
+// StartSniffer switches the radio into promiscuous sniffer mode on the current channel and
+// data rate. Use SetChannel and SetDataRate to choose what to listen to.
+// While sniffing, Transmit and Receive are unavailable; use ReadRawFrame instead.
+// This method is concurrent safe.
+func (d *Device) StartSniffer() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.sniffing {
+		return nil
+	}
+	if d.beacon != nil {
+		return fmt.Errorf("%w: %w", ErrPkg, ErrBeaconActive)
+	}
+
+	d.snifferPipes = d.savePipes()
+	d.setCE(false)
+	// Power up as receiver with CRC disabled
+	d.beginBatch()
+	d.writeRegister(_CONFIG, _PWR_UP|_PRIM_RX)
+	// 2-byte addresses (SETUP_AW = 00)
+	d.writeRegister(_SETUP_AW, 0)
+	d.writeRegister(_EN_AA, 0)
+	d.writeRegister(_EN_RXADDR, _ERX_P0)
+	d.writeRegisterN(_RX_ADDR_P0, snifferAddress[:])
+	d.txAddrSet = false
+	d.writeRegister(_DYNPD, 0)
+	d.writeRegister(_FEATURE, 0)
+	d.writeRegister(_RX_PW_P0, _MAX_PAYLOAD_BYTES)
+	d.clearStatus()
+	d.endBatch()
+	d.flushRX()
+	d.setCE(true)
+
+	d.sniffing = true
+	d.setMode(ModeRX)
+	d.log(LogInfo, "Sniffer mode enabled.", Field{"channel", int(d.config.ChannelNumber)})
+	return nil
+}
+
+// StopSniffer leaves the sniffer mode and restores the configuration the device was created with,
+// and the RX pipes as they were before StartSniffer.
+// This method is concurrent safe.
+func (d *Device) StopSniffer() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.sniffing {
+		return nil
+	}
+
+	d.setCE(false)
+	d.configureRadio()
+	// configureRadio only sets pipes 0 and 1 up, as the device was created
+	d.restorePipes(d.snifferPipes)
+	d.clearStatus()
+	d.flushRX()
+	d.setCE(true)
+
+	d.sniffing = false
+	d.setMode(ModeRX)
+	d.log(LogInfo, "Sniffer mode disabled.")
+	return nil
+}
+
+// ReadRawFrame reads the next raw 32-byte frame captured in sniffer mode.
+// It returns false if no frame is available or the sniffer mode is not enabled.
+// This method is concurrent safe.
+func (d *Device) ReadRawFrame() (Packet, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var frame Packet
+	if !d.sniffing {
+		return frame, false
+	}
+	if _, ok := d.available(); !ok {
+		return frame, false
+	}
+
+	d.scratch[0] = _R_RX_PAYLOAD
+	for i := 1; i <= _MAX_PAYLOAD_BYTES; i++ {
+		d.scratch[i] = _NOP
+	}
+	_, data := d.spiTransfer(_MAX_PAYLOAD_BYTES + 1)
+	copy(frame[:], data)
+
+	d.clearStatus()
+	return frame, true
+}
+
+// SniffedAddress summarises the traffic seen for one address by a Sniffer.
+type SniffedAddress struct {
+	Address      Address
+	AddressWidth byte
+	// Packets is the number of valid packets decoded for this address.
+	Packets int
+	// LastSeen is the time the last packet was decoded.
+	LastSeen time.Time
+	// LastPayload is the payload of the last packet.
+	LastPayload []byte
+}
+
+// Sniffer captures raw frames from a Device in sniffer mode and decodes them as ESB packets,
+// keeping track of the addresses it discovers.
+type Sniffer struct {
+	dev *Device
+	crc CRCLength
+
+	mu    sync.Mutex
+	seen  map[Address]*SniffedAddress
+	raw   int
+	valid int
+}
+
+// NewSniffer creates a Sniffer for the given device.
+// crc is the CRC length used by the transmitters of interest.
+// Defaults to CRCLength16 if CRCLengthDisabled is provided.
+func NewSniffer(dev *Device, crc CRCLength) *Sniffer {
+	if crc == CRCLengthDisabled {
+		crc = CRCLength16
+	}
+	return &Sniffer{
+		dev:  dev,
+		crc:  crc,
+		seen: make(map[Address]*SniffedAddress),
+	}
+}
+
+// Run enables the sniffer mode and decodes frames until the context is cancelled.
+// handler, if not nil, is called for every valid packet.
+// The sniffer mode is disabled again before Run returns.
+func (s *Sniffer) Run(ctx context.Context, handler func(ESBPacket)) error {
+	if err := s.dev.StartSniffer(); err != nil {
+		return err
+	}
+	defer s.dev.StopSniffer()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		frame, ok := s.dev.ReadRawFrame()
+		if !ok {
+			s.dev.config.Clock.Sleep(time.Millisecond)
+			continue
+		}
+		if p, ok := s.Process(frame[:]); ok && handler != nil {
+			handler(p)
+		}
+	}
+}
+
+// Process decodes a raw frame and records the result.
+// It returns the decoded packet and true if the frame contains a valid ESB packet.
+// This method is concurrent safe.
+func (s *Sniffer) Process(frame []byte) (ESBPacket, bool) {
+	p, ok := DecodeESBFrame(frame, s.crc)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.raw++
+	if !ok {
+		return p, false
+	}
+	s.valid++
+
+	entry, found := s.seen[p.Address]
+	if !found {
+		entry = &SniffedAddress{Address: p.Address, AddressWidth: p.AddressWidth}
+		s.seen[p.Address] = entry
+	}
+	entry.Packets++
+	entry.LastSeen = s.dev.config.Clock.Now()
+	entry.LastPayload = p.Payload
+	return p, true
+}
+
+// Addresses returns the discovered addresses, most active first.
+// This method is concurrent safe.
+func (s *Sniffer) Addresses() []SniffedAddress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]SniffedAddress, 0, len(s.seen))
+	for _, entry := range s.seen {
+		out = append(out, *entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Packets > out[j].Packets })
+	return out
+}
+
+// Counters returns the number of raw frames processed and how many of them were valid packets.
+// This method is concurrent safe.
+func (s *Sniffer) Counters() (raw, valid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.raw, s.valid
+}
@@ -0,0 +1,200 @@
+package nrf24
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// airFrame builds the on-air bits of an ESB packet (without preamble) as the
+// sniffer would capture them, padded to 32 bytes.
+func airFrame(addr []byte, pid byte, noAck bool, payload []byte, crc CRCLength) []byte {
+	buf := make([]byte, 40)
+	n := 0
+	put := func(v uint32, bits int) {
+		for i := bits - 1; i >= 0; i-- {
+			if v>>i&1 != 0 {
+				buf[n/8] |= 0x80 >> (n % 8)
+			}
+			n++
+		}
+	}
+	// Address goes MSByte first
+	for i := len(addr) - 1; i >= 0; i-- {
+		put(uint32(addr[i]), 8)
+	}
+	pcf := uint32(len(payload))<<3 | uint32(pid&0x03)<<1
+	if noAck {
+		pcf |= 1
+	}
+	put(pcf, 9)
+	for _, b := range payload {
+		put(uint32(b), 8)
+	}
+	if crc == CRCLength16 {
+		put(uint32(crc16Bits(buf, n)), 16)
+	} else {
+		put(uint32(crc8Bits(buf, n)), 8)
+	}
+	return buf[:32]
+}
+
+func TestDecodeESBFrame(t *testing.T) {
+	addr := []byte{0xE7, 0xE6, 0xE5, 0xE4, 0xE3}
+	frame := airFrame(addr, 2, true, []byte("sensor"), CRCLength16)
+
+	p, ok := DecodeESBFrame(frame, CRCLength16)
+	if !ok {
+		t.Fatalf("Expected valid packet in frame %X", frame)
+	}
+	if p.AddressWidth != 5 || !bytes.Equal(p.Address[:5], addr) {
+		t.Errorf("Expected address %X (width 5), got %X (width %d)", addr, p.Address, p.AddressWidth)
+	}
+	if p.PID != 2 || !p.NoAck {
+		t.Errorf("Expected PID 2 and NoAck, got PID %d NoAck %v", p.PID, p.NoAck)
+	}
+	if string(p.Payload) != "sensor" {
+		t.Errorf("Expected payload 'sensor', got %q", p.Payload)
+	}
+
+	// Corrupt a payload bit: the CRC must reject the frame
+	frame[7] ^= 0x10
+	if _, ok := DecodeESBFrame(frame, CRCLength16); ok {
+		t.Error("Expected corrupted frame to be rejected")
+	}
+}
+
+func TestDecodeESBFrameShifted(t *testing.T) {
+	// Address with MSB 0 (preceded by a 0x55 preamble on air): the radio locks one bit late
+	addr := []byte{0x12, 0x34, 0x56}
+	frame := airFrame(addr, 1, false, []byte{0x01, 0x02}, CRCLength8)
+
+	var shifted [32]byte
+	for i := range shifted {
+		shifted[i] = frame[i] << 1
+		if i+1 < len(frame) {
+			shifted[i] |= frame[i+1] >> 7
+		}
+	}
+
+	p, ok := DecodeESBFrame(shifted[:], CRCLength8)
+	if !ok {
+		t.Fatalf("Expected valid packet in shifted frame %X", shifted)
+	}
+	if p.AddressWidth != 3 || !bytes.Equal(p.Address[:3], addr) {
+		t.Errorf("Expected address %X (width 3), got %X (width %d)", addr, p.Address, p.AddressWidth)
+	}
+	if !bytes.Equal(p.Payload, []byte{0x01, 0x02}) {
+		t.Errorf("Expected payload 0102, got %X", p.Payload)
+	}
+}
+
+func TestEncodeESBFrame(t *testing.T) {
+	// The radio CRC-16 is CRC-16/CCITT-FALSE, whose check value is 0x29B1
+	if crc := crc16Bits([]byte("123456789"), 72); crc != 0x29B1 {
+		t.Errorf("crc16Bits(\"123456789\") = %04X, want 29B1", crc)
+	}
+
+	for _, crc := range []CRCLength{CRCLength8, CRCLength16} {
+		p := ESBPacket{Address: Address{0x01, 0x02, 0x03, 0x04}, AddressWidth: 4, PID: 3, Payload: []byte("esb")}
+		frame, err := EncodeESBFrame(p, crc)
+		if err != nil {
+			t.Fatalf("EncodeESBFrame failed: %v", err)
+		}
+		want := airFrame(p.Address[:4], 3, false, p.Payload, crc)
+		if !bytes.Equal(frame, want[:len(frame)]) {
+			t.Errorf("EncodeESBFrame(CRC %d) = %X, want %X", crc, frame, want[:len(frame)])
+		}
+		got, ok := DecodeESBFrame(frame, crc)
+		if !ok || got.Address != p.Address || got.PID != 3 || string(got.Payload) != "esb" {
+			t.Errorf("DecodeESBFrame(EncodeESBFrame()) = %+v, %v", got, ok)
+		}
+	}
+
+	if _, err := EncodeESBFrame(ESBPacket{AddressWidth: 2}, CRCLength16); err == nil {
+		t.Error("Expected an error for a 2-byte address")
+	}
+	if _, err := EncodeESBFrame(ESBPacket{AddressWidth: 5}, CRCLengthDisabled); err == nil {
+		t.Error("Expected an error without CRC")
+	}
+}
+
+func TestSniffer(t *testing.T) {
+	mockSPI := &mockSPIConn{}
+	dev, _ := NewWithHardware(HardwareConfig{CE: &mockPin{}}, mockSPI)
+	if err := dev.OpenRxPipe(1, []byte{0xD1, 0xD2, 0xD3, 0xD4, 0xD5}); err != nil {
+		t.Fatalf("OpenRxPipe failed: %v", err)
+	}
+	if err := dev.OpenRxPipe(2, []byte{0xA2}); err != nil {
+		t.Fatalf("OpenRxPipe failed: %v", err)
+	}
+
+	mockSPI.tx = nil
+	if err := dev.StartSniffer(); err != nil {
+		t.Fatalf("StartSniffer failed: %v", err)
+	}
+	// CONFIG without CRC, 2-byte addresses, 0x00AA listening address
+	for _, op := range [][]byte{{0x20 | _CONFIG, 0x03}, {0x20 | _SETUP_AW, 0x00}, {0x20 | _RX_ADDR_P0, 0xAA, 0x00}} {
+		if !bytes.Contains(mockSPI.tx, op) {
+			t.Errorf("Expected SPI write %X when starting sniffer, got: %X", op, mockSPI.tx)
+		}
+	}
+
+	if err := dev.Transmit(Address{1, 2, 3, 4, 5}, []byte("x")); err == nil {
+		t.Error("Expected Transmit to fail while sniffing")
+	}
+	mockSPI.tx = nil
+	if _, err := dev.Ping(context.Background(), Address{1, 2, 3, 4, 5}); !errors.Is(err, ErrSnifferActive) {
+		t.Errorf("Expected Ping to fail with ErrSnifferActive while sniffing, got %v", err)
+	}
+	if len(mockSPI.tx) != 0 {
+		t.Errorf("Expected Ping to leave the sniffer alone, got SPI writes %X", mockSPI.tx)
+	}
+
+	addr := []byte{0xC2, 0xC2, 0xC2, 0xC2, 0xC2}
+	frame := airFrame(addr, 0, false, []byte("hi"), CRCLength16)
+	mockSPI.rxQueue = nil
+	mockSPI.queueRx([]byte{0x00, 0x40})             // available() -> RX_DR, pipe 0
+	mockSPI.queueRx(append([]byte{0x40}, frame...)) // R_RX_PAYLOAD
+
+	raw, ok := dev.ReadRawFrame()
+	if !ok {
+		t.Fatal("Expected ReadRawFrame to return a frame")
+	}
+
+	s := NewSniffer(dev, CRCLength16)
+	s.Process(make([]byte, 32)) // noise
+	if _, ok := s.Process(raw[:]); !ok {
+		t.Fatalf("Expected raw frame to decode, got %X", raw)
+	}
+	total, valid := s.Counters()
+	if total != 2 || valid != 1 {
+		t.Errorf("Expected counters (2, 1), got (%d, %d)", total, valid)
+	}
+	found := s.Addresses()
+	if len(found) != 1 || !bytes.Equal(found[0].Address[:5], addr) || string(found[0].LastPayload) != "hi" {
+		t.Errorf("Unexpected discovered addresses: %+v", found)
+	}
+
+	mockSPI.tx = nil
+	dev.StopSniffer()
+	if !bytes.Contains(mockSPI.tx, []byte{0x20 | _SETUP_AW, 0x03}) {
+		t.Errorf("Expected StopSniffer to restore 5-byte addresses, got: %X", mockSPI.tx)
+	}
+	if !bytes.Contains(mockSPI.tx, []byte{0x20 | _RX_ADDR_P0, 0xE7, 0xE7, 0xE7, 0xE7, 0xE7}) {
+		t.Errorf("Expected StopSniffer to restore the address of pipe 0, got: %X", mockSPI.tx)
+	}
+	// The pipes opened before StartSniffer are opened again
+	for _, op := range [][]byte{{0x20 | _RX_ADDR_P1, 0xD1, 0xD2, 0xD3, 0xD4, 0xD5}, {0x20 | (_RX_ADDR_P0 + 2), 0xA2}} {
+		if !bytes.Contains(mockSPI.tx, op) {
+			t.Errorf("Expected SPI write %X when stopping sniffer, got: %X", op, mockSPI.tx)
+		}
+	}
+	if en := dev.register(_EN_RXADDR); en != _ERX_P0|_ERX_P1|1<<2 {
+		t.Errorf("EN_RXADDR = %02X, want pipes 0-2 enabled", en)
+	}
+	if dev.pipeAddrs[2] != (Address{0xA2}) {
+		t.Errorf("Expected StopSniffer to keep the address of pipe 2, got %v", dev.pipeAddrs[2])
+	}
+}
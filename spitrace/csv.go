@@ -0,0 +1,160 @@
+package spitrace
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumns holds the indexes of the columns of a logic-analyzer export, -1 when absent.
+type csvColumns struct {
+	time, mosi, miso, packet, kind int
+}
+
+// LoadCSV reads the SPI decoder output of a logic analyzer exported as CSV.
+//
+// The columns are found by name in the header row:
+//   - a time column ("Time [s]", "start_time"), in seconds;
+//   - the "MOSI" and "MISO" columns, with bytes in hexadecimal ("0x07" or "07");
+//   - how bytes are grouped into transactions (chip select asserted):
+//     a "Packet ID" column (Saleae Logic 1), a "type" column with enable, result and
+//     disable rows (Saleae Logic 2), or else one transaction per row, the MOSI and MISO
+//     cells holding every byte of it separated by spaces (sigrok transfer annotations).
+func LoadCSV(r io.Reader) ([]Transaction, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading CSV header: %w", ErrInvalidTrace, err)
+	}
+	cols := csvColumns{time: -1, mosi: -1, miso: -1, packet: -1, kind: -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case strings.Contains(name, "mosi"):
+			cols.mosi = i
+		case strings.Contains(name, "miso"):
+			cols.miso = i
+		case strings.Contains(name, "packet"):
+			cols.packet = i
+		case name == "type":
+			cols.kind = i
+		case strings.Contains(name, "time") && cols.time < 0:
+			cols.time = i
+		}
+	}
+	if cols.mosi < 0 || cols.miso < 0 {
+		return nil, fmt.Errorf("%w: CSV header %q has no MOSI and MISO columns", ErrInvalidTrace, header)
+	}
+
+	var (
+		txs   []Transaction
+		cur   *Transaction
+		start = -1.0
+		lastP string
+	)
+	field := func(rec []string, i int) string {
+		if i < 0 || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+	begin := func(rec []string) error {
+		var t Transaction
+		if s := field(rec, cols.time); s != "" {
+			secs, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("%w: time %q: %w", ErrInvalidTrace, s, err)
+			}
+			if start < 0 {
+				start = secs
+			}
+			t.Time = time.Duration((secs - start) * float64(time.Second))
+		}
+		txs = append(txs, t)
+		cur = &txs[len(txs)-1]
+		return nil
+	}
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTrace, err)
+		}
+
+		switch {
+		case cols.kind >= 0:
+			switch strings.ToLower(field(rec, cols.kind)) {
+			case "enable":
+				if err := begin(rec); err != nil {
+					return nil, err
+				}
+				continue
+			case "disable":
+				cur = nil
+				continue
+			}
+			if cur == nil {
+				// Bytes without chip select: a transaction on their own
+				if err := begin(rec); err != nil {
+					return nil, err
+				}
+			}
+		case cols.packet >= 0:
+			if p := field(rec, cols.packet); cur == nil || p != lastP || p == "" {
+				if err := begin(rec); err != nil {
+					return nil, err
+				}
+				lastP = p
+			}
+		default:
+			if err := begin(rec); err != nil {
+				return nil, err
+			}
+		}
+
+		w, err := parseCSVBytes(field(rec, cols.mosi))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: MOSI: %w", ErrInvalidTrace, line, err)
+		}
+		rd, err := parseCSVBytes(field(rec, cols.miso))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: MISO: %w", ErrInvalidTrace, line, err)
+		}
+		if len(w) != len(rd) {
+			return nil, fmt.Errorf("%w: line %d: %d MOSI bytes but %d MISO bytes", ErrInvalidTrace, line, len(w), len(rd))
+		}
+		cur.Write = append(cur.Write, w...)
+		cur.Read = append(cur.Read, rd...)
+	}
+
+	// Drop the transactions without data, e.g. an enable followed by a disable
+	n := 0
+	for _, t := range txs {
+		if len(t.Write) > 0 {
+			txs[n] = t
+			n++
+		}
+	}
+	return txs[:n], nil
+}
+
+// parseCSVBytes parses space separated hexadecimal bytes, with or without a 0x prefix.
+func parseCSVBytes(s string) ([]byte, error) {
+	var out []byte
+	for _, f := range strings.Fields(s) {
+		f = strings.TrimPrefix(strings.TrimPrefix(f, "0x"), "0X")
+		v, err := strconv.ParseUint(f, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid byte %q", f)
+		}
+		out = append(out, byte(v))
+	}
+	return out, nil
+}
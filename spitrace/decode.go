@@ -0,0 +1,140 @@
+package spitrace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/michcald/nrf24"
+)
+
+// nRF24L01+ SPI commands.
+const (
+	cmdRRegister       = 0x00 // + register (0x00-0x1F)
+	cmdWRegister       = 0x20 // + register (0x00-0x1F)
+	cmdActivate        = 0x50
+	cmdRRxPlWid        = 0x60
+	cmdRRxPayload      = 0x61
+	cmdWTxPayload      = 0xA0
+	cmdWAckPayload     = 0xA8 // + pipe (0-5)
+	cmdWTxPayloadNoAck = 0xB0
+	cmdFlushTx         = 0xE1
+	cmdFlushRx         = 0xE2
+	cmdReuseTxPl       = 0xE3
+	cmdNop             = 0xFF
+)
+
+// Op is a transaction decoded into the command it carries.
+type Op struct {
+	Transaction
+	// Command is the datasheet name of the command, e.g. "W_REGISTER".
+	Command string
+	// Detail describes the arguments of the command, e.g. "RF_CH=0x4C (RF_CH=76 (2476 MHz))".
+	Detail string
+	// Status is the STATUS register, clocked out by the radio with the command byte.
+	// It is only valid if HasStatus is true.
+	Status    byte
+	HasStatus bool
+}
+
+// String formats the operation as an annotated trace line.
+func (o Op) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%.6f %s", o.Time.Seconds(), o.Command)
+	if o.Detail != "" {
+		b.WriteString(" " + o.Detail)
+	}
+	if o.HasStatus {
+		fmt.Fprintf(&b, " | STATUS=0x%02X %s", o.Status, nrf24.DecodeStatus(o.Status))
+	}
+	return b.String()
+}
+
+// Decode annotates each transaction with its SPI command.
+func Decode(txs []Transaction) []Op {
+	ops := make([]Op, len(txs))
+	for i, t := range txs {
+		ops[i] = DecodeTransaction(t)
+	}
+	return ops
+}
+
+// DecodeTransaction annotates a transaction with its SPI command.
+func DecodeTransaction(t Transaction) Op {
+	o := Op{Transaction: t}
+	if len(t.Read) > 0 {
+		o.Status, o.HasStatus = t.Read[0], true
+	}
+	if len(t.Write) == 0 {
+		o.Command = "EMPTY"
+		return o
+	}
+	cmd, args := t.Write[0], t.Write[1:]
+	// The bytes clocked out by the radio after the STATUS byte
+	var data []byte
+	if len(t.Read) > 1 {
+		data = t.Read[1:]
+	}
+
+	switch {
+	case cmd&0xE0 == cmdRRegister:
+		o.Command = "R_REGISTER"
+		o.Detail = decodeRegister(cmd&0x1F, data)
+	case cmd&0xE0 == cmdWRegister:
+		o.Command = "W_REGISTER"
+		o.Detail = decodeRegister(cmd&0x1F, args)
+	case cmd == cmdActivate:
+		o.Command = "ACTIVATE"
+		o.Detail = fmt.Sprintf("data=%X", args)
+	case cmd == cmdRRxPlWid:
+		o.Command = "R_RX_PL_WID"
+		if len(data) > 0 {
+			o.Detail = fmt.Sprintf("width=%d", data[0])
+		}
+	case cmd == cmdRRxPayload:
+		o.Command = "R_RX_PAYLOAD"
+		o.Detail = fmt.Sprintf("len=%d data=%X", len(args), data)
+		if o.HasStatus {
+			o.Detail = fmt.Sprintf("pipe=%d %s", (o.Status>>1)&0x07, o.Detail)
+		}
+	case cmd == cmdWTxPayload:
+		o.Command = "W_TX_PAYLOAD"
+		o.Detail = fmt.Sprintf("len=%d data=%X", len(args), args)
+	case cmd&0xF8 == cmdWAckPayload:
+		o.Command = "W_ACK_PAYLOAD"
+		o.Detail = fmt.Sprintf("pipe=%d len=%d data=%X", cmd&0x07, len(args), args)
+	case cmd == cmdWTxPayloadNoAck:
+		o.Command = "W_TX_PAYLOAD_NOACK"
+		o.Detail = fmt.Sprintf("len=%d data=%X", len(args), args)
+	case cmd == cmdFlushTx:
+		o.Command = "FLUSH_TX"
+	case cmd == cmdFlushRx:
+		o.Command = "FLUSH_RX"
+	case cmd == cmdReuseTxPl:
+		o.Command = "REUSE_TX_PL"
+	case cmd == cmdNop:
+		o.Command = "NOP"
+	default:
+		o.Command = fmt.Sprintf("UNKNOWN_%02X", cmd)
+		o.Detail = fmt.Sprintf("data=%X", args)
+	}
+	return o
+}
+
+// decodeRegister describes the value of a register read or written in a transaction.
+func decodeRegister(reg byte, val []byte) string {
+	name := nrf24.RegisterName(reg)
+	switch {
+	case len(val) == 0:
+		return name
+	case len(val) > 1:
+		hex := make([]string, len(val))
+		for i, v := range val {
+			hex[i] = fmt.Sprintf("%02X", v)
+		}
+		return name + "=" + strings.Join(hex, ":")
+	}
+	if s := nrf24.DecodeRegister(reg, val[0]); s != "" {
+		return fmt.Sprintf("%s=0x%02X (%s)", name, val[0], s)
+	}
+	return fmt.Sprintf("%s=0x%02X", name, val[0])
+}
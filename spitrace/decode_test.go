@@ -0,0 +1,101 @@
+package spitrace
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeTransaction(t *testing.T) {
+	tests := []struct {
+		w, r []byte
+		want string
+	}{
+		{[]byte{0x25, 0x4C}, []byte{0x0E, 0x00}, "W_REGISTER RF_CH=0x4C (RF_CH=76 (2476 MHz))"},
+		{[]byte{0x07, 0xFF}, []byte{0x0E, 0x0E}, "R_REGISTER STATUS=0x0E (RX_DR=0 TX_DS=0 MAX_RT=0 RX_P_NO=empty TX_FULL=0)"},
+		{[]byte{0x30, 0xE7, 0xE7, 0xE7, 0xE7, 0xE7}, []byte{0x0E, 0, 0, 0, 0, 0}, "W_REGISTER TX_ADDR=E7:E7:E7:E7:E7"},
+		{[]byte{0x61, 0xFF, 0xFF}, []byte{0x42, 0x68, 0x69}, "R_RX_PAYLOAD pipe=1 len=2 data=6869"},
+		{[]byte{0x60, 0xFF}, []byte{0x42, 0x02}, "R_RX_PL_WID width=2"},
+		{[]byte{0xA0, 0x68, 0x69}, []byte{0x0E, 0x0E, 0x0E}, "W_TX_PAYLOAD len=2 data=6869"},
+		{[]byte{0xA9, 0x01}, []byte{0x0E, 0x0E}, "W_ACK_PAYLOAD pipe=1 len=1 data=01"},
+		{[]byte{0xB0, 0x01}, []byte{0x0E, 0x0E}, "W_TX_PAYLOAD_NOACK len=1 data=01"},
+		{[]byte{0xE1}, []byte{0x2E}, "FLUSH_TX"},
+		{[]byte{0xE2}, []byte{0x0E}, "FLUSH_RX"},
+		{[]byte{0xE3}, []byte{0x0E}, "REUSE_TX_PL"},
+		{[]byte{0xFF}, []byte{0x0E}, "NOP"},
+		{[]byte{0x50, 0x73}, []byte{0x0E, 0x0E}, "ACTIVATE data=73"},
+		{[]byte{0x90}, []byte{0x0E}, "UNKNOWN_90 data="},
+	}
+	for _, tt := range tests {
+		op := DecodeTransaction(Transaction{Write: tt.w, Read: tt.r})
+		got := op.Command
+		if op.Detail != "" {
+			got += " " + op.Detail
+		}
+		if got != tt.want {
+			t.Errorf("Decode(w=%X) = %q, want %q", tt.w, got, tt.want)
+		}
+		if !op.HasStatus || op.Status != tt.r[0] {
+			t.Errorf("Decode(w=%X): status = %02X, %v, want %02X", tt.w, op.Status, op.HasStatus, tt.r[0])
+		}
+	}
+
+	op := DecodeTransaction(Transaction{Time: 12000, Write: []byte{0xE1}, Read: []byte{0x20}})
+	if want := "0.000012 FLUSH_TX | STATUS=0x20 RX_DR=0 TX_DS=1 MAX_RT=0 RX_P_NO=0 TX_FULL=0"; op.String() != want {
+		t.Errorf("String() = %q, want %q", op.String(), want)
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	want := []Transaction{
+		{Time: 0, Write: []byte{0x25, 0x4C}, Read: []byte{0x0E, 0x00}},
+		{Time: 500000, Write: []byte{0xE1}, Read: []byte{0x0E}},
+	}
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"saleae1", `Time [s],Packet ID,MOSI,MISO
+1.000000,0,0x25,0x0E
+1.000010,0,0x4C,0x00
+1.000500,1,0xE1,0x0E
+`},
+		{"saleae2", `name,type,start_time,duration,mosi,miso
+"SPI","enable",2.0,0,,
+"SPI","result",2.000001,0.000008,0x25,0x0E
+"SPI","result",2.000010,0.000008,0x4C,0x00
+"SPI","disable",2.000020,0,,
+"SPI","enable",2.0005,0,,
+"SPI","result",2.000501,0.000008,0xE1,0x0E
+"SPI","disable",2.000510,0,,
+`},
+		{"sigrok", `time,mosi,miso
+0.25,25 4C,0E 00
+0.2505,E1,0E
+`},
+	}
+	for _, tt := range tests {
+		got, err := LoadCSV(strings.NewReader(tt.csv))
+		if err != nil {
+			t.Errorf("%s: LoadCSV failed: %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: got %d transactions, want %d", tt.name, len(got), len(want))
+			continue
+		}
+		for i := range want {
+			if d := got[i].Time - want[i].Time; d < -1000 || d > 1000 ||
+				!bytes.Equal(got[i].Write, want[i].Write) || !bytes.Equal(got[i].Read, want[i].Read) {
+				t.Errorf("%s: transaction %d = %v, want %v", tt.name, i, got[i], want[i])
+			}
+		}
+	}
+
+	for _, bad := range []string{"time,data\n0,01\n", "time,mosi,miso\n0,01 02,0E\n", "time,mosi,miso\n0,ZZ,0E\n"} {
+		if _, err := LoadCSV(strings.NewReader(bad)); !errors.Is(err, ErrInvalidTrace) {
+			t.Errorf("LoadCSV(%q): expected ErrInvalidTrace, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package spitrace records the SPI transactions between the driver and a radio, and replays
+// them in tests.
+//
+// Wrap the SPI connection of a real radio with a Recorder to capture a session:
+//
+//	nrf24.New(nrf24.Config{WrapSPI: func(s nrf24.SPI) nrf24.SPI { return spitrace.NewRecorder(s, f) }})
+//
+// Then feed the trace to nrf24.NewWithHardware through a Replayer: the driver gets the
+// recorded responses back, and any difference in what it writes is reported as a divergence.
+// As long as the driver writes the same bytes it takes the same decisions, so the replay is
+// deterministic even for sessions that depended on the timing of a flaky module.
+//
+// A trace is a text file with one transaction per line: the time since the first transaction,
+// the bytes written and the bytes read, in hexadecimal.
+//
+//	# nrf24 spitrace v1
+//	0.000000 w=0700 r=0E0E
+//	0.000012 w=2705 r=0E00
+//
+// Blank lines and lines starting with '#' are ignored.
+//
+// Decode annotates transactions with the SPI command they carry and the STATUS bits, for
+// traces and for logic-analyzer captures read with LoadCSV:
+//
+//	0.000012 W_REGISTER RF_CH=0x4C (RF_CH=76 (2476 MHz)) | STATUS=0x0E RX_DR=0 ...
+package spitrace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michcald/nrf24"
+)
+
+// Header is the first line of the traces written by a Recorder.
+const Header = "# nrf24 spitrace v1"
+
+var (
+	// ErrDivergence is returned when the driver writes something else than the trace.
+	ErrDivergence = errors.New("spitrace: driver diverged from the trace")
+	// ErrInvalidTrace is returned for malformed trace lines.
+	ErrInvalidTrace = errors.New("spitrace: invalid trace")
+)
+
+// Transaction is a single SPI transfer.
+type Transaction struct {
+	// Time is the time since the first transaction of the trace.
+	Time time.Duration
+	// Write holds the bytes sent to the radio (MOSI).
+	Write []byte
+	// Read holds the bytes received from the radio (MISO).
+	Read []byte
+}
+
+// String formats the transaction as a trace line.
+func (t Transaction) String() string {
+	return fmt.Sprintf("%.6f w=%X r=%X", t.Time.Seconds(), t.Write, t.Read)
+}
+
+// ParseTransaction parses a trace line.
+func ParseTransaction(line string) (Transaction, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 || !strings.HasPrefix(fields[1], "w=") || !strings.HasPrefix(fields[2], "r=") {
+		return Transaction{}, fmt.Errorf("%w: %q", ErrInvalidTrace, line)
+	}
+	secs, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("%w: %q: %w", ErrInvalidTrace, line, err)
+	}
+	w, err := hex.DecodeString(fields[1][2:])
+	if err != nil {
+		return Transaction{}, fmt.Errorf("%w: %q: %w", ErrInvalidTrace, line, err)
+	}
+	r, err := hex.DecodeString(fields[2][2:])
+	if err != nil {
+		return Transaction{}, fmt.Errorf("%w: %q: %w", ErrInvalidTrace, line, err)
+	}
+	return Transaction{Time: time.Duration(secs * float64(time.Second)), Write: w, Read: r}, nil
+}
+
+// Load reads a trace.
+func Load(r io.Reader) ([]Transaction, error) {
+	var txs []Transaction
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		t, err := ParseTransaction(line)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, s.Err()
+}
+
+// Recorder is an nrf24.SPI that writes every transaction of the wrapped connection to a trace.
+type Recorder struct {
+	spi nrf24.SPI
+
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+	err   error
+}
+
+// NewRecorder wraps an SPI connection, writing its transactions to w.
+func NewRecorder(spi nrf24.SPI, w io.Writer) *Recorder {
+	return &Recorder{spi: spi, w: w}
+}
+
+// Tx performs the transaction on the wrapped connection and records it.
+// Failed transactions are not recorded.
+func (r *Recorder) Tx(w, rd []byte) error {
+	// Copy the written bytes first: the driver reads into the same buffer
+	t := Transaction{Write: append([]byte(nil), w...)}
+	if err := r.spi.Tx(w, rd); err != nil {
+		return err
+	}
+	t.Read = append([]byte(nil), rd[:len(w)]...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if r.start.IsZero() {
+		r.start = now
+		r.write(Header + "\n")
+	}
+	t.Time = now.Sub(r.start)
+	r.write(t.String() + "\n")
+	return nil
+}
+
+func (r *Recorder) write(s string) {
+	if r.err == nil {
+		_, r.err = io.WriteString(r.w, s)
+	}
+}
+
+// Err returns the first error writing the trace.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Replayer is an nrf24.SPI that answers with the responses of a trace and checks that
+// the driver writes the same bytes.
+type Replayer struct {
+	mu   sync.Mutex
+	txs  []Transaction
+	next int
+	err  error
+}
+
+// NewReplayer creates a replayer for a trace.
+func NewReplayer(txs []Transaction) *Replayer {
+	return &Replayer{txs: txs}
+}
+
+// Tx checks w against the next transaction of the trace and copies its response into r.
+// After the first divergence, every call fails with it.
+func (p *Replayer) Tx(w, r []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	if p.next >= len(p.txs) {
+		p.err = fmt.Errorf("%w: unexpected transaction %d w=%X after the end of the trace", ErrDivergence, p.next+1, w)
+		return p.err
+	}
+	t := p.txs[p.next]
+	if !bytes.Equal(w, t.Write) {
+		p.err = fmt.Errorf("%w: transaction %d wrote %X, trace has %X", ErrDivergence, p.next+1, w, t.Write)
+		return p.err
+	}
+	p.next++
+	copy(r, t.Read)
+	return nil
+}
+
+// Remaining returns the number of transactions of the trace not replayed yet.
+func (p *Replayer) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.txs) - p.next
+}
+
+// Err returns the first divergence, or nil.
+func (p *Replayer) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Done returns the first divergence, or an error if part of the trace was not replayed.
+func (p *Replayer) Done() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	if p.next < len(p.txs) {
+		return fmt.Errorf("%w: %d transactions not replayed, next is w=%X", ErrDivergence, len(p.txs)-p.next, p.txs[p.next].Write)
+	}
+	return nil
+}
+
+// NopPin is a pin doing nothing, to pass as CE to nrf24.NewWithHardware with a Replayer:
+// traces hold the SPI transactions only.
+type NopPin struct{}
+
+func (NopPin) Out(l nrf24.Level) error                     { return nil }
+func (NopPin) In(pull nrf24.Pull) error                    { return nil }
+func (NopPin) Read() nrf24.Level                           { return nrf24.High }
+func (NopPin) Watch(edge nrf24.Edge, handler func()) error { return nil }
+func (NopPin) Unwatch() error                              { return nil }
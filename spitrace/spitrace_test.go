@@ -0,0 +1,117 @@
+package spitrace
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/michcald/nrf24"
+	"github.com/michcald/nrf24/sim"
+)
+
+var (
+	rxAddr = nrf24.Address{0xB2, 0xB2, 0xB2, 0xB2, 0xB2}
+	config = nrf24.RadioConfig{ChannelNumber: 76, RxAddr: nrf24.Address{0xA1, 0xA1, 0xA1, 0xA1, 0xA1}, EnableDynamicPayload: true}
+)
+
+// record runs a session on a simulated radio and returns its trace.
+func record(t *testing.T) []byte {
+	t.Helper()
+	air := sim.NewAir()
+	if _, _, err := air.NewDevice(nrf24.RadioConfig{ChannelNumber: 76, RxAddr: rxAddr, EnableDynamicPayload: true}); err != nil {
+		t.Fatalf("NewDevice failed: %v", err)
+	}
+	radio := air.NewRadio()
+	var buf bytes.Buffer
+	rec := NewRecorder(radio, &buf)
+	dev, err := nrf24.NewWithHardware(nrf24.HardwareConfig{RadioConfig: config, CE: radio.CE()}, rec)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+	if err := dev.Transmit(rxAddr, []byte("hello")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatalf("Recorder failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRecordReplay(t *testing.T) {
+	trace := record(t)
+	if !strings.HasPrefix(string(trace), Header+"\n") {
+		t.Fatalf("Expected the trace to start with the header, got %q", trace)
+	}
+	txs, err := Load(bytes.NewReader(trace))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(txs) == 0 {
+		t.Fatal("Expected transactions in the trace")
+	}
+
+	p := NewReplayer(txs)
+	dev, err := nrf24.NewWithHardware(nrf24.HardwareConfig{RadioConfig: config, CE: NopPin{}}, p)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+	if err := dev.Transmit(rxAddr, []byte("hello")); err != nil {
+		t.Fatalf("Transmit failed: %v", err)
+	}
+	if err := p.Done(); err != nil {
+		t.Errorf("Done() = %v", err)
+	}
+}
+
+func TestReplayDivergence(t *testing.T) {
+	txs, err := Load(bytes.NewReader(record(t)))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	p := NewReplayer(txs)
+	dev, err := nrf24.NewWithHardware(nrf24.HardwareConfig{RadioConfig: config, CE: NopPin{}}, p)
+	if err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+	// A different payload diverges when it is written to the TX FIFO
+	if err := dev.Transmit(rxAddr, []byte("world")); err == nil {
+		t.Error("Expected Transmit to fail")
+	}
+	if err := p.Err(); err == nil || !strings.Contains(err.Error(), "A0776F726C64") {
+		t.Errorf("Expected the divergence on the payload write, got %v", err)
+	}
+	if err := p.Done(); !errors.Is(err, ErrDivergence) {
+		t.Errorf("Expected ErrDivergence from Done, got %v", err)
+	}
+
+	// A shorter session leaves part of the trace
+	p = NewReplayer(txs)
+	if _, err := nrf24.NewWithHardware(nrf24.HardwareConfig{RadioConfig: config, CE: NopPin{}}, p); err != nil {
+		t.Fatalf("NewWithHardware failed: %v", err)
+	}
+	if p.Remaining() == 0 {
+		t.Error("Expected transactions left")
+	}
+	if err := p.Done(); !errors.Is(err, ErrDivergence) {
+		t.Errorf("Expected ErrDivergence for a partial replay, got %v", err)
+	}
+}
+
+func TestParseTransaction(t *testing.T) {
+	want := Transaction{Time: 1500000, Write: []byte{0x07, 0x00}, Read: []byte{0x0E, 0x0E}}
+	got, err := ParseTransaction(want.String())
+	if err != nil {
+		t.Fatalf("ParseTransaction(%q) failed: %v", want.String(), err)
+	}
+	if got.Time != want.Time || !bytes.Equal(got.Write, want.Write) || !bytes.Equal(got.Read, want.Read) {
+		t.Errorf("ParseTransaction(%q) = %v, want %v", want.String(), got, want)
+	}
+
+	for _, line := range []string{"0.1 w=07", "x w=07 r=0E", "0.1 w=0 r=0E", "0.1 r=0E w=07"} {
+		if _, err := ParseTransaction(line); !errors.Is(err, ErrInvalidTrace) {
+			t.Errorf("ParseTransaction(%q): expected ErrInvalidTrace, got %v", line, err)
+		}
+	}
+}
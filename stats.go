@@ -0,0 +1,78 @@
+package nrf24
+
+import (
+	"errors"
+)
+
+// Stats holds the traffic counters of a Device since it was created.
+type Stats struct {
+	// TxSuccess is the number of packets delivered (acknowledged, or sent with NoAck).
+	TxSuccess uint64
+	// TxMaxRetries is the number of packets dropped after the maximum number of retransmissions.
+	TxMaxRetries uint64
+	// TxTimeouts is the number of transmissions the radio never completed.
+	TxTimeouts uint64
+	// Retransmits is the total number of hardware retransmissions.
+	Retransmits uint64
+	// RetransmitCounts is the number of acknowledged transmissions by number of retransmissions.
+	// Packets dropped after the maximum number of retransmissions are counted at AutoRetransmitCount.
+	RetransmitCounts [16]uint64
+	// RxPackets is the number of packets received on each data pipe.
+	RxPackets [6]uint64
+	// RxFIFOFull is the number of packets read while the RX FIFO was full.
+	// The radio drops the packets it receives in that state, so it counts potential overflows.
+	RxFIFOFull uint64
+	// CarrierChecks is the number of carrier detections (IsCarrierDetected and ScanChannel).
+	CarrierChecks uint64
+	// CarrierDetected is the number of carrier detections that found a carrier.
+	CarrierDetected uint64
+}
+
+// Stats returns a snapshot of the traffic counters.
+// This method is concurrent safe.
+func (d *Device) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// countCarrier updates the carrier detection counters.
+// Call with lock held.
+func (d *Device) countCarrier(detected bool) {
+	d.stats.CarrierChecks++
+	if detected {
+		d.stats.CarrierDetected++
+	}
+}
+
+// afterTX updates the counters and records a finished transmission.
+// Call with lock held.
+func (d *Device) afterTX(payload []byte, noAck bool, err error) {
+	var retransmits byte
+	switch {
+	case errors.Is(err, ErrMaxRetries):
+		d.stats.TxMaxRetries++
+		retransmits = d.config.AutoRetransmitCount
+	case err != nil:
+		d.stats.TxTimeouts++
+	default:
+		d.stats.TxSuccess++
+		if !noAck && d.config.EnableAutoAck {
+			retransmits = d.readRegister(_OBSERVE_TX) & 0x0F
+		}
+	}
+	d.stats.Retransmits += uint64(retransmits)
+	if err == nil && !noAck || errors.Is(err, ErrMaxRetries) {
+		d.stats.RetransmitCounts[retransmits&0x0F]++
+	}
+	d.recordTX(payload, noAck, retransmits, err)
+	if d.observer != nil {
+		if err != nil {
+			d.observer.TxFailed(d.txAddr, retransmits, err)
+		} else {
+			d.observer.TxDone(d.txAddr, retransmits)
+		}
+	}
+	// The radio is back in standby once the transmission is over
+	d.setMode(ModeStandby)
+}